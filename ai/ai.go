@@ -7,7 +7,11 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"regexp"
 	"strings"
+
+	"cli-convert/charset"
+	"cli-convert/document"
 )
 
 // Provider representa um provedor de IA configurado.
@@ -158,12 +162,32 @@ func CallAI(messages []chatMessage) (string, error) {
 // ──────────────────────────────────────────────
 
 // DetectFormat detecta o formato de um arquivo usando assinatura de conteúdo.
-// Retorna o formato (json, csv, xml, yaml) sem precisar de IA.
+// Retorna o formato (json, ndjson, csv, xml, yaml, toml, xlsx) sem precisar de IA.
 func DetectFormat(filePath string) (string, error) {
 	data, err := os.ReadFile(filePath)
 	if err != nil {
 		return "", fmt.Errorf("erro ao ler arquivo: %w", err)
 	}
+	return DetectFormatContent(data, false)
+}
+
+// DetectFormatContent detecta o formato a partir do conteúdo já lido. Com
+// partial, data é só o início da entrada (por exemplo, uma espiada no
+// stdin): a última linha, possivelmente cortada, é descartada e um JSON
+// incompleto não é validado.
+func DetectFormatContent(data []byte, partial bool) (string, error) {
+	// XLSX: pacote zip com a parte xl/workbook.xml
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		if bytes.Contains(data, []byte("xl/workbook.xml")) || (partial && bytes.Contains(data, []byte("xl/"))) {
+			return "xlsx", nil
+		}
+		return "", fmt.Errorf("arquivo zip não reconhecido")
+	}
+
+	// UTF-16 é convertido para UTF-8 antes da análise
+	if encoding := charset.Guess(data, partial); encoding == charset.UTF16LE || encoding == charset.UTF16BE {
+		data = charset.Decode(data, encoding)
+	}
 
 	trimmed := strings.TrimSpace(string(data))
 
@@ -173,7 +197,13 @@ func DetectFormat(filePath string) (string, error) {
 
 	// JSON: começa com { ou [
 	if trimmed[0] == '{' || trimmed[0] == '[' {
-		if json.Valid(data) {
+		if !partial && json.Valid(data) {
+			return "json", nil
+		}
+		if isJSONLines(completeLines(trimmed, partial)) {
+			return "ndjson", nil
+		}
+		if partial {
 			return "json", nil
 		}
 	}
@@ -183,10 +213,25 @@ func DetectFormat(filePath string) (string, error) {
 		return "xml", nil
 	}
 
+	// CSV com qualquer delimitador comum e o mesmo número de colunas em
+	// todas as linhas, sem cara de YAML
+	delimiter := ","
+	if dialect, ok := SniffCSV(data, partial); ok {
+		delimiter = string(dialect.Delimiter)
+		if dialect.Consistent && dialect.Delimiter != ',' && !strings.Contains(trimmed, ": ") {
+			if dialect.Delimiter == '\t' {
+				return "tsv", nil
+			}
+			return "csv", nil
+		}
+	}
+
 	// YAML: verifica padrões típicos (key: value, - item, etc.)
-	lines := strings.Split(trimmed, "\n")
+	lines := strings.Split(completeLines(trimmed, partial), "\n")
 	yamlScore := 0
 	csvScore := 0
+	tomlScore := 0
+	contentLines := 0
 
 	for _, line := range lines {
 		line = strings.TrimSpace(line)
@@ -194,19 +239,30 @@ func DetectFormat(filePath string) (string, error) {
 			continue
 		}
 
+		contentLines++
+
 		// Padrões YAML
 		if strings.Contains(line, ": ") || strings.HasPrefix(line, "- ") {
 			yamlScore++
 		}
 
-		// Padrões CSV: múltiplas vírgulas por linha
-		commas := strings.Count(line, ",")
-		if commas >= 1 {
+		// Padrões TOML: [tabela], [[array]] ou chave = valor
+		if tomlHeaderPattern.MatchString(line) || tomlKeyValuePattern.MatchString(line) {
+			tomlScore++
+		}
+
+		// Padrões CSV: o delimitador aparece na linha
+		if strings.Contains(line, delimiter) {
 			csvScore++
 		}
 	}
 
-	// CSV: se todas as linhas têm o mesmo número de vírgulas
+	// TOML: a maioria das linhas é cabeçalho de tabela ou chave = valor
+	if tomlScore > yamlScore && tomlScore*2 > contentLines {
+		return "toml", nil
+	}
+
+	// CSV: se todas as linhas têm o mesmo número de delimitadores
 	if csvScore > 0 && csvScore == yamlScore {
 		// Mais provável ser CSV se há header consistente
 		commasPerLine := make([]int, 0)
@@ -215,7 +271,7 @@ func DetectFormat(filePath string) (string, error) {
 			if line == "" {
 				continue
 			}
-			commasPerLine = append(commasPerLine, strings.Count(line, ","))
+			commasPerLine = append(commasPerLine, strings.Count(line, delimiter))
 		}
 		if len(commasPerLine) > 1 {
 			allSame := true
@@ -241,93 +297,94 @@ func DetectFormat(filePath string) (string, error) {
 	return "", fmt.Errorf("não foi possível detectar o formato automaticamente")
 }
 
-// InferSchema gera um JSON Schema a partir dos dados de um arquivo.
-func InferSchema(filePath string) (map[string]interface{}, error) {
-	data, err := os.ReadFile(filePath)
-	if err != nil {
-		return nil, fmt.Errorf("erro ao ler arquivo: %w", err)
-	}
+var (
+	tomlHeaderPattern   = regexp.MustCompile(`^\[\[?[^\[\]=]+\]\]?(\s*#.*)?$`)
+	tomlKeyValuePattern = regexp.MustCompile(`^[A-Za-z0-9_."'-]+(\s*\.\s*[A-Za-z0-9_"'-]+)*\s*=\s*\S`)
+)
 
-	schema := map[string]interface{}{
-		"title":    "Schema gerado automaticamente",
-		"source":   filePath,
-		"type":     "object",
-		"properties": map[string]interface{}{},
+// completeLines descarta a última linha de um conteúdo parcial, que pode
+// ter sido cortada no meio.
+func completeLines(content string, partial bool) string {
+	if !partial {
+		return content
 	}
+	if i := strings.LastIndex(content, "\n"); i >= 0 {
+		return content[:i]
+	}
+	return ""
+}
 
-	trimmed := strings.TrimSpace(string(data))
-
-	// JSON
-	if trimmed[0] == '{' || trimmed[0] == '[' {
-		var parsed interface{}
-		if err := json.Unmarshal(data, &parsed); err != nil {
-			return nil, fmt.Errorf("JSON inválido: %w", err)
+// isJSONLines indica se cada linha não vazia é um valor JSON válido.
+func isJSONLines(content string) bool {
+	count := 0
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
 		}
-		schema["properties"] = generateJSONSchemaProperties(parsed)
-		schema["format"] = "json"
-		return schema, nil
+		if !json.Valid([]byte(line)) {
+			return false
+		}
+		count++
 	}
+	return count > 1
+}
 
-	// Para outros formatos, delega à IA
-	format := "desconhecido"
-	if trimmed[0] == '<' {
-		format = "xml"
-	} else {
-		// Tenta detectar
-		format, _ = DetectFormat(filePath)
-	}
+// SchemaDialect é o $schema dos documentos gerados por InferSchema.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
 
-	// Amostra dos primeiros 500 chars
-	sample := trimmed
-	if len(sample) > 500 {
-		sample = sample[:500] + "..."
+// InferSchema gera um JSON Schema (draft 2020-12) localmente a partir dos
+// dados já lidos (a árvore produzida pelos leitores do cli-convert), sem
+// chamar a IA. Todos os registros são considerados: tipos diferentes viram
+// uniões e "required" lista os campos presentes em todos eles.
+func InferSchema(data interface{}, title string, opts InferOptions) *document.Object {
+	schema := document.NewObject()
+	schema.Set("$schema", SchemaDialect)
+	if title != "" {
+		schema.Set("title", title)
 	}
 
-	prompt := fmt.Sprintf(`Analise este arquivo %s e gere um JSON Schema descrevendo a estrutura dos dados.
-Retorne APENAS o JSON válido, sem markdown, sem explicação.
-Arquivo:
-%s`, format, sample)
+	inferred := inferSchemaNode(data, opts)
+	for _, key := range inferred.Keys() {
+		value, _ := inferred.Get(key)
+		schema.Set(key, value)
+	}
+	return schema
+}
 
-	messages := []chatMessage{
-		{Role: "system", Content: "Você é um gerador de schemas. Retorne APENAS JSON válido."},
-		{Role: "user", Content: prompt},
+// DescribeSchema pede à IA uma descrição dos campos de um schema já inferido.
+// É um passo opcional: o schema em si não depende da resposta.
+func DescribeSchema(schema *document.Object, filePath string, format string) (string, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("erro ao ler arquivo: %w", err)
 	}
 
-	response, err := CallAI(messages)
+	schemaJSON, err := json.MarshalIndent(schema, "", "  ")
 	if err != nil {
-		return nil, err
+		return "", fmt.Errorf("erro ao serializar schema: %w", err)
 	}
 
-	// Tenta parsear a resposta como JSON
-	response = strings.TrimSpace(response)
-	// Remove possíveis wrappers markdown
-	if strings.HasPrefix(response, "```") {
-		lines := strings.Split(response, "\n")
-		var jsonLines []string
-		inBlock := false
-		for _, line := range lines {
-			if strings.HasPrefix(line, "```") {
-				inBlock = !inBlock
-				continue
-			}
-			if inBlock {
-				jsonLines = append(jsonLines, line)
-			}
-		}
-		response = strings.Join(jsonLines, "\n")
+	// Amostra dos primeiros 500 chars
+	sample := strings.TrimSpace(string(data))
+	if len(sample) > 500 {
+		sample = sample[:500] + "..."
 	}
 
-	var aiSchema map[string]interface{}
-	if err := json.Unmarshal([]byte(response), &aiSchema); err != nil {
-		// Se não conseguiu parsear, retorna o schema básico com a resposta da IA
-		schema["ai_analysis"] = response
-		schema["format"] = format
-		return schema, nil
+	prompt := fmt.Sprintf(`Este JSON Schema foi inferido de um arquivo %s:
+%s
+
+Amostra do arquivo:
+%s
+
+Descreva em poucas linhas o que cada campo provavelmente representa.`, format, schemaJSON, sample)
+
+	messages := []chatMessage{
+		{Role: "system", Content: "Você é um analista de dados. Responda de forma objetiva em português brasileiro."},
+		{Role: "user", Content: prompt},
 	}
 
-	aiSchema["source"] = filePath
-	aiSchema["format"] = format
-	return aiSchema, nil
+	return CallAI(messages)
 }
 
 // AskQuestion permite fazer perguntas em linguagem natural sobre um arquivo.
@@ -366,40 +423,3 @@ Minha pergunta é: %s`, format, sample, question)
 
 	return CallAI(messages)
 }
-
-// generateJSONSchemaProperties gera propriedades de schema recursivamente.
-func generateJSONSchemaProperties(data interface{}) map[string]interface{} {
-	props := make(map[string]interface{})
-
-	switch v := data.(type) {
-	case map[string]interface{}:
-		for key, value := range v {
-			prop := map[string]interface{}{}
-			switch val := value.(type) {
-			case nil:
-				prop["type"] = "null"
-			case bool:
-				prop["type"] = "boolean"
-			case float64:
-				prop["type"] = "number"
-			case string:
-				prop["type"] = "string"
-			case []interface{}:
-				prop["type"] = "array"
-				if len(val) > 0 {
-					prop["items"] = generateJSONSchemaProperties(val[0])
-				}
-			case map[string]interface{}:
-				prop["type"] = "object"
-				prop["properties"] = generateJSONSchemaProperties(val)
-			default:
-				prop["type"] = "string"
-			}
-			props[key] = prop
-		}
-	case []interface{}:
-		props["items"] = map[string]interface{}{"type": "object"}
-	}
-
-	return props
-}
@@ -0,0 +1,200 @@
+package ai
+
+import (
+	"encoding/json"
+	"math"
+
+	"cli-convert/document"
+)
+
+// InferOptions ajusta a inferência de schema.
+type InferOptions struct {
+	// EnumMax ativa a detecção de enum: campos string com no máximo
+	// EnumMax valores distintos (e algum valor repetido) ganham "enum".
+	// Zero desativa.
+	EnumMax int
+}
+
+// typeOrder é a ordem em que os tipos aparecem em uniões.
+var typeOrder = []string{"object", "array", "string", "integer", "number", "boolean", "null"}
+
+// schemaNode acumula tudo o que foi observado em uma posição do documento,
+// unindo todos os registros em vez de olhar só o primeiro.
+type schemaNode struct {
+	types map[string]bool
+	// untyped marca um valor fora dos tipos da árvore: o nó fica sem
+	// "type" e aceita qualquer valor.
+	untyped bool
+
+	// Objetos: propriedades na ordem em que aparecem e em quantos objetos
+	// cada uma esteve presente.
+	objects    int
+	properties map[string]*schemaNode
+	order      []string
+	presence   map[string]int
+
+	// Arrays: um único nó para os itens de todos os arrays.
+	items *schemaNode
+
+	// Strings: valores distintos para a detecção de enum, até o limite.
+	strings      []string
+	seenStrings  map[string]bool
+	stringCount  int
+	enumOverflow bool
+}
+
+func newSchemaNode() *schemaNode {
+	return &schemaNode{types: make(map[string]bool)}
+}
+
+func (n *schemaNode) observe(value interface{}, opts InferOptions) {
+	switch v := value.(type) {
+	case nil:
+		n.types["null"] = true
+	case bool:
+		n.types["boolean"] = true
+	case int, int64:
+		n.types["integer"] = true
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			n.types["integer"] = true
+		} else {
+			n.types["number"] = true
+		}
+	case json.Number:
+		if document.IsInteger(v) {
+			n.types["integer"] = true
+		} else {
+			n.types["number"] = true
+		}
+	case string:
+		n.types["string"] = true
+		n.observeString(v, opts)
+	case document.DateTime:
+		n.types["string"] = true
+		n.observeString(v.Text, opts)
+	case *document.Object:
+		n.types["object"] = true
+		if n.properties == nil {
+			n.properties = make(map[string]*schemaNode)
+			n.presence = make(map[string]int)
+		}
+		n.objects++
+		for _, key := range v.Keys() {
+			child, ok := n.properties[key]
+			if !ok {
+				child = newSchemaNode()
+				n.properties[key] = child
+				n.order = append(n.order, key)
+			}
+			n.presence[key]++
+			item, _ := v.Get(key)
+			child.observe(item, opts)
+		}
+	case []interface{}:
+		n.types["array"] = true
+		if len(v) > 0 && n.items == nil {
+			n.items = newSchemaNode()
+		}
+		for _, item := range v {
+			n.items.observe(item, opts)
+		}
+	default:
+		n.untyped = true
+	}
+}
+
+func (n *schemaNode) observeString(s string, opts InferOptions) {
+	n.stringCount++
+	if opts.EnumMax <= 0 || n.enumOverflow {
+		return
+	}
+	if n.seenStrings == nil {
+		n.seenStrings = make(map[string]bool)
+	}
+	if !n.seenStrings[s] {
+		n.seenStrings[s] = true
+		n.strings = append(n.strings, s)
+		// Acima do limite, desiste de guardar valores.
+		if len(n.strings) > opts.EnumMax {
+			n.enumOverflow = true
+			n.seenStrings = nil
+			n.strings = nil
+		}
+	}
+}
+
+// schema converte o nó em um JSON Schema com as chaves em ordem estável.
+func (n *schemaNode) schema(opts InferOptions) *document.Object {
+	result := document.NewObject()
+
+	var types []interface{}
+	for _, name := range typeOrder {
+		// Inteiros e números juntos são simplesmente "number".
+		if name == "integer" && n.types["number"] {
+			continue
+		}
+		if n.types[name] {
+			types = append(types, name)
+		}
+	}
+	switch {
+	case n.untyped, len(types) == 0:
+	case len(types) == 1:
+		result.Set("type", types[0])
+	default:
+		result.Set("type", types)
+	}
+
+	if n.properties != nil {
+		properties := document.NewObject()
+		var required []interface{}
+		for _, key := range n.order {
+			properties.Set(key, n.properties[key].schema(opts))
+			if n.presence[key] == n.objects {
+				required = append(required, key)
+			}
+		}
+		result.Set("properties", properties)
+		if len(required) > 0 {
+			result.Set("required", required)
+		}
+	}
+
+	if n.items != nil {
+		result.Set("items", n.items.schema(opts))
+	}
+
+	if n.isEnum(opts) {
+		values := make([]interface{}, 0, len(n.strings))
+		for _, s := range n.strings {
+			values = append(values, s)
+		}
+		if n.types["null"] {
+			values = append(values, nil)
+		}
+		result.Set("enum", values)
+	}
+	return result
+}
+
+// isEnum indica se o campo tem poucos valores string distintos, repetidos
+// ao longo dos registros, e nenhum outro tipo além de null.
+func (n *schemaNode) isEnum(opts InferOptions) bool {
+	if opts.EnumMax <= 0 || n.untyped || len(n.strings) == 0 || n.stringCount <= len(n.strings) {
+		return false
+	}
+	for name := range n.types {
+		if name != "string" && name != "null" {
+			return false
+		}
+	}
+	return true
+}
+
+// inferSchemaNode une todos os valores de data em um único schema.
+func inferSchemaNode(data interface{}, opts InferOptions) *document.Object {
+	node := newSchemaNode()
+	node.observe(data, opts)
+	return node.schema(opts)
+}
@@ -0,0 +1,210 @@
+package ai
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvDelimiters são os delimitadores testados por SniffCSV, em ordem de
+// preferência para empates.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+// sniffSampleRecords limita quantos registros da amostra são analisados.
+const sniffSampleRecords = 100
+
+// CSVDialect é o dialeto deduzido de uma amostra CSV.
+type CSVDialect struct {
+	Delimiter rune
+	// HasHeader indica se a primeira linha parece ser um cabeçalho.
+	HasHeader bool
+	// Quoted indica que a amostra usa campos entre aspas.
+	Quoted bool
+	// Columns é o número de colunas da maioria das linhas.
+	Columns int
+	// Consistent indica que todas as linhas da amostra têm Columns campos.
+	Consistent bool
+}
+
+// DelimiterName devolve o delimitador em forma legível ("\t" vira "tab").
+func (d CSVDialect) DelimiterName() string {
+	if d.Delimiter == '\t' {
+		return "tab"
+	}
+	return string(d.Delimiter)
+}
+
+// SniffCSV testa ",", ";", tab e "|" sobre a amostra e escolhe o delimitador
+// que produz o mesmo número de colunas (duas ou mais) no maior número de
+// linhas; aspas bem formadas em volta dos campos desempatam. Com partial, a
+// última linha (possivelmente cortada) é ignorada. O segundo valor é false
+// quando nenhum delimitador separa a amostra em colunas.
+func SniffCSV(data []byte, partial bool) (CSVDialect, bool) {
+	sample := completeLines(strings.TrimPrefix(string(data), "\ufeff"), partial)
+
+	var best CSVDialect
+	bestScore := 0.0
+	var bestRecords [][]string
+	for _, delimiter := range csvDelimiters {
+		records, ok := sniffRecords(sample, delimiter)
+		if !ok {
+			continue
+		}
+		columns, matching := modalColumns(records)
+		if columns < 2 {
+			continue
+		}
+
+		quoted := quotedFields(sample, delimiter)
+		score := float64(matching) / float64(len(records))
+		if quoted > 0 {
+			score += 0.1
+		}
+		if score > bestScore {
+			bestScore = score
+			best = CSVDialect{
+				Delimiter:  delimiter,
+				Quoted:     quoted > 0,
+				Columns:    columns,
+				Consistent: matching == len(records),
+			}
+			bestRecords = records
+		}
+	}
+
+	if bestRecords == nil {
+		return CSVDialect{}, false
+	}
+	best.HasHeader = looksLikeHeader(bestRecords)
+	return best, true
+}
+
+// sniffRecords lê a amostra com o delimitador indicado. Linhas vazias e
+// iniciadas por "#" são ignoradas.
+func sniffRecords(sample string, delimiter rune) ([][]string, bool) {
+	reader := csv.NewReader(strings.NewReader(sample))
+	reader.Comma = delimiter
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+
+	var records [][]string
+	for len(records) < sniffSampleRecords {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// Uma aspa aberta no fim da amostra costuma ser só o corte.
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) && parseErr.Err == csv.ErrQuote && len(records) > 0 {
+				break
+			}
+			return nil, false
+		}
+		records = append(records, record)
+	}
+	return records, len(records) > 0
+}
+
+// modalColumns devolve o número de campos mais comum e quantas linhas o têm.
+// Empates ficam com o maior número de campos.
+func modalColumns(records [][]string) (int, int) {
+	counts := make(map[int]int)
+	for _, record := range records {
+		counts[len(record)]++
+	}
+	columns, matching := 0, 0
+	for n, count := range counts {
+		if count > matching || (count == matching && n > columns) {
+			columns, matching = n, count
+		}
+	}
+	return columns, matching
+}
+
+// quotedFields conta campos que abrem aspas logo após o delimitador ou no
+// início da linha e as fecham antes do próximo delimitador ou do fim da linha.
+func quotedFields(sample string, delimiter rune) int {
+	count := 0
+	sep := string(delimiter)
+	for _, line := range strings.Split(sample, "\n") {
+		line = strings.TrimRight(line, "\r")
+		for _, field := range strings.Split(line, sep) {
+			field = strings.TrimSpace(field)
+			if len(field) >= 2 && field[0] == '"' && field[len(field)-1] == '"' {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// looksLikeHeader compara a primeira linha com as demais, coluna a coluna:
+// uma coluna numérica (ou de tamanho fixo) cujo primeiro valor não é número
+// (ou tem outro tamanho) conta a favor do cabeçalho; uma primeira linha do
+// mesmo tipo conta contra. Sem sinal nenhum, a primeira linha é cabeçalho se
+// não tiver números nem valores repetidos.
+func looksLikeHeader(records [][]string) bool {
+	header := records[0]
+	seen := make(map[string]bool)
+	for _, name := range header {
+		name = strings.TrimSpace(name)
+		if isNumeric(name) || (name != "" && seen[name]) {
+			return false
+		}
+		seen[name] = true
+	}
+	if len(records) < 2 {
+		return true
+	}
+
+	votes := 0
+	for col, name := range header {
+		numeric, length := true, -1
+		values := 0
+		for _, record := range records[1:] {
+			if col >= len(record) {
+				continue
+			}
+			value := strings.TrimSpace(record[col])
+			if value == "" {
+				continue
+			}
+			values++
+			if !isNumeric(value) {
+				numeric = false
+			}
+			switch {
+			case length == -1:
+				length = len(value)
+			case length != len(value):
+				length = -2
+			}
+		}
+		if values == 0 {
+			continue
+		}
+
+		switch {
+		case numeric:
+			votes++
+		case length >= 0 && len(strings.TrimSpace(name)) != length:
+			votes++
+		case length >= 0:
+			votes--
+		}
+	}
+	return votes >= 0
+}
+
+// isNumeric aceita números com ponto ou vírgula decimal ("1.5", "1,5").
+func isNumeric(s string) bool {
+	if s == "" || !strings.ContainsAny(s[:1], "+-.0123456789") {
+		return false
+	}
+	s = strings.ReplaceAll(s, ",", ".")
+	_, err := strconv.ParseFloat(s, 64)
+	return err == nil
+}
@@ -0,0 +1,351 @@
+// Package charset converte texto entre UTF-8 e as codificações que
+// aparecem em arquivos de sistemas legados: UTF-16 (LE e BE), Latin-1
+// (ISO-8859-1) e Windows-1252. Também deduz a codificação de um trecho de
+// bytes, pelo BOM ou por heurística.
+//
+// Usa apenas a biblioteca padrão.
+package charset
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Nomes canônicos das codificações suportadas.
+const (
+	UTF8        = "utf-8"
+	UTF16LE     = "utf-16le"
+	UTF16BE     = "utf-16be"
+	Latin1      = "latin1"
+	Windows1252 = "windows-1252"
+)
+
+// aliases mapeia as grafias aceitas para o nome canônico.
+var aliases = map[string]string{
+	"utf-8":        UTF8,
+	"utf8":         UTF8,
+	"utf-16le":     UTF16LE,
+	"utf16le":      UTF16LE,
+	"utf-16be":     UTF16BE,
+	"utf16be":      UTF16BE,
+	"latin1":       Latin1,
+	"latin-1":      Latin1,
+	"iso-8859-1":   Latin1,
+	"iso8859-1":    Latin1,
+	"windows-1252": Windows1252,
+	"cp1252":       Windows1252,
+}
+
+// Normalize valida o nome de uma codificação e devolve o nome canônico.
+func Normalize(name string) (string, error) {
+	if canonical, ok := aliases[strings.ToLower(strings.TrimSpace(name))]; ok {
+		return canonical, nil
+	}
+	return "", fmt.Errorf("unsupported encoding: %s (use utf-8, utf-16le, utf-16be, latin1 or windows-1252)", name)
+}
+
+var boms = []struct {
+	encoding string
+	mark     []byte
+}{
+	{UTF8, []byte{0xEF, 0xBB, 0xBF}},
+	{UTF16LE, []byte{0xFF, 0xFE}},
+	{UTF16BE, []byte{0xFE, 0xFF}},
+}
+
+// DetectBOM devolve a codificação indicada pelo BOM no início de data e o
+// tamanho do BOM, ou "" e 0 quando não há BOM.
+func DetectBOM(data []byte) (string, int) {
+	for _, bom := range boms {
+		if bytes.HasPrefix(data, bom.mark) {
+			return bom.encoding, len(bom.mark)
+		}
+	}
+	return "", 0
+}
+
+// Guess deduz a codificação de data. Sem BOM, UTF-16 é reconhecido pelos
+// bytes zero dos caracteres ASCII; texto UTF-8 válido fica em UTF-8; o
+// resto é Windows-1252 quando usa os bytes 0x80-0x9F (aspas curvas, "€") e
+// Latin-1 caso contrário. Com partial, data é o começo de uma entrada maior
+// e um caractere cortado no fim não conta.
+func Guess(data []byte, partial bool) string {
+	if encoding, _ := DetectBOM(data); encoding != "" {
+		return encoding
+	}
+	if encoding := guessUTF16(data); encoding != "" {
+		return encoding
+	}
+	if partial {
+		data = trimIncompleteRune(data)
+	}
+	if utf8.Valid(data) {
+		return UTF8
+	}
+	for _, b := range data {
+		if b >= 0x80 && b <= 0x9F {
+			return Windows1252
+		}
+	}
+	return Latin1
+}
+
+// guessUTF16 procura o padrão de ASCII em UTF-16: um byte zero em quase
+// todas as posições pares (BE) ou ímpares (LE).
+func guessUTF16(data []byte) string {
+	pairs := min(len(data), 4096) / 2
+	if pairs < 2 {
+		return ""
+	}
+	even, odd := 0, 0
+	for i := 0; i < pairs*2; i += 2 {
+		if data[i] == 0 {
+			even++
+		}
+		if data[i+1] == 0 {
+			odd++
+		}
+	}
+	switch {
+	case odd*10 >= pairs*7 && even*10 < pairs:
+		return UTF16LE
+	case even*10 >= pairs*7 && odd*10 < pairs:
+		return UTF16BE
+	}
+	return ""
+}
+
+// trimIncompleteRune descarta uma sequência UTF-8 cortada no fim de data.
+func trimIncompleteRune(data []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				return data[:len(data)-i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// windows1252 dá os caracteres dos bytes 0x80-0x9F; os bytes sem
+// caractere definido ficam com o controle C1 de mesmo valor, como no
+// Latin-1.
+var windows1252 = [32]rune{
+	'€', '\u0081', '‚', 'ƒ', '„', '…', '†', '‡',
+	'ˆ', '‰', 'Š', '‹', 'Œ', '\u008D', 'Ž', '\u008F',
+	'\u0090', '‘', '’', '“', '”', '•', '–', '—',
+	'˜', '™', 'š', '›', 'œ', '\u009D', 'ž', 'Ÿ',
+}
+
+// NewReader devolve um leitor que entrega o conteúdo de r convertido de
+// encoding para UTF-8, sem o BOM. encoding deve ser um nome canônico.
+func NewReader(r io.Reader, encoding string) io.Reader {
+	src := bufio.NewReader(r)
+	if found, size := DetectBOM(peekBOM(src)); found == encoding {
+		src.Discard(size)
+	}
+	if encoding == UTF8 {
+		return src
+	}
+	return &reader{src: src, encoding: encoding}
+}
+
+func peekBOM(src *bufio.Reader) []byte {
+	data, _ := src.Peek(3)
+	return data
+}
+
+// Decode converte data de encoding para UTF-8, sem o BOM.
+func Decode(data []byte, encoding string) []byte {
+	decoded, _ := io.ReadAll(NewReader(bytes.NewReader(data), encoding))
+	return decoded
+}
+
+type reader struct {
+	src      *bufio.Reader
+	encoding string
+	out      []byte
+	err      error
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.out) < len(p) && r.err == nil {
+		char, err := r.next()
+		if err != nil {
+			r.err = err
+			break
+		}
+		r.out = utf8.AppendRune(r.out, char)
+	}
+	n := copy(p, r.out)
+	r.out = r.out[:copy(r.out, r.out[n:])]
+	if n == 0 && r.err != nil {
+		return 0, r.err
+	}
+	return n, nil
+}
+
+// next lê o próximo caractere da origem.
+func (r *reader) next() (rune, error) {
+	switch r.encoding {
+	case UTF16LE, UTF16BE:
+		unit, err := r.unit()
+		if err != nil {
+			return 0, err
+		}
+		if !utf16.IsSurrogate(rune(unit)) {
+			return rune(unit), nil
+		}
+		// Um par de surrogates forma um caractere fora do BMP; um
+		// surrogate sozinho vira U+FFFD.
+		if peek, err := r.src.Peek(2); err == nil {
+			low := r.decodeUnit(peek)
+			if char := utf16.DecodeRune(rune(unit), rune(low)); char != utf8.RuneError {
+				r.src.Discard(2)
+				return char, nil
+			}
+		}
+		return utf8.RuneError, nil
+	default:
+		b, err := r.src.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if r.encoding == Windows1252 && b >= 0x80 && b <= 0x9F {
+			return windows1252[b-0x80], nil
+		}
+		return rune(b), nil
+	}
+}
+
+// unit lê uma unidade UTF-16; um byte solto no fim vira U+FFFD.
+func (r *reader) unit() (uint16, error) {
+	var pair [2]byte
+	n, err := io.ReadFull(r.src, pair[:])
+	if n == 1 {
+		return utf8.RuneError, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return r.decodeUnit(pair[:]), nil
+}
+
+func (r *reader) decodeUnit(pair []byte) uint16 {
+	if r.encoding == UTF16BE {
+		return uint16(pair[0])<<8 | uint16(pair[1])
+	}
+	return uint16(pair[1])<<8 | uint16(pair[0])
+}
+
+// Writer converte o UTF-8 escrito nele para a codificação de destino.
+// Saídas UTF-16 começam com BOM; em Latin-1 e Windows-1252 o BOM é
+// descartado e caracteres sem representação viram "?".
+type Writer struct {
+	dst      io.Writer
+	encoding string
+	// OnReplace, se definido, é chamado para cada caractere trocado por "?".
+	OnReplace func(char rune)
+	pending   []byte
+	started   bool
+}
+
+// NewWriter devolve um Writer que grava em dst na codificação indicada,
+// que deve ser um nome canônico.
+func NewWriter(dst io.Writer, encoding string) *Writer {
+	return &Writer{dst: dst, encoding: encoding}
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.encoding == UTF8 {
+		return w.dst.Write(p)
+	}
+
+	data := p
+	if len(w.pending) > 0 {
+		data = append(w.pending, p...)
+		w.pending = nil
+	}
+
+	out := make([]byte, 0, len(data)*2)
+	for len(data) > 0 {
+		if !utf8.FullRune(data) {
+			w.pending = append([]byte(nil), data...)
+			break
+		}
+		char, size := utf8.DecodeRune(data)
+		data = data[size:]
+		out = w.appendRune(out, char)
+	}
+
+	if _, err := w.dst.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *Writer) appendRune(out []byte, char rune) []byte {
+	if !w.started {
+		w.started = true
+		switch w.encoding {
+		case UTF16LE, UTF16BE:
+			out = w.appendUnit(out, 0xFEFF)
+			if char == '\uFEFF' {
+				return out
+			}
+		default:
+			if char == '\uFEFF' {
+				return out
+			}
+		}
+	}
+
+	switch w.encoding {
+	case UTF16LE, UTF16BE:
+		if char > 0xFFFF {
+			high, low := utf16.EncodeRune(char)
+			out = w.appendUnit(out, uint16(high))
+			return w.appendUnit(out, uint16(low))
+		}
+		return w.appendUnit(out, uint16(char))
+	}
+
+	if char < 0x80 || (w.encoding == Latin1 && char <= 0xFF) || (char >= 0xA0 && char <= 0xFF) {
+		return append(out, byte(char))
+	}
+	if w.encoding == Windows1252 {
+		for i, candidate := range windows1252 {
+			if candidate == char {
+				return append(out, byte(0x80+i))
+			}
+		}
+	}
+	if w.OnReplace != nil {
+		w.OnReplace(char)
+	}
+	return append(out, '?')
+}
+
+func (w *Writer) appendUnit(out []byte, unit uint16) []byte {
+	if w.encoding == UTF16BE {
+		return append(out, byte(unit>>8), byte(unit))
+	}
+	return append(out, byte(unit), byte(unit>>8))
+}
+
+// Close grava o que sobrou de um caractere UTF-8 incompleto no fim da
+// escrita (como U+FFFD). Não fecha dst.
+func (w *Writer) Close() error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	w.pending = nil
+	_, err := w.dst.Write(w.appendRune(nil, utf8.RuneError))
+	return err
+}
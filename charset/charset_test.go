@@ -0,0 +1,121 @@
+package charset
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+const sample = "ação, “citação” €5 😀"
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+	}{
+		{UTF8, sample},
+		{UTF16LE, sample},
+		{UTF16BE, sample},
+		{Latin1, "ação, ñ ü ©"},
+		{Windows1252, "ação, “citação” €5 — Œ"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			var encoded bytes.Buffer
+			writer := NewWriter(&encoded, tt.encoding)
+			// Escreve byte a byte para cortar os caracteres entre chamadas.
+			for i := 0; i < len(tt.text); i++ {
+				if _, err := writer.Write([]byte{tt.text[i]}); err != nil {
+					t.Fatalf("Unexpected write error: %v", err)
+				}
+			}
+			if err := writer.Close(); err != nil {
+				t.Fatalf("Unexpected close error: %v", err)
+			}
+
+			decoded, err := io.ReadAll(NewReader(&encoded, tt.encoding))
+			if err != nil {
+				t.Fatalf("Unexpected read error: %v", err)
+			}
+			if string(decoded) != tt.text {
+				t.Errorf("Round trip changed the text:\nExpected: %q\nGot:      %q", tt.text, decoded)
+			}
+		})
+	}
+}
+
+func TestEncodedBytes(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		expected []byte
+	}{
+		{UTF16LE, "aé", []byte{0xFF, 0xFE, 'a', 0, 0xE9, 0}},
+		{UTF16BE, "\ufeffa", []byte{0xFE, 0xFF, 0, 'a'}},
+		{Latin1, "\ufeffçã", []byte{0xE7, 0xE3}},
+		{Windows1252, "“€”", []byte{0x93, 0x80, 0x94}},
+	}
+
+	for _, tt := range tests {
+		var encoded bytes.Buffer
+		NewWriter(&encoded, tt.encoding).Write([]byte(tt.text))
+		if !bytes.Equal(encoded.Bytes(), tt.expected) {
+			t.Errorf("%s: expected % x, got % x", tt.encoding, tt.expected, encoded.Bytes())
+		}
+	}
+}
+
+func TestWriterReplacesUnencodable(t *testing.T) {
+	var encoded bytes.Buffer
+	var replaced []rune
+	writer := NewWriter(&encoded, Latin1)
+	writer.OnReplace = func(char rune) { replaced = append(replaced, char) }
+	writer.Write([]byte("a€b😀"))
+
+	if encoded.String() != "a?b?" {
+		t.Errorf("Unexpected output: %q", encoded.String())
+	}
+	if string(replaced) != "€😀" {
+		t.Errorf("Unexpected replaced characters: %q", string(replaced))
+	}
+}
+
+func TestGuess(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		partial  bool
+		expected string
+	}{
+		{"utf-8 bom", []byte("\xEF\xBB\xBFid"), false, UTF8},
+		{"utf-16le bom", []byte{0xFF, 0xFE, 'i', 0}, false, UTF16LE},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0, 'i'}, false, UTF16BE},
+		{"utf-16le without bom", []byte{'i', 0, 'd', 0, ',', 0, 'x', 0}, false, UTF16LE},
+		{"utf-16be without bom", []byte{0, 'i', 0, 'd', 0, ',', 0, 'x'}, false, UTF16BE},
+		{"ascii", []byte("id,nome\n1,Ana\n"), false, UTF8},
+		{"utf-8", []byte("nome\nJoão\n"), false, UTF8},
+		{"utf-8 cut in the middle of a character", []byte("nome\nJo\xC3"), true, UTF8},
+		{"latin1", []byte("nome\nJo\xE3o\n"), false, Latin1},
+		{"windows-1252 quotes", []byte("\x93Jo\xE3o\x94\n"), false, Windows1252},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Guess(tt.data, tt.partial); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for name, expected := range map[string]string{"UTF8": UTF8, "ISO-8859-1": Latin1, "cp1252": Windows1252, " utf-16LE ": UTF16LE} {
+		if got, err := Normalize(name); err != nil || got != expected {
+			t.Errorf("Normalize(%q) = %q, %v; expected %q", name, got, err, expected)
+		}
+	}
+	if _, err := Normalize("ebcdic"); err == nil {
+		t.Error("Expected error for unsupported encoding")
+	}
+}
@@ -0,0 +1,293 @@
+// Package codegen gera tipos Go e TypeScript a partir de um JSON Schema
+// inferido (o documento produzido por ai.InferSchema). Objetos aninhados
+// viram tipos nomeados, arrays viram slices/listas e campos ausentes em
+// algum registro são marcados como opcionais.
+package codegen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"cli-convert/document"
+)
+
+// Options ajusta a geração de código.
+type Options struct {
+	// Name é o nome do tipo raiz; quando a raiz é uma lista de registros,
+	// o tipo de cada registro usa o singular desse nome.
+	Name string
+	// Package é o pacote do arquivo Go gerado (padrão: "main").
+	Package string
+	// Source aparece no cabeçalho do arquivo gerado.
+	Source string
+	// AttrPrefix e TextKey identificam atributos e texto de elementos XML
+	// na árvore lida (ex.: "@" e "#text"), para gerar as tags xml certas.
+	AttrPrefix string
+	TextKey    string
+}
+
+// generators associa cada linguagem ao seu gerador.
+var generators = map[string]func(*model, Options) ([]byte, error){
+	"go":         generateGo,
+	"typescript": generateTypeScript,
+}
+
+// Languages lista as linguagens aceitas por Generate.
+func Languages() []string {
+	var names []string
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Generate produz o código dos tipos descritos por schema na linguagem
+// lang ("go" ou "typescript"; "ts" também é aceito).
+func Generate(lang string, schema *document.Object, opts Options) ([]byte, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "ts" {
+		lang = "typescript"
+	}
+	generate, ok := generators[lang]
+	if !ok {
+		return nil, fmt.Errorf("unsupported language: %s (supported: %s)", lang, strings.Join(Languages(), ", "))
+	}
+	if opts.Name == "" {
+		opts.Name = "Root"
+	}
+	return generate(buildModel(schema, opts), opts)
+}
+
+// Tipos de typeRef.
+const (
+	kindAny     = "any"
+	kindString  = "string"
+	kindInteger = "integer"
+	kindNumber  = "number"
+	kindBoolean = "boolean"
+	kindObject  = "object" // struct nomeada
+	kindMap     = "map"    // objeto sem propriedades conhecidas
+	kindArray   = "array"
+	kindUnion   = "union"
+)
+
+// typeRef é o tipo de um valor, independente da linguagem.
+type typeRef struct {
+	kind     string
+	name     string     // kindObject
+	elem     *typeRef   // kindArray
+	members  []*typeRef // kindUnion
+	nullable bool
+}
+
+type field struct {
+	key      string
+	typ      *typeRef
+	optional bool
+}
+
+// namedType é um objeto do schema que vira um tipo com nome.
+type namedType struct {
+	name   string
+	fields []field
+}
+
+// model reúne o tipo raiz e todos os tipos nomeados, na ordem em que foram
+// encontrados.
+type model struct {
+	root *typeRef
+	// rootName é o nome do tipo raiz quando ele não é uma struct (ex.: a
+	// lista de registros).
+	rootName string
+	types    []*namedType
+	used     map[string]bool
+}
+
+func buildModel(schema *document.Object, opts Options) *model {
+	m := &model{used: make(map[string]bool)}
+	m.rootName = typeName(opts.Name)
+
+	if types, _ := schemaTypes(schema); len(types) != 1 || types[0] != "object" {
+		// O nome fica para o alias da raiz (ex.: a lista de registros, cujo
+		// tipo de registro usa o singular).
+		m.used[m.rootName] = true
+	}
+	m.root = m.resolve(schema, m.rootName, "")
+	return m
+}
+
+// schemaTypes separa "null" dos demais tipos declarados em "type".
+func schemaTypes(schema *document.Object) (types []string, nullable bool) {
+	value, _ := schema.Get("type")
+	var list []interface{}
+	switch v := value.(type) {
+	case string:
+		list = []interface{}{v}
+	case []interface{}:
+		list = v
+	}
+	for _, item := range list {
+		name, _ := item.(string)
+		if name == "null" {
+			nullable = true
+		} else if name != "" {
+			types = append(types, name)
+		}
+	}
+	return types, nullable
+}
+
+// resolve converte um subschema em typeRef. suggested é o nome usado se o
+// subschema for um objeto; parent desempata nomes repetidos.
+func (m *model) resolve(schema *document.Object, suggested, parent string) *typeRef {
+	types, nullable := schemaTypes(schema)
+	switch len(types) {
+	case 0:
+		return &typeRef{kind: kindAny}
+	case 1:
+		ref := m.resolveType(types[0], schema, suggested, parent)
+		ref.nullable = nullable
+		return ref
+	}
+
+	union := &typeRef{kind: kindUnion, nullable: nullable}
+	for _, name := range types {
+		union.members = append(union.members, m.resolveType(name, schema, suggested, parent))
+	}
+	return union
+}
+
+func (m *model) resolveType(name string, schema *document.Object, suggested, parent string) *typeRef {
+	switch name {
+	case "string", "integer", "number", "boolean":
+		return &typeRef{kind: name}
+	case "array":
+		items, ok := schemaChild(schema, "items")
+		if !ok {
+			return &typeRef{kind: kindArray, elem: &typeRef{kind: kindAny}}
+		}
+		elemName := singular(suggested)
+		if elemName == suggested {
+			elemName += "Item"
+		}
+		return &typeRef{kind: kindArray, elem: m.resolve(items, elemName, parent)}
+	case "object":
+		props, ok := schemaChild(schema, "properties")
+		if !ok || props.Len() == 0 {
+			return &typeRef{kind: kindMap}
+		}
+		return &typeRef{kind: kindObject, name: m.addObject(props, schema, suggested, parent)}
+	}
+	return &typeRef{kind: kindAny}
+}
+
+// addObject registra o tipo nomeado do objeto e resolve seus campos.
+func (m *model) addObject(props, schema *document.Object, suggested, parent string) string {
+	named := &namedType{name: m.uniqueName(suggested, parent)}
+	m.types = append(m.types, named)
+
+	required := make(map[string]bool)
+	if list, ok := schema.Get("required"); ok {
+		if items, ok := list.([]interface{}); ok {
+			for _, item := range items {
+				if key, ok := item.(string); ok {
+					required[key] = true
+				}
+			}
+		}
+	}
+
+	for _, key := range props.Keys() {
+		value, _ := props.Get(key)
+		child, ok := value.(*document.Object)
+		if !ok {
+			child = document.NewObject()
+		}
+		named.fields = append(named.fields, field{
+			key:      key,
+			typ:      m.resolve(child, typeName(key), named.name),
+			optional: !required[key],
+		})
+	}
+	return named.name
+}
+
+// uniqueName evita dois tipos com o mesmo nome: primeiro prefixa o nome do
+// tipo pai, depois numera.
+func (m *model) uniqueName(suggested, parent string) string {
+	name := suggested
+	if m.used[name] && parent != "" {
+		name = parent + suggested
+	}
+	base := name
+	for i := 2; m.used[name]; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	m.used[name] = true
+	return name
+}
+
+func schemaChild(schema *document.Object, key string) (*document.Object, bool) {
+	value, ok := schema.Get(key)
+	if !ok {
+		return nil, false
+	}
+	obj, ok := value.(*document.Object)
+	return obj, ok
+}
+
+// initialisms são escritas em maiúsculas nos nomes gerados, como manda o
+// estilo Go (ID, URL...).
+var initialisms = map[string]bool{
+	"id": true, "url": true, "uri": true, "api": true, "http": true, "https": true,
+	"json": true, "xml": true, "yaml": true, "html": true, "uuid": true, "ip": true,
+	"sql": true, "csv": true,
+}
+
+// typeName converte uma chave qualquer ("first_name", "@id", "user-id") em
+// um identificador exportado ("FirstName", "ID", "UserID").
+func typeName(key string) string {
+	parts := strings.FieldsFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, part := range parts {
+		if initialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	name := b.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		name = "N" + name
+	}
+	return name
+}
+
+// singular tira o plural inglês mais comum de um nome ("Users" → "User",
+// "Categories" → "Category"). Nomes sem plural reconhecível voltam iguais.
+func singular(name string) string {
+	switch {
+	case strings.HasSuffix(name, "ies") && len(name) > 4:
+		return strings.TrimSuffix(name, "ies") + "y"
+	case strings.HasSuffix(name, "sses"), strings.HasSuffix(name, "shes"),
+		strings.HasSuffix(name, "ches"), strings.HasSuffix(name, "xes"):
+		return strings.TrimSuffix(name, "es")
+	case strings.HasSuffix(name, "ss"), strings.HasSuffix(name, "us"), strings.HasSuffix(name, "is"):
+		return name
+	case strings.HasSuffix(name, "s") && len(name) > 3:
+		return strings.TrimSuffix(name, "s")
+	}
+	return name
+}
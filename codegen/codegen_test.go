@@ -0,0 +1,179 @@
+package codegen
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"cli-convert/document"
+)
+
+func mustSchema(t *testing.T, text string) *document.Object {
+	t.Helper()
+	value, err := document.Unmarshal([]byte(text))
+	if err != nil {
+		t.Fatalf("invalid test JSON %s: %v", text, err)
+	}
+	return value.(*document.Object)
+}
+
+// usersSchema tem o formato produzido por ai.InferSchema para uma lista de
+// registros heterogêneos.
+const usersSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "array",
+	"items": {
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer"},
+			"full_name": {"type": ["string", "null"]},
+			"address": {"type": "object", "properties": {"city": {"type": "string"}}, "required": ["city"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"orders": {"type": "array", "items": {"type": "object", "properties": {"sku": {"type": "string"}, "qty": {"type": ["integer", "string"]}}, "required": ["sku"]}},
+			"meta": {"type": "object"},
+			"score": {"type": "number"}
+		},
+		"required": ["id", "full_name", "tags", "orders"]
+	}
+}`
+
+func TestGenerateGo(t *testing.T) {
+	code, err := Generate("go", mustSchema(t, usersSchema), Options{Name: "users", Package: "models", Source: "users.json"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := "// Code generated by cli-convert codegen from users.json. DO NOT EDIT.\n" +
+		"\n" +
+		"package models\n" +
+		"\n" +
+		"type Users []User\n" +
+		"\n" +
+		"type User struct {\n" +
+		"\tID       int64                  `json:\"id\" xml:\"id\" yaml:\"id\"`\n" +
+		"\tFullName *string                `json:\"full_name\" xml:\"full_name\" yaml:\"full_name\"`\n" +
+		"\tAddress  *Address               `json:\"address,omitempty\" xml:\"address,omitempty\" yaml:\"address,omitempty\"`\n" +
+		"\tTags     []string               `json:\"tags\" xml:\"tags\" yaml:\"tags\"`\n" +
+		"\tOrders   []Order                `json:\"orders\" xml:\"orders\" yaml:\"orders\"`\n" +
+		"\tMeta     map[string]interface{} `json:\"meta,omitempty\" xml:\"meta,omitempty\" yaml:\"meta,omitempty\"`\n" +
+		"\tScore    float64                `json:\"score,omitempty\" xml:\"score,omitempty\" yaml:\"score,omitempty\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Address struct {\n" +
+		"\tCity string `json:\"city\" xml:\"city\" yaml:\"city\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Order struct {\n" +
+		"\tSku string      `json:\"sku\" xml:\"sku\" yaml:\"sku\"`\n" +
+		"\tQty interface{} `json:\"qty,omitempty\" xml:\"qty,omitempty\" yaml:\"qty,omitempty\"`\n" +
+		"}\n"
+	if string(code) != expected {
+		t.Errorf("Unexpected Go code:\nExpected:\n%s\nGot:\n%s", expected, code)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "users.go", code, 0); err != nil {
+		t.Errorf("Generated Go code does not parse: %v", err)
+	}
+}
+
+func TestGenerateTypeScript(t *testing.T) {
+	code, err := Generate("ts", mustSchema(t, usersSchema), Options{Name: "users"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := `// Gerado por cli-convert codegen. Não edite manualmente.
+
+export type Users = User[];
+
+export interface User {
+  id: number;
+  full_name: string | null;
+  address?: Address;
+  tags: string[];
+  orders: Order[];
+  meta?: Record<string, unknown>;
+  score?: number;
+}
+
+export interface Address {
+  city: string;
+}
+
+export interface Order {
+  sku: string;
+  qty?: number | string;
+}
+`
+	if string(code) != expected {
+		t.Errorf("Unexpected TypeScript code:\nExpected:\n%s\nGot:\n%s", expected, code)
+	}
+}
+
+func TestGenerateGo_XMLTagsAndNameClashes(t *testing.T) {
+	schema := mustSchema(t, `{
+		"type": "object",
+		"properties": {
+			"@id": {"type": "integer"},
+			"#text": {"type": "string"},
+			"item": {"type": "object", "properties": {"item": {"type": "object", "properties": {"n": {"type": "integer"}}}}}
+		},
+		"required": ["@id", "#text", "item"]
+	}`)
+	code, err := Generate("go", schema, Options{Name: "order", AttrPrefix: "@", TextKey: "#text"})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	expected := "// Code generated by cli-convert codegen. DO NOT EDIT.\n" +
+		"\n" +
+		"package main\n" +
+		"\n" +
+		"type Order struct {\n" +
+		"\tID   int64  `json:\"@id\" xml:\"id,attr\" yaml:\"@id\"`\n" +
+		"\tText string `json:\"#text\" xml:\",chardata\" yaml:\"#text\"`\n" +
+		"\tItem Item   `json:\"item\" xml:\"item\" yaml:\"item\"`\n" +
+		"}\n" +
+		"\n" +
+		"type Item struct {\n" +
+		"\tItem *ItemItem `json:\"item,omitempty\" xml:\"item,omitempty\" yaml:\"item,omitempty\"`\n" +
+		"}\n" +
+		"\n" +
+		"type ItemItem struct {\n" +
+		"\tN int64 `json:\"n,omitempty\" xml:\"n,omitempty\" yaml:\"n,omitempty\"`\n" +
+		"}\n"
+	if string(code) != expected {
+		t.Errorf("Unexpected Go code:\nExpected:\n%s\nGot:\n%s", expected, code)
+	}
+}
+
+func TestGenerate_UnsupportedLanguage(t *testing.T) {
+	if _, err := Generate("rust", document.NewObject(), Options{}); err == nil {
+		t.Error("Expected error for unsupported language")
+	}
+}
+
+func TestNames(t *testing.T) {
+	names := map[string]string{
+		"first_name": "FirstName",
+		"@id":        "ID",
+		"user-id":    "UserID",
+		"firstName":  "FirstName",
+		"2fa":        "N2fa",
+		"#":          "Field",
+	}
+	for key, expected := range names {
+		if got := typeName(key); got != expected {
+			t.Errorf("typeName(%q) = %q, expected %q", key, got, expected)
+		}
+	}
+
+	singulars := map[string]string{
+		"Users": "User", "Categories": "Category", "Boxes": "Box",
+		"Status": "Status", "Address": "Address", "Data": "Data",
+	}
+	for plural, expected := range singulars {
+		if got := singular(plural); got != expected {
+			t.Errorf("singular(%q) = %q, expected %q", plural, got, expected)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strings"
+)
+
+// generateGo escreve uma struct por objeto, com tags json, xml e yaml.
+// Campos opcionais recebem omitempty; campos que podem ser null (e structs
+// opcionais, que omitempty não omite) viram ponteiros.
+func generateGo(m *model, opts Options) ([]byte, error) {
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "main"
+	}
+
+	var b bytes.Buffer
+	b.WriteString("// Code generated by cli-convert codegen")
+	if opts.Source != "" {
+		fmt.Fprintf(&b, " from %s", opts.Source)
+	}
+	b.WriteString(". DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n", pkg)
+
+	// A raiz que não é struct vira um tipo nomeado (ex.: a lista de
+	// registros).
+	if m.root.kind != kindObject || m.root.name != m.rootName {
+		fmt.Fprintf(&b, "\ntype %s %s\n", m.rootName, goType(m.root, false))
+	}
+
+	for _, named := range m.types {
+		fmt.Fprintf(&b, "\ntype %s struct {\n", named.name)
+		names := make(map[string]bool)
+		for _, f := range named.fields {
+			name := typeName(strings.TrimPrefix(f.key, opts.AttrPrefix))
+			if opts.TextKey != "" && f.key == opts.TextKey {
+				name = "Text"
+			}
+			unique := name
+			for i := 2; names[unique]; i++ {
+				unique = fmt.Sprintf("%s%d", name, i)
+			}
+			names[unique] = true
+
+			pointer := f.typ.nullable || f.optional && f.typ.kind == kindObject
+			fmt.Fprintf(&b, "\t%s %s `%s`\n", unique, goType(f.typ, pointer), goTags(f, opts))
+		}
+		b.WriteString("}\n")
+	}
+
+	source, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format Go code: %v", err)
+	}
+	return source, nil
+}
+
+// goType devolve o tipo Go de ref. Slices, mapas e interface{} já aceitam
+// nil e nunca viram ponteiro.
+func goType(ref *typeRef, pointer bool) string {
+	var name string
+	switch ref.kind {
+	case kindString:
+		name = "string"
+	case kindInteger:
+		name = "int64"
+	case kindNumber:
+		name = "float64"
+	case kindBoolean:
+		name = "bool"
+	case kindObject:
+		name = ref.name
+	case kindMap:
+		return "map[string]interface{}"
+	case kindArray:
+		return "[]" + goType(ref.elem, ref.elem.nullable)
+	default:
+		return "interface{}"
+	}
+	if pointer {
+		return "*" + name
+	}
+	return name
+}
+
+// goTags monta as tags json, xml e yaml de um campo. Atributos e texto de
+// elementos XML usam as opções ",attr" e ",chardata".
+func goTags(f field, opts Options) string {
+	omit := ""
+	if f.optional {
+		omit = ",omitempty"
+	}
+
+	xmlTag := f.key + omit
+	switch {
+	case opts.TextKey != "" && f.key == opts.TextKey:
+		xmlTag = ",chardata"
+	case opts.AttrPrefix != "" && strings.HasPrefix(f.key, opts.AttrPrefix):
+		xmlTag = strings.TrimPrefix(f.key, opts.AttrPrefix) + ",attr" + omit
+	}
+
+	return fmt.Sprintf(`json:"%s%s" xml:"%s" yaml:"%s%s"`, tagEscape(f.key), omit, tagEscape(xmlTag), tagEscape(f.key), omit)
+}
+
+// tagEscape protege aspas e barras invertidas dentro de uma tag.
+func tagEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, `"`, `\"`)
+}
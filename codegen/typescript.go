@@ -0,0 +1,95 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// generateTypeScript escreve uma interface por objeto. Campos opcionais
+// recebem "?" e campos que podem ser null ganham "| null".
+func generateTypeScript(m *model, opts Options) ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteString("// Gerado por cli-convert codegen")
+	if opts.Source != "" {
+		fmt.Fprintf(&b, " a partir de %s", opts.Source)
+	}
+	b.WriteString(". Não edite manualmente.\n")
+
+	if m.root.kind != kindObject || m.root.name != m.rootName {
+		fmt.Fprintf(&b, "\nexport type %s = %s;\n", m.rootName, tsType(m.root))
+	}
+
+	for _, named := range m.types {
+		fmt.Fprintf(&b, "\nexport interface %s {\n", named.name)
+		for _, f := range named.fields {
+			optional := ""
+			if f.optional {
+				optional = "?"
+			}
+			fmt.Fprintf(&b, "  %s%s: %s;\n", tsPropertyName(f.key), optional, tsType(f.typ))
+		}
+		b.WriteString("}\n")
+	}
+	return b.Bytes(), nil
+}
+
+func tsType(ref *typeRef) string {
+	var name string
+	switch ref.kind {
+	case kindString:
+		name = "string"
+	case kindInteger, kindNumber:
+		name = "number"
+	case kindBoolean:
+		name = "boolean"
+	case kindObject:
+		name = ref.name
+	case kindMap:
+		name = "Record<string, unknown>"
+	case kindArray:
+		elem := tsType(ref.elem)
+		if strings.Contains(elem, "|") {
+			elem = "(" + elem + ")"
+		}
+		name = elem + "[]"
+	case kindUnion:
+		var members []string
+		seen := make(map[string]bool)
+		for _, member := range ref.members {
+			// integer e number são o mesmo tipo em TypeScript.
+			if t := tsType(member); !seen[t] {
+				seen[t] = true
+				members = append(members, t)
+			}
+		}
+		name = strings.Join(members, " | ")
+	default:
+		// Só null (ou nenhum tipo conhecido).
+		if ref.nullable {
+			return "null"
+		}
+		return "unknown"
+	}
+	if ref.nullable {
+		return name + " | null"
+	}
+	return name
+}
+
+// tsPropertyName deixa identificadores válidos sem aspas e coloca as demais
+// chaves entre aspas.
+func tsPropertyName(key string) string {
+	for i, r := range key {
+		if r == '_' || r == '$' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r) {
+			continue
+		}
+		return strconv.Quote(key)
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
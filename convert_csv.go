@@ -1,142 +1,226 @@
 package main
 
 import (
+	"bufio"
 	"encoding/csv"
-	"encoding/json"
-	"encoding/xml"
 	"fmt"
 	"io"
-	"sort"
-	"strconv"
 	"strings"
+
+	"cli-convert/document"
 )
 
-func convertCsvToJson(input io.Reader, output io.Writer, delimiter rune) error {
-	file, err := io.ReadAll(input)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %v", err)
-	}
+// csvHeaderSample é a quantidade de registros lidos antes de fixar o
+// cabeçalho quando os registros chegam em streaming.
+const csvHeaderSample = 1000
 
-	reader := csv.NewReader(strings.NewReader(string(file)))
-	reader.Comma = delimiter
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to parse CSV: %v", err)
-	}
+// csvFormat lê e escreve registros separados por opts.Delimiter.
+type csvFormat struct{}
 
-	if len(records) == 0 {
-		return fmt.Errorf("empty CSV file")
-	}
+func (csvFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	return readCsvDocument(input, opts)
+}
 
-	header := records[0]
-	var rows []map[string]interface{}
+func (csvFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	return writeCsvDocument(data, output, opts)
+}
 
-	for _, record := range records[1:] {
-		row := make(map[string]interface{})
-		for j, value := range record {
-			cleanValue := strings.TrimSpace(value)
-			row[header[j]] = parseValue(cleanValue)
-		}
-		rows = append(rows, row)
+func (csvFormat) WriteStream(next recordStream, output io.Writer, opts convertOptions) error {
+	writer := newCSVWriter(output, opts.Delimiter, opts.Dialect)
+	if err := streamDataAsCSV(writer, next, csvHeaderSample, opts.Lossy); err != nil {
+		return err
 	}
+	return writer.Flush()
+}
+
+// Modos de --ragged-rows para linhas com quantidade de campos diferente
+// do cabeçalho.
+const (
+	// raggedError interrompe a leitura (padrão).
+	raggedError = "error"
+	// raggedPad completa linhas curtas com null e descarta os campos
+	// excedentes das longas.
+	raggedPad = "pad"
+	// raggedExtra completa linhas curtas com null e guarda os campos
+	// excedentes em um array na chave csvExtraKey.
+	raggedExtra = "extra"
+)
 
-	jsonBytes, err := json.MarshalIndent(rows, "", "  ")
+// csvExtraKey recebe os campos além do cabeçalho no modo raggedExtra.
+const csvExtraKey = "_extra"
+
+// readCsvDocument lê os registros usando a primeira linha como cabeçalho.
+// opts.SkipRows descarta linhas do início (preâmbulos de relatório) antes
+// da leitura; com opts.Header ou opts.NoHeader o arquivo não tem linha de
+// cabeçalho e os nomes vêm da opção ou são gerados (col1, col2, ...).
+func readCsvDocument(input io.Reader, opts convertOptions) (interface{}, error) {
+	buffered, err := skipCsvPreamble(input, opts)
 	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %v", err)
+		return nil, err
 	}
-
-	_, err = output.Write(jsonBytes)
-	return err
+	reader := csv.NewReader(buffered)
+	reader.Comma = opts.Delimiter
+	reader.Comment = opts.Comment
+	reader.FieldsPerRecord = -1
+	return readRecordDocument(reader, opts)
 }
 
-func convertCsvToXml(input io.Reader, output io.Writer, delimiter rune, rootName string) error {
-	reader := csv.NewReader(input)
-	reader.Comma = delimiter
+// recordSource entrega as linhas já separadas em campos: *csv.Reader ou
+// *tsvReader.
+type recordSource interface {
+	Read() ([]string, error)
+	FieldPos(field int) (line, column int)
+}
 
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to parse CSV: %v", err)
+// skipCsvPreamble descarta o BOM e as opts.SkipRows primeiras linhas.
+func skipCsvPreamble(input io.Reader, opts convertOptions) (*bufio.Reader, error) {
+	buffered := bufio.NewReader(input)
+	// O BOM UTF-8 gravado por --bom (e pelo Excel) não faz parte do
+	// primeiro nome de coluna.
+	if bom, _ := buffered.Peek(3); string(bom) == "\ufeff" {
+		buffered.Discard(3)
 	}
-
-	if len(records) < 1 {
-		return fmt.Errorf("empty CSV file")
+	for i := 0; i < opts.SkipRows; i++ {
+		if _, err := buffered.ReadString('\n'); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read input file: %v", err)
+		}
 	}
+	return buffered, nil
+}
 
-	header := records[0]
-	var rows []map[string]interface{}
+// readRecordDocument monta os registros das linhas de source, com o
+// cabeçalho vindo da primeira linha ou de opts.
+func readRecordDocument(source recordSource, opts convertOptions) (interface{}, error) {
+	var header []string
+	if len(opts.Header) > 0 {
+		header = dedupeCsvHeader(opts.Header)
+	}
 
-	for _, record := range records[1:] {
-		row := make(map[string]interface{})
-		for j, value := range record {
+	rows := []interface{}{}
+	for index := 0; ; index++ {
+		record, err := source.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %v", err)
+		}
+		line, _ := source.FieldPos(0)
+		line += opts.SkipRows
 
-			cleanValue := strings.TrimSpace(value)
-			if num, err := strconv.ParseFloat(cleanValue, 64); err == nil {
-				row[header[j]] = num
+		if header == nil {
+			if opts.NoHeader {
+				header = generatedCsvHeader(len(record))
 			} else {
-				row[header[j]] = cleanValue
+				header = dedupeCsvHeader(record)
+				continue
 			}
 		}
+
+		row, err := csvRecord(record, header, line, opts)
+		if err != nil {
+			return nil, err
+		}
 		rows = append(rows, row)
 	}
 
-	xmlRoot := XmlElement{XMLName: xml.Name{Local: rootName}}
-	for _, rowMap := range rows {
-		rowElem := convertToXmlElement(rowMap, "row")
-		xmlRoot.Children = append(xmlRoot.Children, rowElem)
+	if header == nil {
+		return nil, fmt.Errorf("empty CSV file")
 	}
+	return rows, nil
+}
 
-	xmlData, err := xml.MarshalIndent(xmlRoot, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal XML: %v", err)
+// csvRecord monta o registro de uma linha, aplicando opts.RaggedRows
+// quando a linha não tem a mesma quantidade de campos do cabeçalho.
+func csvRecord(record, header []string, line int, opts convertOptions) (*document.Object, error) {
+	if len(record) != len(header) && (opts.RaggedRows == "" || opts.RaggedRows == raggedError) {
+		return nil, fmt.Errorf("line %d: %d field(s), header has %d (use --ragged-rows pad or extra)", line, len(record), len(header))
 	}
 
-	xmlHeader := []byte(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
-
-	if _, err := output.Write(append(xmlHeader, xmlData...)); err != nil {
-		return fmt.Errorf("failed to write XML output file: %v", err)
+	row := document.NewObject()
+	for j, name := range header {
+		if j >= len(record) {
+			row.Set(name, nil)
+			continue
+		}
+		value, err := opts.Types.value(strings.TrimSpace(record[j]), name)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		row.Set(name, value)
 	}
 
-	return nil
+	if extra := record[min(len(record), len(header)):]; len(extra) > 0 {
+		if opts.RaggedRows == raggedPad {
+			opts.Lossy.add(fmt.Sprintf("line %d", line), "%d field(s) beyond the header dropped", len(extra))
+			return row, nil
+		}
+		values := make([]interface{}, len(extra))
+		for j, text := range extra {
+			value, err := opts.Types.value(strings.TrimSpace(text), csvExtraKey)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			values[j] = value
+		}
+		row.Set(csvExtraKey, values)
+	}
+	return row, nil
 }
 
-func convertCsvToYaml(input io.Reader, output io.Writer, delimiter rune) error {
-	reader := csv.NewReader(input)
-	reader.Comma = delimiter
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to parse CSV: %v", err)
+// dedupeCsvHeader torna os nomes de coluna únicos: repetições ganham um
+// sufixo ("id", "id_2") e nomes vazios viram colN.
+func dedupeCsvHeader(names []string) []string {
+	header := make([]string, len(names))
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		used[name] = true
 	}
 
-	if len(records) == 0 {
-		return fmt.Errorf("empty CSV file")
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		switch {
+		case name == "":
+			name = fmt.Sprintf("col%d", i+1)
+		case !seen[name]:
+			seen[name] = true
+			header[i] = name
+			continue
+		}
+		base := name
+		for n := 2; used[name] || seen[name]; n++ {
+			name = fmt.Sprintf("%s_%d", base, n)
+		}
+		seen[name] = true
+		header[i] = name
 	}
+	return header
+}
 
-	header := records[0]
-	var rows []interface{}
+func generatedCsvHeader(count int) []string {
+	header := make([]string, count)
+	for i := range header {
+		header[i] = fmt.Sprintf("col%d", i+1)
+	}
+	return header
+}
 
-	for _, record := range records[1:] {
-		row := make(map[string]interface{})
-		for j, value := range record {
-			cleanValue := strings.TrimSpace(value)
-			row[header[j]] = parseValue(cleanValue)
-		}
-		rows = append(rows, row)
+func writeCsvDocument(data interface{}, output io.Writer, opts convertOptions) error {
+	writer := newCSVWriter(output, opts.Delimiter, opts.Dialect)
+	if err := writeDataAsCSV(writer, data, opts.Lossy); err != nil {
+		return err
 	}
-	return WriteAsYaml(rows, output)
+	return writer.Flush()
 }
 
 func flattenValues(data interface{}, separator string) string {
 	switch v := data.(type) {
-	case map[string]interface{}:
-		keys := make([]string, 0, len(v))
-		for key := range v {
-			keys = append(keys, key)
-		}
-		sort.Strings(keys)
-
+	case *document.Object:
 		var parts []string
-		for _, key := range keys {
-			value := v[key]
+		for _, key := range v.Keys() {
+			value, _ := v.Get(key)
 			parts = append(parts, flattenValues(value, separator))
 		}
 		return strings.Join(parts, separator)
@@ -151,8 +235,10 @@ func flattenValues(data interface{}, separator string) string {
 	}
 }
 
-func writeDataAsCSV(writer *csv.Writer, data interface{}) error {
+func writeDataAsCSV(writer *csvWriter, data interface{}, lossy *lossyLog) error {
 	var rows []interface{}
+	// rowPath dá o caminho de cada registro na árvore de entrada.
+	rowPath := func(i int) string { return pointerIndex("", i) }
 
 	switch v := data.(type) {
 	case []interface{}:
@@ -160,55 +246,142 @@ func writeDataAsCSV(writer *csv.Writer, data interface{}) error {
 			return nil
 		}
 		rows = v
-	case map[string]interface{}:
-		if len(v) == 0 {
+	case *document.Object:
+		if v.Len() == 0 {
 			return fmt.Errorf("empty object")
 		}
 		rows = []interface{}{v}
+		rowPath = func(int) string { return "" }
 	default:
 		return fmt.Errorf("format not supported")
 	}
 
-	headerSet := make(map[string]struct{})
-	for _, row := range rows {
-		if obj, ok := row.(map[string]interface{}); ok {
-			for key := range obj {
-				headerSet[key] = struct{}{}
+	headers := collectCSVHeaders(rows)
+	if err := writer.Write(textCells(headers)); err != nil {
+		return err
+	}
+
+	for i, row := range rows {
+		if obj, ok := row.(*document.Object); ok {
+			if err := writer.Write(buildCSVRecord(obj, headers, rowPath(i), lossy)); err != nil {
+				return err
 			}
+		} else {
+			lossy.add(rowPath(i), "%s is not a record and is skipped", valueKind(row))
 		}
 	}
+	return nil
+}
+
+// streamDataAsCSV escreve registros vindos de next sem mantê-los em memória.
+// O cabeçalho é descoberto nos primeiros sampleSize registros; um campo que
+// só aparece depois disso gera erro em vez de ser descartado em silêncio.
+func streamDataAsCSV(writer *csvWriter, next recordStream, sampleSize int, lossy *lossyLog) error {
+	var sample []interface{}
+	done := false
 
-	headers := make([]string, 0, len(headerSet))
-	for key := range headerSet {
-		headers = append(headers, key)
+	for len(sample) < sampleSize {
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			done = true
+			break
+		}
+		sample = append(sample, row)
 	}
-	sort.Strings(headers)
 
-	if err := writer.Write(headers); err != nil {
+	if len(sample) == 0 {
+		return nil
+	}
+
+	headers := collectCSVHeaders(sample)
+	known := make(map[string]struct{}, len(headers))
+	for _, header := range headers {
+		known[header] = struct{}{}
+	}
+
+	if err := writer.Write(textCells(headers)); err != nil {
 		return err
 	}
 
+	for i, row := range sample {
+		if obj, ok := row.(*document.Object); ok {
+			if err := writer.Write(buildCSVRecord(obj, headers, pointerIndex("", i), lossy)); err != nil {
+				return err
+			}
+		} else {
+			lossy.add(pointerIndex("", i), "%s is not a record and is skipped", valueKind(row))
+		}
+	}
+
+	for index := len(sample); !done; index++ {
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		obj, isObj := row.(*document.Object)
+		if !isObj {
+			lossy.add(pointerIndex("", index), "%s is not a record and is skipped", valueKind(row))
+			continue
+		}
+		for _, key := range obj.Keys() {
+			if _, exists := known[key]; !exists {
+				return fmt.Errorf("record %d: field %q not found in header discovered from the first %d records", index, key, sampleSize)
+			}
+		}
+		if err := writer.Write(buildCSVRecord(obj, headers, pointerIndex("", index), lossy)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectCSVHeaders retorna a união das chaves de todos os objetos, na ordem
+// em que aparecem pela primeira vez.
+func collectCSVHeaders(rows []interface{}) []string {
+	headerSet := make(map[string]struct{})
+	var headers []string
 	for _, row := range rows {
-		if obj, ok := row.(map[string]interface{}); ok {
-			record := make([]string, len(headers))
-			for i, header := range headers {
-				if value, exists := obj[header]; exists {
-					switch v := value.(type) {
-					case map[string]interface{}, []interface{}:
-						record[i] = flattenValues(v, " | ")
-					case nil:
-						record[i] = ""
-					default:
-						record[i] = fmt.Sprintf("%v", v)
-					}
-				} else {
-					record[i] = ""
+		if obj, ok := row.(*document.Object); ok {
+			for _, key := range obj.Keys() {
+				if _, exists := headerSet[key]; !exists {
+					headerSet[key] = struct{}{}
+					headers = append(headers, key)
 				}
 			}
-			if err := writer.Write(record); err != nil {
-				return err
+		}
+	}
+	return headers
+}
+
+// buildCSVRecord monta a linha do registro em path. Objetos e arrays viram
+// uma célula com os valores separados por " | ", sem as chaves, o que é
+// registrado como perda.
+func buildCSVRecord(obj *document.Object, headers []string, path string, lossy *lossyLog) []csvCell {
+	record := make([]csvCell, len(headers))
+	for i, header := range headers {
+		value, _ := obj.Get(header)
+		switch v := value.(type) {
+		case *document.Object, []interface{}:
+			lossy.add(pointerChild(path, header), "%s flattened into one cell (use --flatten to keep the structure)", valueKind(v))
+			record[i] = csvCell{Text: flattenValues(v, " | ")}
+		case nil:
+			record[i] = csvCell{Kind: csvNull}
+		case string:
+			record[i] = csvCell{Text: v}
+		default:
+			kind := csvText
+			if valueKind(v) == "number" || valueKind(v) == "boolean" {
+				kind = csvNumeric
 			}
+			record[i] = csvCell{Text: fmt.Sprintf("%v", v), Kind: kind}
 		}
 	}
-	return nil
+	return record
 }
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Estilos de aspas da escrita CSV.
+const (
+	// csvQuoteMinimal só usa aspas quando o campo precisa (padrão).
+	csvQuoteMinimal = "minimal"
+	// csvQuoteAll coloca aspas em todos os campos, exceto null.
+	csvQuoteAll = "all"
+	// csvQuoteNonNumeric coloca aspas em tudo que não é número, booleano
+	// ou null.
+	csvQuoteNonNumeric = "nonnumeric"
+	// csvQuoteNone nunca usa aspas; delimitadores, quebras de linha e "\"
+	// dentro do campo são escapados com "\".
+	csvQuoteNone = "none"
+)
+
+// Estilos de escape de aspas dentro de um campo entre aspas.
+const (
+	// csvEscapeDouble dobra as aspas: "diz ""oi""" (padrão, RFC 4180).
+	csvEscapeDouble = "double"
+	// csvEscapeBackslash usa barra invertida: "diz \"oi\"".
+	csvEscapeBackslash = "backslash"
+)
+
+// csvDialect define como os registros são escritos em CSV. O valor zero
+// produz o mesmo CSV de encoding/csv: aspas só quando necessário, "\n" e
+// null como célula vazia.
+type csvDialect struct {
+	Quote  string
+	Escape string
+	CRLF   bool
+	// BOM grava a marca de ordem de bytes UTF-8 no início do arquivo (o
+	// Excel precisa dela para abrir acentos corretamente).
+	BOM bool
+	// Null é o texto das células null (ex.: "\N" para carga em banco).
+	Null string
+}
+
+// csvCellKind separa valores que os estilos de aspas tratam de forma
+// diferente.
+type csvCellKind int
+
+const (
+	csvText csvCellKind = iota
+	csvNumeric
+	csvNull
+)
+
+// csvCell é uma célula pronta para escrita: o texto e o tipo do valor.
+type csvCell struct {
+	Text string
+	Kind csvCellKind
+}
+
+// textCells converte nomes de coluna em células de texto.
+func textCells(values []string) []csvCell {
+	cells := make([]csvCell, len(values))
+	for i, value := range values {
+		cells[i] = csvCell{Text: value}
+	}
+	return cells
+}
+
+// csvWriter escreve registros CSV seguindo um csvDialect. Todos os
+// conversores para CSV passam por ele.
+type csvWriter struct {
+	output  *bufio.Writer
+	comma   rune
+	dialect csvDialect
+	started bool
+}
+
+func newCSVWriter(output io.Writer, comma rune, dialect csvDialect) *csvWriter {
+	return &csvWriter{output: bufio.NewWriter(output), comma: comma, dialect: dialect}
+}
+
+// Write escreve uma linha.
+func (w *csvWriter) Write(cells []csvCell) error {
+	if !w.started {
+		w.started = true
+		if w.dialect.BOM {
+			w.output.WriteString("\ufeff")
+		}
+	}
+
+	for i, cell := range cells {
+		if i > 0 {
+			w.output.WriteRune(w.comma)
+		}
+		w.writeCell(cell)
+	}
+	if w.dialect.CRLF {
+		_, err := w.output.WriteString("\r\n")
+		return err
+	}
+	return w.output.WriteByte('\n')
+}
+
+func (w *csvWriter) writeCell(cell csvCell) {
+	if cell.Kind == csvNull {
+		w.output.WriteString(w.dialect.Null)
+		return
+	}
+
+	switch w.dialect.Quote {
+	case csvQuoteNone:
+		w.output.WriteString(w.escapeUnquoted(cell.Text))
+		return
+	case csvQuoteAll:
+		w.writeQuoted(cell.Text)
+		return
+	case csvQuoteNonNumeric:
+		if cell.Kind == csvText {
+			w.writeQuoted(cell.Text)
+			return
+		}
+	}
+
+	if w.needsQuotes(cell.Text) {
+		w.writeQuoted(cell.Text)
+		return
+	}
+	w.output.WriteString(cell.Text)
+}
+
+// needsQuotes segue as regras de encoding/csv e também protege textos
+// iguais ao marcador de null.
+func (w *csvWriter) needsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == w.dialect.Null || field == `\.` {
+		return true
+	}
+	if strings.ContainsRune(field, w.comma) || strings.ContainsAny(field, "\"\r\n") {
+		return true
+	}
+	if w.dialect.Escape == csvEscapeBackslash && strings.Contains(field, `\`) {
+		return true
+	}
+	first, _ := utf8.DecodeRuneInString(field)
+	return first == ' ' || first == '\t'
+}
+
+func (w *csvWriter) writeQuoted(field string) {
+	w.output.WriteByte('"')
+	if w.dialect.Escape == csvEscapeBackslash {
+		field = strings.ReplaceAll(field, `\`, `\\`)
+		field = strings.ReplaceAll(field, `"`, `\"`)
+	} else {
+		field = strings.ReplaceAll(field, `"`, `""`)
+	}
+	w.output.WriteString(field)
+	w.output.WriteByte('"')
+}
+
+// escapeUnquoted escapa com "\" o que quebraria um campo sem aspas.
+func (w *csvWriter) escapeUnquoted(field string) string {
+	var b strings.Builder
+	for _, r := range field {
+		switch {
+		case r == '\t' && w.comma == '\t':
+			b.WriteString(`\t`)
+		case r == '\\' || r == w.comma:
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Flush grava o que ainda está no buffer.
+func (w *csvWriter) Flush() error {
+	if err := w.output.Flush(); err != nil {
+		return fmt.Errorf("error flushing CSV writer: %v", err)
+	}
+	return nil
+}
+
+// parseCSVDialect valida as opções de dialeto vindas da linha de comando.
+func parseCSVDialect(quote, escape, lineEnding string, bom bool, null string) (csvDialect, error) {
+	dialect := csvDialect{Quote: quote, Escape: escape, BOM: bom, Null: null}
+	switch quote {
+	case csvQuoteMinimal, csvQuoteAll, csvQuoteNonNumeric, csvQuoteNone:
+	default:
+		return dialect, fmt.Errorf("unsupported quote style: %s (use minimal, all, nonnumeric or none)", quote)
+	}
+	switch escape {
+	case csvEscapeDouble, csvEscapeBackslash:
+	default:
+		return dialect, fmt.Errorf("unsupported escape style: %s (use double or backslash)", escape)
+	}
+	switch lineEnding {
+	case "lf":
+	case "crlf":
+		dialect.CRLF = true
+	default:
+		return dialect, fmt.Errorf("unsupported line ending: %s (use lf or crlf)", lineEnding)
+	}
+	return dialect, nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"cli-convert/charset"
+)
+
+// encodingAuto deduz a codificação da entrada pelo BOM ou, sem ele, pelos
+// bytes do início (charset.Guess).
+const encodingAuto = "auto"
+
+// parseInputEncoding valida --input-encoding, que aceita também "auto".
+func parseInputEncoding(name string) (string, error) {
+	if strings.EqualFold(strings.TrimSpace(name), encodingAuto) {
+		return encodingAuto, nil
+	}
+	return charset.Normalize(name)
+}
+
+// isZipData indica uma entrada binária (xlsx), que nunca é transcodificada.
+func isZipData(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("PK\x03\x04"))
+}
+
+// decodeInput devolve a entrada convertida para UTF-8, sem BOM, e a
+// codificação usada. Com encodingAuto, a codificação é deduzida do início
+// da entrada. Entradas zip passam intactas e a codificação volta vazia.
+func decodeInput(input *bufio.Reader, encoding string) (*bufio.Reader, string, error) {
+	peek, err := input.Peek(detectPeekSize)
+	if err != nil && err != io.EOF {
+		return nil, "", fmt.Errorf("failed to read input: %v", err)
+	}
+	if isZipData(peek) {
+		return input, "", nil
+	}
+	if encoding == encodingAuto {
+		encoding = charset.Guess(peek, err == nil)
+	}
+	return bufio.NewReaderSize(charset.NewReader(input, encoding), detectPeekSize), encoding, nil
+}
+
+// decodeData converte um arquivo lido inteiro para UTF-8, deduzindo a
+// codificação.
+func decodeData(data []byte) []byte {
+	if isZipData(data) {
+		return data
+	}
+	return charset.Decode(data, charset.Guess(data, false))
+}
+
+// newOutputEncoder grava em output na codificação indicada. Cada caractere
+// sem representação nela é escrito como "?" e registrado em lossy uma vez.
+func newOutputEncoder(output io.Writer, encoding string, lossy *lossyLog) *charset.Writer {
+	encoder := charset.NewWriter(output, encoding)
+	replaced := make(map[rune]bool)
+	encoder.OnReplace = func(char rune) {
+		if !replaced[char] {
+			replaced[char] = true
+			lossy.add("", "character %q has no %s representation and is written as \"?\"", char, encoding)
+		}
+	}
+	return encoder
+}
+
+// detectFileEncoding deduz a codificação pelos primeiros detectPeekSize
+// bytes do arquivo, como decodeInput, e indica se ele começa com BOM.
+// Arquivos zip voltam com codificação vazia.
+func detectFileEncoding(path string) (string, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read input file: %v", err)
+	}
+	defer file.Close()
+
+	prefix := make([]byte, detectPeekSize)
+	n, err := io.ReadFull(file, prefix)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", false, fmt.Errorf("failed to read input file: %v", err)
+	}
+	prefix = prefix[:n]
+	if isZipData(prefix) {
+		return "", false, nil
+	}
+	_, bomSize := charset.DetectBOM(prefix)
+	return charset.Guess(prefix, err == nil), bomSize > 0, nil
+}
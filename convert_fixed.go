@@ -0,0 +1,391 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"cli-convert/document"
+)
+
+// Alinhamentos de uma coluna de largura fixa.
+const (
+	fixedAlignLeft  = "left"
+	fixedAlignRight = "right"
+)
+
+// fixedColumn é uma coluna do arquivo de largura fixa. Start é a posição
+// do primeiro caractere a partir de 0 (no arquivo de spec, a partir de 1);
+// larguras contam caracteres, não bytes.
+type fixedColumn struct {
+	Name  string
+	Start int
+	Width int
+	// Type é um dos tipos de --column-types (auto, string, integer,
+	// number, boolean).
+	Type  string
+	Align string
+}
+
+// fixedSpec descreve as colunas de um arquivo de largura fixa, na ordem
+// em que aparecem na linha.
+type fixedSpec struct {
+	Columns []fixedColumn
+}
+
+// loadFixedSpec lê o spec das colunas de um arquivo em qualquer formato com
+// leitor: uma lista de objetos (ou um objeto com a chave "columns") com
+// name, width e, opcionalmente, start (a partir de 1; padrão: logo após a
+// coluna anterior), type e align. Em CSV:
+//
+//	name,start,width,type,align
+//	id,1,6,integer,right
+//	nome,7,20,string,left
+func loadFixedSpec(path string) (*fixedSpec, error) {
+	tree, _, err := readFileTree(path, "", convertOptions{Delimiter: ','})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixed-width spec %s: %v", path, err)
+	}
+	spec, err := parseFixedSpec(tree)
+	if err != nil {
+		return nil, fmt.Errorf("fixed-width spec %s: %v", path, err)
+	}
+	return spec, nil
+}
+
+func parseFixedSpec(tree interface{}) (*fixedSpec, error) {
+	if obj, ok := tree.(*document.Object); ok {
+		tree, _ = obj.Get("columns")
+	}
+	items, ok := tree.([]interface{})
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("expected a list of columns")
+	}
+
+	spec := &fixedSpec{}
+	names := make(map[string]bool, len(items))
+	next := 0
+	for i, item := range items {
+		obj, ok := item.(*document.Object)
+		if !ok {
+			return nil, fmt.Errorf("column %d: expected an object with name and width", i+1)
+		}
+
+		column := fixedColumn{Start: next, Type: columnAuto}
+		name, _ := obj.Get("name")
+		column.Name = strings.TrimSpace(fmt.Sprint(name))
+		if name == nil || column.Name == "" {
+			return nil, fmt.Errorf("column %d: missing name", i+1)
+		}
+		if names[column.Name] {
+			return nil, fmt.Errorf("column %q declared twice", column.Name)
+		}
+		names[column.Name] = true
+
+		if value, _ := obj.Get("start"); value != nil {
+			start, ok := specInteger(value)
+			if !ok || start < 1 {
+				return nil, fmt.Errorf("column %q: start must be a position from 1, got %v", column.Name, value)
+			}
+			column.Start = start - 1
+		}
+		if column.Start < next {
+			return nil, fmt.Errorf("column %q starts at %d, inside the previous column", column.Name, column.Start+1)
+		}
+
+		value, _ := obj.Get("width")
+		width, ok := specInteger(value)
+		if !ok || width < 1 {
+			return nil, fmt.Errorf("column %q: width must be a positive integer, got %v", column.Name, value)
+		}
+		column.Width = width
+
+		if value, _ := obj.Get("type"); value != nil && value != "" {
+			column.Type = strings.ToLower(fmt.Sprint(value))
+			if !isColumnType(column.Type) {
+				return nil, fmt.Errorf("column %q: unsupported type %q (use string, integer, number, boolean or auto)", column.Name, column.Type)
+			}
+		}
+
+		// Números alinham à direita por padrão; o resto, à esquerda.
+		column.Align = fixedAlignLeft
+		if column.Type == columnInteger || column.Type == columnNumber {
+			column.Align = fixedAlignRight
+		}
+		if value, _ := obj.Get("align"); value != nil && value != "" {
+			column.Align = strings.ToLower(fmt.Sprint(value))
+			if column.Align != fixedAlignLeft && column.Align != fixedAlignRight {
+				return nil, fmt.Errorf("column %q: unsupported align %q (use left or right)", column.Name, column.Align)
+			}
+		}
+
+		spec.Columns = append(spec.Columns, column)
+		next = column.Start + column.Width
+	}
+	return spec, nil
+}
+
+// specInteger aceita os inteiros de qualquer leitor (int do CSV, int64 do
+// YAML e do TOML, json.Number do JSON) e textos numéricos.
+func specInteger(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case json.Number:
+		i, err := v.Int64()
+		return int(i), err == nil
+	case float64:
+		return int(v), v == math.Trunc(v)
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(v))
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// names devolve os nomes das colunas, que fazem o papel do cabeçalho CSV.
+func (s *fixedSpec) names() []string {
+	names := make([]string, len(s.Columns))
+	for i, column := range s.Columns {
+		names[i] = column.Name
+	}
+	return names
+}
+
+// end é a posição logo após a última coluna.
+func (s *fixedSpec) end() int {
+	last := s.Columns[len(s.Columns)-1]
+	return last.Start + last.Width
+}
+
+// types junta os tipos declarados no spec às regras de --column-types, que
+// prevalecem.
+func (s *fixedSpec) types(base *typeInference) *typeInference {
+	types := &typeInference{Mode: base.mode(), Columns: make(map[string]string)}
+	for _, column := range s.Columns {
+		if column.Type != columnAuto {
+			types.Columns[column.Name] = column.Type
+		}
+	}
+	if base != nil {
+		for name, kind := range base.Columns {
+			types.Columns[name] = kind
+		}
+	}
+	return types
+}
+
+// split corta a linha nas colunas do spec. Linhas curtas deixam as últimas
+// colunas vazias; o segundo valor conta os caracteres (exceto espaços)
+// além da última coluna.
+func (s *fixedSpec) split(line string) ([]string, int) {
+	runes := []rune(line)
+	record := make([]string, len(s.Columns))
+	for i, column := range s.Columns {
+		start := min(column.Start, len(runes))
+		end := min(column.Start+column.Width, len(runes))
+		record[i] = string(runes[start:end])
+	}
+
+	rest := 0
+	if end := s.end(); len(runes) > end {
+		rest = utf8.RuneCountInString(strings.TrimSpace(string(runes[end:])))
+	}
+	return record, rest
+}
+
+// fixedFormat lê e escreve texto de largura fixa com as colunas de
+// opts.Fixed. Não há linha de cabeçalho: os nomes vêm do spec (use
+// --skip-rows para descartar um cabeçalho existente).
+type fixedFormat struct{}
+
+func (fixedFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	return readFixedDocument(input, opts)
+}
+
+func (fixedFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	writer, err := newFixedWriter(output, opts)
+	if err != nil {
+		return err
+	}
+
+	switch v := data.(type) {
+	case []interface{}:
+		for i, row := range v {
+			if err := writer.Write(row, pointerIndex("", i)); err != nil {
+				return err
+			}
+		}
+	case *document.Object:
+		if err := writer.Write(v, ""); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("format not supported")
+	}
+	return writer.Flush()
+}
+
+func (fixedFormat) WriteStream(next recordStream, output io.Writer, opts convertOptions) error {
+	writer, err := newFixedWriter(output, opts)
+	if err != nil {
+		return err
+	}
+
+	for index := 0; ; index++ {
+		row, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if err := writer.Write(row, pointerIndex("", index)); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// errMissingFixedSpec aparece ao ler ou escrever fixed sem --fixed-spec.
+var errMissingFixedSpec = errors.New("fixed-width format requires a column spec (use --fixed-spec)")
+
+// readFixedDocument lê uma linha por registro, aplicando os tipos do spec
+// com as mesmas regras das células CSV. Linhas em branco são ignoradas.
+func readFixedDocument(input io.Reader, opts convertOptions) (interface{}, error) {
+	spec := opts.Fixed
+	if spec == nil {
+		return nil, errMissingFixedSpec
+	}
+	opts.Types = spec.types(opts.Types)
+	header := spec.names()
+
+	buffered := bufio.NewReader(input)
+	if bom, _ := buffered.Peek(3); string(bom) == "\ufeff" {
+		buffered.Discard(3)
+	}
+
+	rows := []interface{}{}
+	for line := 1; ; line++ {
+		text, err := buffered.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read input file: %v", err)
+		}
+		if text == "" && err == io.EOF {
+			break
+		}
+		text = strings.TrimRight(text, "\r\n")
+
+		skip := line <= opts.SkipRows || strings.TrimSpace(text) == "" ||
+			(opts.Comment != 0 && strings.HasPrefix(text, string(opts.Comment)))
+		if !skip {
+			record, rest := spec.split(text)
+			if rest > 0 {
+				opts.Lossy.add(fmt.Sprintf("line %d", line), "%d character(s) beyond the last column dropped", rest)
+			}
+			row, rowErr := csvRecord(record, header, line, opts)
+			if rowErr != nil {
+				return nil, rowErr
+			}
+			rows = append(rows, row)
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return rows, nil
+}
+
+// fixedWriter escreve registros alinhando cada valor na largura da coluna.
+type fixedWriter struct {
+	output *bufio.Writer
+	spec   *fixedSpec
+	known  map[string]bool
+	crlf   bool
+	lossy  *lossyLog
+}
+
+func newFixedWriter(output io.Writer, opts convertOptions) (*fixedWriter, error) {
+	if opts.Fixed == nil {
+		return nil, errMissingFixedSpec
+	}
+	known := make(map[string]bool, len(opts.Fixed.Columns))
+	for _, column := range opts.Fixed.Columns {
+		known[column.Name] = true
+	}
+	return &fixedWriter{
+		output: bufio.NewWriter(output),
+		spec:   opts.Fixed,
+		known:  known,
+		crlf:   opts.Dialect.CRLF,
+		lossy:  opts.Lossy,
+	}, nil
+}
+
+// Write escreve o registro em path. Valores maiores que a coluna são
+// cortados e campos fora do spec são descartados, ambos registrados como
+// perda; null vira uma coluna em branco.
+func (w *fixedWriter) Write(row interface{}, path string) error {
+	obj, ok := row.(*document.Object)
+	if !ok {
+		w.lossy.add(path, "%s is not a record and is skipped", valueKind(row))
+		return nil
+	}
+	for _, key := range obj.Keys() {
+		if !w.known[key] {
+			w.lossy.add(pointerChild(path, key), "field not in the fixed-width spec dropped")
+		}
+	}
+
+	cells := buildCSVRecord(obj, w.spec.names(), path, w.lossy)
+	var line strings.Builder
+	position := 0
+	for i, column := range w.spec.Columns {
+		line.WriteString(strings.Repeat(" ", column.Start-position))
+		text := cells[i].Text
+		if strings.ContainsAny(text, "\r\n") {
+			w.lossy.add(pointerChild(path, column.Name), "line break replaced by a space")
+			text = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(text)
+		}
+		line.WriteString(fitFixedWidth(text, column, pointerChild(path, column.Name), w.lossy))
+		position = column.Start + column.Width
+	}
+
+	w.output.WriteString(line.String())
+	if w.crlf {
+		_, err := w.output.WriteString("\r\n")
+		return err
+	}
+	return w.output.WriteByte('\n')
+}
+
+// fitFixedWidth completa o texto com espaços até a largura da coluna,
+// conforme o alinhamento, ou o corta.
+func fitFixedWidth(text string, column fixedColumn, path string, lossy *lossyLog) string {
+	runes := []rune(text)
+	if len(runes) > column.Width {
+		lossy.add(path, "value %q truncated to %d character(s)", text, column.Width)
+		return string(runes[:column.Width])
+	}
+	padding := strings.Repeat(" ", column.Width-len(runes))
+	if column.Align == fixedAlignRight {
+		return padding + text
+	}
+	return text + padding
+}
+
+// Flush grava o que ainda está no buffer.
+func (w *fixedWriter) Flush() error {
+	if err := w.output.Flush(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
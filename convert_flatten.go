@@ -0,0 +1,328 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"cli-convert/document"
+)
+
+// --flatten transforma cada registro em um objeto plano com chaves
+// pontilhadas ("address.city", "tags[0]"); --unflatten faz o caminho
+// inverso. Pontos, colchetes e barras invertidas dentro das chaves são
+// escapados com "\" para que a volta seja exata.
+
+// reshapeData aplica --flatten ou --unflatten a cada registro da árvore.
+func reshapeData(data interface{}, opts convertOptions) (interface{}, error) {
+	if !opts.Flatten && !opts.Unflatten {
+		return data, nil
+	}
+	rows, ok := data.([]interface{})
+	if !ok {
+		return reshapeRecord(data, opts)
+	}
+	result := make([]interface{}, len(rows))
+	for i, row := range rows {
+		reshaped, err := reshapeRecord(row, opts)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %v", i+1, err)
+		}
+		result[i] = reshaped
+	}
+	return result, nil
+}
+
+// reshapeStream aplica --flatten ou --unflatten aos registros de next.
+func reshapeStream(next recordStream, opts convertOptions) recordStream {
+	if !opts.Flatten && !opts.Unflatten {
+		return next
+	}
+	index := 0
+	return func() (interface{}, bool, error) {
+		record, ok, err := next()
+		if err != nil || !ok {
+			return record, ok, err
+		}
+		index++
+		reshaped, err := reshapeRecord(record, opts)
+		if err != nil {
+			return nil, false, fmt.Errorf("record %d: %v", index, err)
+		}
+		return reshaped, true, nil
+	}
+}
+
+func reshapeRecord(record interface{}, opts convertOptions) (interface{}, error) {
+	obj, ok := record.(*document.Object)
+	if !ok {
+		return record, nil
+	}
+	if opts.Flatten {
+		return flattenRecord(obj), nil
+	}
+	return unflattenRecord(obj)
+}
+
+// flattenRecord gera um objeto plano com uma chave por valor escalar.
+// Objetos e arrays vazios viram o texto "{}" e "[]", que os leitores CSV
+// e xlsx já interpretam de volta como JSON.
+func flattenRecord(obj *document.Object) *document.Object {
+	flat := document.NewObject()
+	for _, key := range obj.Keys() {
+		value, _ := obj.Get(key)
+		flattenInto(flat, escapeFlatKey(key), value)
+	}
+	return flat
+}
+
+func flattenInto(flat *document.Object, path string, value interface{}) {
+	switch v := value.(type) {
+	case *document.Object:
+		if v.Len() == 0 {
+			flat.Set(path, "{}")
+			return
+		}
+		for _, key := range v.Keys() {
+			item, _ := v.Get(key)
+			flattenInto(flat, path+"."+escapeFlatKey(key), item)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			flat.Set(path, "[]")
+			return
+		}
+		for i, item := range v {
+			flattenInto(flat, fmt.Sprintf("%s[%d]", path, i), item)
+		}
+	default:
+		flat.Set(path, v)
+	}
+}
+
+func escapeFlatKey(key string) string {
+	if !strings.ContainsAny(key, `.[]\`) {
+		return key
+	}
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`.[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// flatSegment é um passo de uma chave pontilhada: um nome de campo ou um
+// índice de array.
+type flatSegment struct {
+	key   string
+	index int
+	isKey bool
+}
+
+// maxFlatIndex limita os índices de array das chaves pontilhadas: o array
+// é alocado até o maior índice, e uma chave como "tags[999999999]" não pode
+// custar gigabytes.
+const maxFlatIndex = 1 << 16
+
+// parseFlatKey divide "a.b[0].c" em segmentos, respeitando os escapes.
+func parseFlatKey(path string) ([]flatSegment, error) {
+	var segments []flatSegment
+	var name strings.Builder
+	named := false
+
+	flushName := func() {
+		if named {
+			segments = append(segments, flatSegment{key: name.String(), isKey: true})
+		}
+		name.Reset()
+		named = false
+	}
+
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		switch c {
+		case '\\':
+			if i+1 >= len(path) {
+				return nil, fmt.Errorf("key %q: dangling escape", path)
+			}
+			i++
+			name.WriteByte(path[i])
+			named = true
+		case '.':
+			if !named && (i == 0 || path[i-1] != ']') {
+				return nil, fmt.Errorf("key %q: empty field name", path)
+			}
+			flushName()
+			if i+1 >= len(path) {
+				return nil, fmt.Errorf("key %q: empty field name", path)
+			}
+		case '[':
+			flushName()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("key %q: unclosed [", path)
+			}
+			index, err := strconv.Atoi(path[i+1 : i+end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("key %q: invalid index %q", path, path[i+1:i+end])
+			}
+			if index >= maxFlatIndex {
+				return nil, fmt.Errorf("key %q: index %d is above the limit of %d", path, index, maxFlatIndex-1)
+			}
+			segments = append(segments, flatSegment{index: index})
+			i += end
+		default:
+			name.WriteByte(c)
+			named = true
+		}
+	}
+	flushName()
+
+	if len(segments) == 0 || !segments[0].isKey {
+		return nil, fmt.Errorf("key %q: must start with a field name", path)
+	}
+	return segments, nil
+}
+
+// flatNode é um nó da estrutura em reconstrução: um valor, um objeto ou
+// um array.
+type flatNode struct {
+	kind      int // flatLeaf, flatObject ou flatArray
+	value     interface{}
+	keys      []string
+	mentioned map[string]bool
+	fields    map[string]*flatNode
+	items     []*flatNode
+}
+
+func newFlatObject() *flatNode {
+	return &flatNode{kind: flatObject, fields: make(map[string]*flatNode), mentioned: make(map[string]bool)}
+}
+
+const (
+	flatLeaf = iota
+	flatObject
+	flatArray
+)
+
+// unflattenRecord reconstrói a estrutura aninhada a partir das chaves
+// pontilhadas. Células vazias (null) não criam objetos nem estendem arrays:
+// em CSV elas também representam campos ausentes no registro.
+func unflattenRecord(flat *document.Object) (*document.Object, error) {
+	root := newFlatObject()
+	for _, path := range flat.Keys() {
+		value, _ := flat.Get(path)
+		segments, err := parseFlatKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := root.set(segments, value, path); err != nil {
+			return nil, err
+		}
+	}
+	return root.toValue().(*document.Object), nil
+}
+
+// set grava value no caminho dado, criando objetos e arrays intermediários.
+func (n *flatNode) set(segments []flatSegment, value interface{}, path string) error {
+	segment := segments[0]
+	last := len(segments) == 1
+
+	// A ordem dos campos segue a primeira coluna que os menciona, mesmo
+	// que essa célula esteja vazia neste registro.
+	if n.kind == flatObject && segment.isKey && !n.mentioned[segment.key] {
+		n.mentioned[segment.key] = true
+		n.keys = append(n.keys, segment.key)
+	}
+
+	child := n.child(segment)
+	if value == nil && (child != nil || !last || n.kind == flatArray) {
+		// null não sobrescreve nada e só é mantido como campo novo de um
+		// objeto.
+		return nil
+	}
+	if child == nil {
+		child = &flatNode{kind: flatLeaf}
+		switch {
+		case last:
+		case segments[1].isKey:
+			child = newFlatObject()
+		default:
+			child.kind = flatArray
+		}
+		if err := n.attach(segment, child, path); err != nil {
+			return err
+		}
+	}
+
+	if last {
+		if child.kind != flatLeaf || child.value != nil {
+			return fmt.Errorf("key %q conflicts with another value at the same path", path)
+		}
+		child.value = value
+		return nil
+	}
+	if child.kind == flatLeaf {
+		return fmt.Errorf("key %q conflicts with a value at the same path", path)
+	}
+	return child.set(segments[1:], value, path)
+}
+
+func (n *flatNode) child(segment flatSegment) *flatNode {
+	if segment.isKey {
+		return n.fields[segment.key]
+	}
+	if segment.index < len(n.items) {
+		return n.items[segment.index]
+	}
+	return nil
+}
+
+func (n *flatNode) attach(segment flatSegment, child *flatNode, path string) error {
+	switch {
+	case n.kind == flatObject && segment.isKey:
+		n.fields[segment.key] = child
+	case n.kind == flatArray && !segment.isKey:
+		for len(n.items) <= segment.index {
+			n.items = append(n.items, nil)
+		}
+		n.items[segment.index] = child
+	case n.kind == flatObject:
+		return fmt.Errorf("key %q indexes an object", path)
+	default:
+		return fmt.Errorf("key %q names a field inside an array", path)
+	}
+	return nil
+}
+
+func (n *flatNode) toValue() interface{} {
+	switch n.kind {
+	case flatObject:
+		obj := document.NewObject()
+		for _, key := range n.keys {
+			if field, ok := n.fields[key]; ok {
+				obj.Set(key, field.toValue())
+			}
+		}
+		return obj
+	case flatArray:
+		list := make([]interface{}, len(n.items))
+		for i, item := range n.items {
+			if item != nil {
+				list[i] = item.toValue()
+			}
+		}
+		return list
+	}
+	// "{}" e "[]" são os contêineres vazios gravados por flattenRecord.
+	switch n.value {
+	case "{}":
+		return document.NewObject()
+	case "[]":
+		return []interface{}{}
+	}
+	return n.value
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cli-convert/document"
+)
+
+// Modos de inferência de tipos para valores lidos como texto (células CSV,
+// texto e atributos XML, escalares YAML sem aspas).
+const (
+	// inferNone mantém todo valor como string.
+	inferNone = "none"
+	// inferSafe só converte quando o valor escreve de volta exatamente o
+	// mesmo texto: números na sintaxe do JSON ("42", "1.50", "1e3") viram
+	// números com o texto original e "true" e "false", booleanos, mas
+	// "007", "+1", "TRUE", "0x1F" e ".inf" continuam strings. Vazio e
+	// "null" (no YAML, também "~") são null. As regras são as mesmas em
+	// CSV, XML e YAML. É o padrão.
+	inferSafe = "safe"
+	// inferAggressive converte tudo o que parece número ou booleano
+	// ("007" → 7, "TRUE" → true, "1e3" → 1000; no YAML, o schema core).
+	inferAggressive = "aggressive"
+)
+
+// Tipos aceitos nas regras por coluna.
+const (
+	columnAuto    = "auto"
+	columnString  = "string"
+	columnInteger = "integer"
+	columnNumber  = "number"
+	columnBoolean = "boolean"
+)
+
+// maxSafeInteger é o maior inteiro representado sem perda em float64 (e
+// em JavaScript). Acima dele, e em decimais, o número segue como
+// json.Number, com o texto original.
+const maxSafeInteger = 1 << 53
+
+var (
+	safeIntegerPattern = regexp.MustCompile(`^(0|-?[1-9][0-9]*)$`)
+	anyIntegerPattern  = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	jsonNumberPattern  = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+)
+
+// typeInference decide o tipo dos valores de texto. Um *typeInference nil
+// usa o modo safe sem regras por coluna.
+type typeInference struct {
+	Mode string
+	// Columns força o tipo pelo nome da coluna CSV, do elemento ou
+	// atributo XML ou da chave YAML.
+	Columns map[string]string
+}
+
+func (t *typeInference) mode() string {
+	if t == nil || t.Mode == "" {
+		return inferSafe
+	}
+	return t.Mode
+}
+
+// columnType devolve o tipo forçado para a primeira chave com regra.
+func (t *typeInference) columnType(keys ...string) string {
+	if t == nil {
+		return columnAuto
+	}
+	for _, key := range keys {
+		if kind, ok := t.Columns[key]; ok {
+			return kind
+		}
+	}
+	return columnAuto
+}
+
+// value converte o texto s da coluna indicada. O erro aparece só quando
+// uma regra por coluna não aceita o valor.
+func (t *typeInference) value(s string, keys ...string) (interface{}, error) {
+	kind := t.columnType(keys...)
+	if kind == columnAuto {
+		return inferText(s, t.mode()), nil
+	}
+	return forceColumnType(s, kind, keys[0])
+}
+
+// inferText aplica o modo a um texto sem regra de coluna.
+func inferText(s string, mode string) interface{} {
+	if mode == inferNone {
+		return s
+	}
+
+	if (strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`)) ||
+		(strings.HasPrefix(s, `'`) && strings.HasSuffix(s, `'`)) {
+		if len(s) > 1 {
+			return s[1 : len(s)-1]
+		}
+		return ""
+	}
+	if s == "" || s == "null" {
+		return nil
+	}
+
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") ||
+		strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		if jsonData, err := document.Unmarshal([]byte(s)); err == nil {
+			return jsonData
+		}
+	}
+
+	if mode == inferAggressive {
+		return inferAggressiveText(s)
+	}
+	return inferSafeText(s)
+}
+
+func inferSafeText(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if safeIntegerPattern.MatchString(s) {
+		return integerValue(s)
+	}
+	if jsonNumberPattern.MatchString(s) {
+		return json.Number(s)
+	}
+	return s
+}
+
+// integerValue devolve int para inteiros até 2^53 e json.Number acima
+// disso, nunca float64.
+func integerValue(s string) interface{} {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil && i >= -maxSafeInteger && i <= maxSafeInteger {
+		return int(i)
+	}
+	return json.Number(strings.TrimPrefix(s, "+"))
+}
+
+func inferAggressiveText(s string) interface{} {
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if anyIntegerPattern.MatchString(s) {
+		// Inteiros nunca viram float; zeros à esquerda são descartados.
+		return integerValue(canonicalInteger(s))
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return f
+	}
+	return s
+}
+
+// canonicalInteger tira o sinal "+" e os zeros à esquerda de um inteiro
+// ("+007" → "7").
+func canonicalInteger(s string) string {
+	sign := ""
+	switch s[0] {
+	case '-':
+		sign = "-"
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	s = strings.TrimLeft(s, "0")
+	if s == "" {
+		return "0"
+	}
+	return sign + s
+}
+
+// forceColumnType converte o texto para o tipo declarado na regra. Texto
+// vazio é null em qualquer tipo, exceto string.
+func forceColumnType(s string, kind string, column string) (interface{}, error) {
+	if kind == columnString {
+		return s, nil
+	}
+	if s == "" || s == "null" {
+		return nil, nil
+	}
+
+	switch kind {
+	case columnInteger:
+		if anyIntegerPattern.MatchString(s) {
+			return integerValue(canonicalInteger(s)), nil
+		}
+	case columnNumber:
+		if anyIntegerPattern.MatchString(s) {
+			return integerValue(canonicalInteger(s)), nil
+		}
+		if jsonNumberPattern.MatchString(s) {
+			return json.Number(s), nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return f, nil
+		}
+	case columnBoolean:
+		switch strings.ToLower(s) {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+	}
+	return nil, fmt.Errorf("column %q: cannot read %q as %s", column, s, kind)
+}
+
+// parseInferMode valida o valor de --infer.
+func parseInferMode(mode string) (string, error) {
+	switch mode {
+	case inferNone, inferSafe, inferAggressive:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unsupported inference mode: %s (use none, safe or aggressive)", mode)
+}
+
+// parseColumnTypes lê regras no formato "zip=string,age=integer".
+func parseColumnTypes(spec string, columns map[string]string) error {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, kind, ok := strings.Cut(item, "=")
+		name, kind = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(kind))
+		if !ok || name == "" {
+			return fmt.Errorf("invalid column type %q (use column=type)", item)
+		}
+		if !isColumnType(kind) {
+			return fmt.Errorf("unsupported column type %q for %q (use string, integer, number, boolean or auto)", kind, name)
+		}
+		columns[name] = kind
+	}
+	return nil
+}
+
+func isColumnType(kind string) bool {
+	switch kind {
+	case columnAuto, columnString, columnInteger, columnNumber, columnBoolean:
+		return true
+	}
+	return false
+}
+
+// columnTypesFromSchema extrai regras por coluna de um JSON Schema: cada
+// propriedade com um único tipo (além de null) vira uma regra. Propriedades
+// aninhadas também contam, já que as regras valem pelo nome da chave; a
+// primeira declaração de um nome prevalece.
+func columnTypesFromSchema(schema interface{}, columns map[string]string) {
+	obj, ok := schema.(*document.Object)
+	if !ok {
+		return
+	}
+	if props, ok := obj.Get("properties"); ok {
+		if propsObj, ok := props.(*document.Object); ok {
+			for _, key := range propsObj.Keys() {
+				prop, _ := propsObj.Get(key)
+				if kind := schemaColumnType(prop); kind != "" {
+					if _, exists := columns[key]; !exists {
+						columns[key] = kind
+					}
+				}
+				columnTypesFromSchema(prop, columns)
+			}
+		}
+	}
+	if items, ok := obj.Get("items"); ok {
+		columnTypesFromSchema(items, columns)
+	}
+}
+
+func schemaColumnType(prop interface{}) string {
+	obj, ok := prop.(*document.Object)
+	if !ok {
+		return ""
+	}
+	value, _ := obj.Get("type")
+	var types []string
+	switch v := value.(type) {
+	case string:
+		types = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			if name, ok := item.(string); ok && name != "null" {
+				types = append(types, name)
+			}
+		}
+	}
+	if len(types) != 1 {
+		return ""
+	}
+	switch types[0] {
+	case columnString, columnInteger, columnNumber, columnBoolean:
+		return types[0]
+	}
+	return ""
+}
+
+// loadTypeInference monta a inferência a partir de --infer, --types-from
+// (um JSON Schema) e --column-types. Regras de --column-types prevalecem
+// sobre as do schema.
+func loadTypeInference(mode, columnTypes, schemaPath string) (*typeInference, error) {
+	mode, err := parseInferMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	types := &typeInference{Mode: mode, Columns: make(map[string]string)}
+
+	if schemaPath != "" {
+		schema, _, err := readFileTree(schemaPath, "", convertOptions{Delimiter: ','})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read types schema %s: %v", schemaPath, err)
+		}
+		columnTypesFromSchema(schema, types.Columns)
+	}
+	if err := parseColumnTypes(columnTypes, types.Columns); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
@@ -1,99 +1,282 @@
 package main
 
 import (
-	"encoding/csv"
+	"bufio"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"math"
+	"strings"
+
+	"cli-convert/document"
 )
 
-func convertToXmlElement(data interface{}, tagName string) XmlElement {
+// convertToXmlElement converte o valor em path para um elemento tagName.
+// Valores sem representação exata em XML (null, arrays aninhados) são
+// registrados em lossy.
+func convertToXmlElement(data interface{}, tagName string, attrPrefix string, path string, lossy *lossyLog) XmlElement {
 	elem := XmlElement{XMLName: xml.Name{Local: tagName}}
 
 	switch v := data.(type) {
-	case map[string]interface{}:
-		for key, val := range v {
+	case *document.Object:
+		for _, key := range v.Keys() {
+			val, _ := v.Get(key)
+			childPath := pointerChild(path, key)
+			if key == xmlTextKey {
+				elem.Value = fmt.Sprintf("%v", val)
+				continue
+			}
+			if attrName, ok := xmlAttrName(key, val, attrPrefix); ok {
+				elem.Attrs = append(elem.Attrs, xml.Attr{Name: xml.Name{Local: attrName}, Value: fmt.Sprintf("%v", val)})
+				continue
+			}
 			if array, ok := val.([]interface{}); ok {
-				for _, item := range array {
-					child := convertToXmlElement(item, key)
+				for i, item := range array {
+					if _, nested := item.([]interface{}); nested {
+						lossy.add(pointerIndex(childPath, i), "nested array flattened into repeated <%s> elements", key)
+					}
+					child := convertToXmlElement(item, key, attrPrefix, pointerIndex(childPath, i), lossy)
 					elem.Children = append(elem.Children, child)
 				}
 			} else {
-				child := convertToXmlElement(val, key)
+				child := convertToXmlElement(val, key, attrPrefix, childPath, lossy)
 				elem.Children = append(elem.Children, child)
 			}
 
 		}
 	case []interface{}:
-		for _, item := range v {
-			elem.Children = append(elem.Children, convertToXmlElement(item, tagName))
+		for i, item := range v {
+			if _, nested := item.([]interface{}); nested {
+				lossy.add(pointerIndex(path, i), "nested array flattened into repeated <%s> elements", tagName)
+			}
+			elem.Children = append(elem.Children, convertToXmlElement(item, tagName, attrPrefix, pointerIndex(path, i), lossy))
 		}
+	case nil:
+		lossy.add(path, "null written as an empty element")
 	default:
 		elem.Value = fmt.Sprintf("%v", v)
 	}
 	return elem
 }
 
-func convertJsonToCsv(input io.Reader, output io.Writer, delimiter rune) error {
-	inputData, err := io.ReadAll(input)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %v", err)
+// xmlAttrName indica se a chave deve virar um atributo XML: ela precisa ter o
+// prefixo configurado e um valor escalar.
+func xmlAttrName(key string, val interface{}, attrPrefix string) (string, bool) {
+	if attrPrefix == "" || !strings.HasPrefix(key, attrPrefix) || len(key) == len(attrPrefix) {
+		return "", false
 	}
+	switch val.(type) {
+	case *document.Object, []interface{}, nil:
+		return "", false
+	}
+	return strings.TrimPrefix(key, attrPrefix), true
+}
+
+// jsonArrayStream percorre um array JSON de nível superior elemento a
+// elemento, sem carregar o array inteiro em memória.
+type jsonArrayStream struct {
+	decoder *json.Decoder
+	index   int
+}
 
-	var data interface{}
-	if err := json.Unmarshal(inputData, &data); err != nil {
-		return fmt.Errorf("failed to parse JSON: %v", err)
+// openJsonArray inspeciona o primeiro caractere significativo da entrada. Se
+// for '[', consome o início do array e retorna um stream para os elementos;
+// caso contrário retorna nil e a entrada permanece intacta.
+func openJsonArray(input *bufio.Reader) (*jsonArrayStream, error) {
+	for {
+		b, err := input.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				return nil, fmt.Errorf("failed to parse JSON: empty input")
+			}
+			return nil, fmt.Errorf("failed to read input file: %v", err)
+		}
+		switch b {
+		case ' ', '\t', '\n', '\r':
+			continue
+		}
+		if err := input.UnreadByte(); err != nil {
+			return nil, err
+		}
+		if b != '[' {
+			return nil, nil
+		}
+		break
 	}
 
-	writer := csv.NewWriter(output)
-	writer.Comma = delimiter
+	decoder := document.NewDecoder(input)
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %v", err)
+	}
+	return &jsonArrayStream{decoder: decoder}, nil
+}
 
-	err = writeDataAsCSV(writer, data)
-	if err != nil {
-		return err
+// Next retorna o próximo elemento do array. O segundo valor é false quando o
+// array terminou.
+func (s *jsonArrayStream) Next() (interface{}, bool, error) {
+	if !s.decoder.More() {
+		if _, err := s.decoder.Token(); err != nil {
+			return nil, false, fmt.Errorf("failed to parse JSON: %v", err)
+		}
+		if _, err := s.decoder.Token(); err != io.EOF {
+			return nil, false, fmt.Errorf("failed to parse JSON: unexpected data after top-level array")
+		}
+		return nil, false, nil
 	}
 
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		return fmt.Errorf("error flushing CSV writer: %v", err)
+	item, err := document.Decode(s.decoder)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse JSON at array index %d: %v", s.index, err)
 	}
+	s.index++
+	return item, true, nil
+}
 
-	return nil
+// jsonFormat lê e escreve um único valor JSON. Um array de nível superior é
+// lido em streaming quando o destino aceita registros um a um.
+type jsonFormat struct{}
+
+func (jsonFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	return readJsonDocument(input)
 }
 
-func convertJsonToXml(input io.Reader, output io.Writer, rootName string) error {
-	inputData, err := io.ReadAll(input)
+func (jsonFormat) ReadStream(input io.Reader, opts convertOptions) (recordStream, interface{}, error) {
+	reader := bufio.NewReader(input)
+	stream, err := openJsonArray(reader)
 	if err != nil {
-		return fmt.Errorf("failed to read input file: %v", err)
+		return nil, nil, err
 	}
+	if stream != nil {
+		return stream.Next, nil, nil
+	}
+
+	data, err := readJsonDocument(reader)
+	return nil, data, err
+}
+
+func (jsonFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	return writeJsonDocument(replaceNonFiniteFloats(data, "", opts.Lossy), output)
+}
+
+// WriteStream escreve os registros como um array JSON, com a mesma
+// indentação de writeJsonDocument, sem montar o array em memória.
+func (jsonFormat) WriteStream(next recordStream, output io.Writer, opts convertOptions) error {
+	writer := bufio.NewWriter(output)
+	writer.WriteString("[")
 
-	var data interface{}
-	if err := json.Unmarshal(inputData, &data); err != nil {
-		return fmt.Errorf("failed to parse JSON: %v", err)
+	count := 0
+	for {
+		record, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		record = replaceNonFiniteFloats(record, pointerIndex("", count), opts.Lossy)
+		item, err := json.MarshalIndent(record, "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %v", err)
+		}
+		if count > 0 {
+			writer.WriteString(",")
+		}
+		writer.WriteString("\n  ")
+		writer.Write(item)
+		count++
 	}
 
-	xmlRoot := convertToXmlElement(data, rootName)
+	if count > 0 {
+		writer.WriteString("\n")
+	}
+	writer.WriteString("]")
 
-	xmlData, err := xml.MarshalIndent(xmlRoot, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to create output file: %v", err)
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
 	}
+	return nil
+}
 
-	xmlHeader := []byte(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+// streamXmlChildren escreve cada item como filho do elemento raiz à medida
+// que é lido, produzindo a mesma saída de convertToXmlElement sobre o array.
+func streamXmlChildren(output io.Writer, next recordStream, rootName string, attrPrefix string, lossy *lossyLog) error {
+	encoder := xml.NewEncoder(output)
+	encoder.Indent("", "  ")
 
-	if _, err := output.Write(append(xmlHeader, xmlData...)); err != nil {
+	start := xml.StartElement{Name: xml.Name{Local: rootName}}
+	if err := encoder.EncodeToken(start); err != nil {
 		return fmt.Errorf("failed to write output file: %v", err)
 	}
 
-	return nil
+	for index := 0; ; index++ {
+		item, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if _, nested := item.([]interface{}); nested {
+			lossy.add(pointerIndex("", index), "nested array flattened into repeated <%s> elements", rootName)
+		}
+		if err := encoder.Encode(convertToXmlElement(item, rootName, attrPrefix, pointerIndex("", index), lossy)); err != nil {
+			return fmt.Errorf("failed to write output file: %v", err)
+		}
+	}
+
+	if err := encoder.EncodeToken(start.End()); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return encoder.Flush()
+}
+
+// readJsonDocument lê um único valor JSON; qualquer coisa depois dele
+// (ex.: um segundo objeto, como em NDJSON) é erro.
+func readJsonDocument(input io.Reader) (interface{}, error) {
+	decoder := document.NewDecoder(input)
+	data, err := document.Decode(decoder)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %v", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("failed to parse JSON: unexpected data after top-level value")
+	}
+	return data, nil
+}
+
+// replaceNonFiniteFloats troca inf e nan (do TOML ou do YAML), que o JSON
+// não representa, por null e registra cada troca em lossy. Objetos e
+// arrays são alterados no lugar.
+func replaceNonFiniteFloats(data interface{}, path string, lossy *lossyLog) interface{} {
+	switch v := data.(type) {
+	case *document.Object:
+		for _, key := range v.Keys() {
+			value, _ := v.Get(key)
+			v.Set(key, replaceNonFiniteFloats(value, pointerChild(path, key), lossy))
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = replaceNonFiniteFloats(item, pointerIndex(path, i), lossy)
+		}
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			lossy.add(path, "%v has no JSON representation and is written as null", v)
+			return nil
+		}
+	}
+	return data
 }
 
-func convertJsonToYaml(input io.Reader, output io.Writer) error {
-	var data interface{}
-	if err := json.NewDecoder(input).Decode(&data); err != nil {
-		return fmt.Errorf("failed to parse JSON: %v", err)
+func writeJsonDocument(data interface{}, output io.Writer) error {
+	jsonBytes, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %v", err)
 	}
 
-	return WriteAsYaml(data, output)
+	if _, err := output.Write(jsonBytes); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
 }
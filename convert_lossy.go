@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"cli-convert/document"
+)
+
+// lossyEvent é um dado que a conversão não conseguiu preservar. Path é o
+// JSON Pointer do valor na árvore ("/0/user") ou, para problemas na
+// entrada, a posição no arquivo ("line 1, column 3").
+type lossyEvent struct {
+	Path   string
+	Reason string
+}
+
+func (e lossyEvent) String() string {
+	if e.Path == "" {
+		return "(root): " + e.Reason
+	}
+	return e.Path + ": " + e.Reason
+}
+
+// lossyLog acumula os eventos de perda de uma conversão. Um log nil ignora
+// os eventos, então os conversores registram sem precisar checar.
+type lossyLog struct {
+	Events []lossyEvent
+}
+
+func (l *lossyLog) add(path, format string, args ...interface{}) {
+	if l == nil {
+		return
+	}
+	l.Events = append(l.Events, lossyEvent{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (l *lossyLog) count() int {
+	if l == nil {
+		return 0
+	}
+	return len(l.Events)
+}
+
+// pointerChild acrescenta uma chave ao JSON Pointer parent (RFC 6901).
+func pointerChild(parent, key string) string {
+	key = strings.ReplaceAll(key, "~", "~0")
+	return parent + "/" + strings.ReplaceAll(key, "/", "~1")
+}
+
+func pointerIndex(parent string, index int) string {
+	return parent + "/" + strconv.Itoa(index)
+}
+
+// reportLossyEvents lista os eventos de perda em stderr. No modo strict é
+// um relatório de erro; caso contrário, um aviso.
+func reportLossyEvents(lossy *lossyLog, strict bool) {
+	if lossy.count() == 0 {
+		return
+	}
+	label := "Warning"
+	if strict {
+		label = "Error"
+	}
+	fmt.Fprintf(os.Stderr, "%s: conversion lost data in %d place(s):\n", label, lossy.count())
+	for _, event := range lossy.Events {
+		fmt.Fprintf(os.Stderr, "  %s\n", event)
+	}
+}
+
+// valueKind nomeia o tipo de um valor da árvore nas mensagens.
+func valueKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case *document.Object:
+		return "object"
+	case []interface{}:
+		return "array"
+	case bool:
+		return "boolean"
+	case int, int64, float64, json.Number:
+		return "number"
+	}
+	return "string"
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"cli-convert/document"
+)
+
+// Modos de --on-bad-line para entradas NDJSON.
+const (
+	badLineAbort   = "abort"
+	badLineSkip    = "skip"
+	badLineCollect = "collect"
+)
+
+// ndjsonLineError aponta a linha e o deslocamento em bytes (a partir de 0)
+// onde um registro NDJSON inválido começa.
+type ndjsonLineError struct {
+	Line   int
+	Offset int64
+	Err    error
+}
+
+func (e *ndjsonLineError) Error() string {
+	return fmt.Sprintf("ndjson: line %d (byte offset %d): %v", e.Line, e.Offset, e.Err)
+}
+
+// badLine é uma linha rejeitada no modo collect.
+type badLine struct {
+	Text string
+	Err  *ndjsonLineError
+}
+
+// badLineLog acumula as linhas rejeitadas durante uma conversão.
+type badLineLog struct {
+	Lines []badLine
+}
+
+func (l *badLineLog) add(text []byte, err *ndjsonLineError) {
+	l.Lines = append(l.Lines, badLine{Text: string(text), Err: err})
+}
+
+// ndjsonFormat lê e escreve JSON Lines: um valor JSON por linha.
+type ndjsonFormat struct{}
+
+func (f ndjsonFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	next, _, err := f.ReadStream(input, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	records := []interface{}{}
+	for {
+		record, ok, err := next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return records, nil
+		}
+		records = append(records, record)
+	}
+}
+
+func (ndjsonFormat) ReadStream(input io.Reader, opts convertOptions) (recordStream, interface{}, error) {
+	mode := opts.OnBadLine
+	if mode == "" {
+		mode = badLineAbort
+	}
+
+	reader := bufio.NewReader(input)
+	lineNumber := 0
+	var offset int64
+
+	next := func() (interface{}, bool, error) {
+		for {
+			line, readErr := reader.ReadBytes('\n')
+			if readErr != nil && readErr != io.EOF {
+				return nil, false, fmt.Errorf("failed to read input file: %v", readErr)
+			}
+			if len(line) == 0 && readErr == io.EOF {
+				return nil, false, nil
+			}
+
+			lineNumber++
+			lineOffset := offset
+			offset += int64(len(line))
+
+			text := bytes.TrimRight(line, "\r\n")
+			if len(bytes.TrimSpace(text)) == 0 {
+				if readErr == io.EOF {
+					return nil, false, nil
+				}
+				continue
+			}
+
+			record, err := document.Unmarshal(text)
+			if err == nil {
+				return record, true, nil
+			}
+
+			lineErr := &ndjsonLineError{Line: lineNumber, Offset: lineOffset, Err: err}
+			switch mode {
+			case badLineSkip:
+			case badLineCollect:
+				if opts.BadLines != nil {
+					opts.BadLines.add(text, lineErr)
+				}
+			default:
+				return nil, false, lineErr
+			}
+			if readErr == io.EOF {
+				return nil, false, nil
+			}
+		}
+	}
+	return next, nil, nil
+}
+
+// Write escreve cada item de um array em uma linha; qualquer outro valor vira
+// uma única linha.
+func (f ndjsonFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	// Os caminhos dos eventos de perda partem do valor inteiro, mesmo
+	// quando ele não é um array.
+	data = replaceNonFiniteFloats(data, "", opts.Lossy)
+	records, ok := data.([]interface{})
+	if !ok {
+		records = []interface{}{data}
+	}
+
+	index := 0
+	return f.WriteStream(func() (interface{}, bool, error) {
+		if index >= len(records) {
+			return nil, false, nil
+		}
+		index++
+		return records[index-1], true, nil
+	}, output, opts)
+}
+
+func (ndjsonFormat) WriteStream(next recordStream, output io.Writer, opts convertOptions) error {
+	writer := bufio.NewWriter(output)
+	for index := 0; ; index++ {
+		record, ok, err := next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+
+		record = replaceNonFiniteFloats(record, pointerIndex("", index), opts.Lossy)
+		line, err := json.Marshal(record)
+		if err != nil {
+			return fmt.Errorf("failed to marshal record %d: %v", index, err)
+		}
+		writer.Write(line)
+		writer.WriteByte('\n')
+	}
+
+	if err := writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
@@ -1,9 +1,17 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+
+	"cli-convert/document"
 )
 
 func TestConvertJsonToCsv(t *testing.T) {
@@ -12,13 +20,12 @@ func TestConvertJsonToCsv(t *testing.T) {
 		{"id": 2, "name": "Bob", "active": false}
 	]`
 
-	expectedCsvOutput := "active,id,name\ntrue,1,Alice\nfalse,2,Bob\n"
+	expectedCsvOutput := "id,name,active\n1,Alice,true\n2,Bob,false\n"
 
 	reader := strings.NewReader(jsonInput)
 	writer := new(bytes.Buffer)
-	delimiter := ','
 
-	err := convertJsonToCsv(reader, writer, delimiter)
+	err := dispatchConversion("json", "csv", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting JSON to CSV: %v", err)
 	}
@@ -34,13 +41,12 @@ func TestConvertJsonToCsv_ComplexData(t *testing.T) {
 		{"id": 2, "user": {"name": "Jane"}, "active": false}
 	]`
 
-	expectedCsvOutput := "active,id,score,tags,user\ntrue,1,,go | test,john@example.com | John\nfalse,2,,,Jane\n"
+	expectedCsvOutput := "id,user,tags,active,score\n1,John | john@example.com,go | test,true,\n2,Jane,,false,\n"
 
 	reader := strings.NewReader(jsonInput)
 	writer := new(bytes.Buffer)
-	delimiter := ','
 
-	err := convertJsonToCsv(reader, writer, delimiter)
+	err := dispatchConversion("json", "csv", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting complex JSON to CSV: %v", err)
 	}
@@ -54,15 +60,15 @@ func TestConvertJsonToYaml(t *testing.T) {
 	jsonInput := `{"id": 1, "name": "Alice", "active": true}`
 
 	expectedYamlOutput := `
-active: true
 id: 1
 name: "Alice"
+active: true
 `
 
 	reader := strings.NewReader(jsonInput)
 	writer := new(bytes.Buffer)
 
-	err := convertJsonToYaml(reader, writer)
+	err := dispatchConversion("json", "yaml", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting JSON to YAML: %v", err)
 	}
@@ -85,6 +91,9 @@ func TestConvertJsonToYaml_ComplexData(t *testing.T) {
 `
 
 	expectedYamlOutput := `
+user: 
+  name: "John"
+  email: "john@example.com"
 projects: 
   - 
     name: "cli-converter"
@@ -96,15 +105,12 @@ tags:
   - "go"
   - "yaml"
   - "cli"
-user: 
-  email: "john@example.com"
-  name: "John"
 `
 
 	reader := strings.NewReader(jsonInput)
 	writer := new(bytes.Buffer)
 
-	err := convertJsonToYaml(reader, writer)
+	err := dispatchConversion("json", "yaml", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting complex JSON to YAML: %v", err)
 	}
@@ -119,22 +125,21 @@ func TestConvertCsvToJson(t *testing.T) {
 
 	expectedJsonOutput := `[
   {
-    "age": 30,
     "id": 1,
-    "name": "John"
+    "name": "John",
+    "age": 30
   },
   {
-    "age": 25,
     "id": 2,
-    "name": "Jane"
+    "name": "Jane",
+    "age": 25
   }
 ]`
 
 	input := strings.NewReader(csvInput)
 	var output bytes.Buffer
-	delimiter := ','
 
-	err := convertCsvToJson(input, &output, delimiter)
+	err := dispatchConversion("csv", "json", input, &output, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting CSV to JSON: %v", err)
 	}
@@ -150,29 +155,28 @@ func TestConvertCsvToJson_WithDifferentTypes(t *testing.T) {
 	expectedJsonOutput := `[
   {
     "id": 1,
-    "is_active": true,
     "name": "John",
+    "is_active": true,
     "score": 98.5
   },
   {
     "id": 2,
-    "is_active": false,
     "name": "Jane",
+    "is_active": false,
     "score": 100
   },
   {
     "id": 3,
-    "is_active": true,
     "name": "João",
+    "is_active": true,
     "score": 85
   }
 ]`
 
 	input := strings.NewReader(csvInput)
 	var output bytes.Buffer
-	delimiter := ','
 
-	err := convertCsvToJson(input, &output, delimiter)
+	err := dispatchConversion("csv", "json", input, &output, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting CSV with types to JSON: %v", err)
 	}
@@ -195,9 +199,8 @@ func TestConvertCsvToYaml(t *testing.T) {
 `
 	reader := strings.NewReader(csvInput)
 	writer := new(bytes.Buffer)
-	delimiter := ','
 
-	err := convertCsvToYaml(reader, writer, delimiter)
+	err := dispatchConversion("csv", "yaml", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting CSV to YAML: %v", err)
 	}
@@ -225,9 +228,8 @@ func TestConvertCsvToYaml_ComplexTypes(t *testing.T) {
 
 	reader := strings.NewReader(csvInput)
 	writer := new(bytes.Buffer)
-	delimiter := ','
 
-	err := convertCsvToYaml(reader, writer, delimiter)
+	err := dispatchConversion("csv", "yaml", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting CSV with complex types to YAML: %v", err)
 	}
@@ -246,15 +248,15 @@ active: true
 
 	expectedJsonOutput := `
 {
-  "active": true,
   "id": 1,
-  "name": "Alice"
+  "name": "Alice",
+  "active": true
 }
 `
 	reader := strings.NewReader(yamlInput)
 	writer := new(bytes.Buffer)
 
-	err := convertYamlToJson(reader, writer)
+	err := dispatchConversion("yaml", "json", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting YAML to JSON: %v", err)
 	}
@@ -283,8 +285,8 @@ func TestConvertYamlToJson_ComplexList(t *testing.T) {
   [
     "donkey",
     {
-      "chest": true,
-      "general": true
+      "general": true,
+      "chest": true
     },
     [
       1791717432,
@@ -298,7 +300,7 @@ func TestConvertYamlToJson_ComplexList(t *testing.T) {
 	reader := strings.NewReader(yamlInput)
 	writer := new(bytes.Buffer)
 
-	err := convertYamlToJson(reader, writer)
+	err := dispatchConversion("yaml", "json", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting complex YAML to JSON: %v", err)
 	}
@@ -320,9 +322,8 @@ func TestConvertYamlToCsv(t *testing.T) {
 
 	reader := strings.NewReader(yamlInput)
 	writer := new(bytes.Buffer)
-	delimiter := ','
 
-	err := convertYamlToCsv(reader, writer, delimiter)
+	err := dispatchConversion("yaml", "csv", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting YAML to CSV: %v", err)
 	}
@@ -345,13 +346,12 @@ func TestConvertYamlToCsv_Complex(t *testing.T) {
   tags: ["csv"]
 `
 
-	expectedCsvOutput := "id,tags,user\n1,go | test,john@example.com | John\n2,csv,Jane\n"
+	expectedCsvOutput := "id,user,tags\n1,John | john@example.com,go | test\n2,Jane,csv\n"
 
 	reader := strings.NewReader(yamlInput)
 	writer := new(bytes.Buffer)
-	delimiter := ','
 
-	err := convertYamlToCsv(reader, writer, delimiter)
+	err := dispatchConversion("yaml", "csv", reader, writer, convertOptions{Delimiter: ','})
 	if err != nil {
 		t.Fatalf("Error converting complex YAML to CSV: %v", err)
 	}
@@ -360,3 +360,1093 @@ func TestConvertYamlToCsv_Complex(t *testing.T) {
 		t.Errorf("Unexpected CSV output (complex YAML):\nExpected:\n%s\nGot:\n%s", expectedCsvOutput, writer.String())
 	}
 }
+
+func TestConvertJsonToCsv_StreamingLateField(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("[")
+	for i := 0; i < csvHeaderSample; i++ {
+		sb.WriteString(`{"id": 1},`)
+	}
+	sb.WriteString(`{"id": 2, "extra": true}]`)
+
+	err := dispatchConversion("json", "csv", strings.NewReader(sb.String()), new(bytes.Buffer), convertOptions{Delimiter: ','})
+	if err == nil || !strings.Contains(err.Error(), `"extra"`) {
+		t.Fatalf("Expected error about field outside the sampled header, got: %v", err)
+	}
+}
+
+func TestConvertJsonToXml_StreamingMatchesTree(t *testing.T) {
+	jsonInput := `[{"tags": ["a", "b"]}, {"id": 2}, "plain"]`
+
+	expectedXmlOutput := `<?xml version="1.0" encoding="UTF-8"?>
+<root>
+  <root>
+    <tags>a</tags>
+    <tags>b</tags>
+  </root>
+  <root>
+    <id>2</id>
+  </root>
+  <root>plain</root>
+</root>`
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("json", "xml", strings.NewReader(jsonInput), writer, convertOptions{RootName: "root", AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting JSON array to XML: %v", err)
+	}
+
+	if writer.String() != expectedXmlOutput {
+		t.Errorf("Unexpected XML output:\nExpected:\n%s\nGot:\n%s", expectedXmlOutput, writer.String())
+	}
+
+	writer.Reset()
+	if err := dispatchConversion("json", "xml", strings.NewReader("[]"), writer, convertOptions{RootName: "root", AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting empty JSON array to XML: %v", err)
+	}
+	if !strings.HasSuffix(writer.String(), "<root></root>") {
+		t.Errorf("Unexpected XML output for empty array: %s", writer.String())
+	}
+}
+
+func TestConvertXmlToJson_Attributes(t *testing.T) {
+	xmlInput := `<order id="42"><total currency="EUR">10.5</total><note>ok</note></order>`
+
+	expectedJsonOutput := `{
+  "order": {
+    "@id": 42,
+    "total": {
+      "@currency": "EUR",
+      "#text": 10.5
+    },
+    "note": "ok"
+  }
+}`
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("xml", "json", strings.NewReader(xmlInput), writer, convertOptions{AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting XML with attributes to JSON: %v", err)
+	}
+	if strings.TrimSpace(writer.String()) != expectedJsonOutput {
+		t.Errorf("Unexpected JSON output:\nExpected:\n%s\nGot:\n%s", expectedJsonOutput, writer.String())
+	}
+
+	writer.Reset()
+	if err := dispatchConversion("xml", "json", strings.NewReader(xmlInput), writer, convertOptions{AttrPrefix: ""}); err != nil {
+		t.Fatalf("Error converting XML with merged attributes to JSON: %v", err)
+	}
+	if !strings.Contains(writer.String(), `"currency": "EUR"`) {
+		t.Errorf("Expected merged attribute in output, got:\n%s", writer.String())
+	}
+}
+
+func TestConvertXml_NamespacedAttributes(t *testing.T) {
+	xmlInput := `<doc xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xml:lang="pt"><v xsi:type="xs:int">1</v></doc>`
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("xml", "ndjson", strings.NewReader(xmlInput), writer, convertOptions{AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting XML with namespaced attributes: %v", err)
+	}
+	expected := `{"doc":{"@xmlns:xsi":"http://www.w3.org/2001/XMLSchema-instance","@xml:lang":"pt","v":{"@xsi:type":"xs:int","#text":1}}}` + "\n"
+	if writer.String() != expected {
+		t.Errorf("Unexpected output:\nExpected: %s\nGot:      %s", expected, writer.String())
+	}
+
+	roundTrip := new(bytes.Buffer)
+	if err := dispatchConversion("ndjson", "xml", strings.NewReader(writer.String()), roundTrip, convertOptions{RootName: "root", AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting back to XML: %v", err)
+	}
+	for _, attr := range []string{`xml:lang="pt"`, `xsi:type="xs:int"`} {
+		if !strings.Contains(roundTrip.String(), attr) {
+			t.Errorf("Expected %s in XML output:\n%s", attr, roundTrip.String())
+		}
+	}
+}
+
+func TestConvertXml_NamespacedElements(t *testing.T) {
+	xmlInput := `<ns:list xmlns:ns="urn:x"><ns:item>1</ns:item><ns:item>2</ns:item><item>3</item></ns:list>`
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("xml", "ndjson", strings.NewReader(xmlInput), writer, convertOptions{AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting XML with namespaced elements: %v", err)
+	}
+	expected := `{"ns:list":{"@xmlns:ns":"urn:x","ns:item":[1,2],"item":3}}` + "\n"
+	if writer.String() != expected {
+		t.Errorf("Unexpected output:\nExpected: %s\nGot:      %s", expected, writer.String())
+	}
+
+	roundTrip := new(bytes.Buffer)
+	if err := dispatchConversion("ndjson", "xml", strings.NewReader(writer.String()), roundTrip, convertOptions{RootName: "root", AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting back to XML: %v", err)
+	}
+	for _, tag := range []string{`<ns:list xmlns:ns="urn:x">`, `<ns:item>1</ns:item>`, `<item>3</item>`} {
+		if !strings.Contains(roundTrip.String(), tag) {
+			t.Errorf("Expected %s in XML output:\n%s", tag, roundTrip.String())
+		}
+	}
+
+	if _, err := parseXmlToElement(strings.NewReader(`<a:x></b:x>`)); err == nil || !strings.Contains(err.Error(), "expected a:x, got b:x") {
+		t.Errorf("Expected mismatched prefix error, got %v", err)
+	}
+}
+
+func TestConvertJsonToXml_Attributes(t *testing.T) {
+	jsonInput := `{"total": {"@currency": "EUR", "#text": 10.5}}`
+
+	expectedXmlOutput := `<?xml version="1.0" encoding="UTF-8"?>
+<order>
+  <total currency="EUR">10.5</total>
+</order>`
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("json", "xml", strings.NewReader(jsonInput), writer, convertOptions{RootName: "order", AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting JSON with attributes to XML: %v", err)
+	}
+	if writer.String() != expectedXmlOutput {
+		t.Errorf("Unexpected XML output:\nExpected:\n%s\nGot:\n%s", expectedXmlOutput, writer.String())
+	}
+}
+
+func TestConvertYamlToJson_MultiDocument(t *testing.T) {
+	yamlInput := "kind: Service\n---\nkind: Deployment\n"
+
+	expectedJsonOutput := `[
+  {
+    "kind": "Service"
+  },
+  {
+    "kind": "Deployment"
+  }
+]`
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("yaml", "json", strings.NewReader(yamlInput), writer, convertOptions{Delimiter: ','}); err != nil {
+		t.Fatalf("Error converting multi-document YAML to JSON: %v", err)
+	}
+	if writer.String() != expectedJsonOutput {
+		t.Errorf("Unexpected JSON output:\nExpected:\n%s\nGot:\n%s", expectedJsonOutput, writer.String())
+	}
+
+	writer.Reset()
+	opts := convertOptions{Documents: "ndjson"}
+	if err := dispatchConversion("yaml", "json", strings.NewReader(yamlInput), writer, opts); err != nil {
+		t.Fatalf("Error converting multi-document YAML to NDJSON: %v", err)
+	}
+	expectedNdjson := "{\"kind\":\"Service\"}\n{\"kind\":\"Deployment\"}\n"
+	if writer.String() != expectedNdjson {
+		t.Errorf("Unexpected NDJSON output:\nExpected:\n%s\nGot:\n%s", expectedNdjson, writer.String())
+	}
+
+	// Um único documento também vira uma linha, mesmo sendo um array.
+	writer.Reset()
+	if err := dispatchConversion("yaml", "ndjson", strings.NewReader("- 1\n- 2\n"), writer, opts); err != nil {
+		t.Fatalf("Error converting YAML to NDJSON: %v", err)
+	}
+	if expected := "[1,2]\n"; writer.String() != expected {
+		t.Errorf("Unexpected NDJSON output:\nExpected: %q\nGot:      %q", expected, writer.String())
+	}
+
+	if err := dispatchConversion("yaml", "csv", strings.NewReader(yamlInput), new(bytes.Buffer), opts); err == nil {
+		t.Error("Expected error for --documents ndjson with --to csv")
+	}
+	if err := dispatchConversion("json", "ndjson", strings.NewReader("[]"), new(bytes.Buffer), opts); err == nil || !strings.Contains(err.Error(), "requires YAML input") {
+		t.Errorf("Expected error for --documents with JSON input, got %v", err)
+	}
+}
+
+func TestWriteAsYamlStream(t *testing.T) {
+	jsonInput := `[{"kind": "Service"}, {"kind": "Deployment"}]`
+
+	expectedYamlOutput := "---\nkind: \"Service\"\n---\nkind: \"Deployment\"\n"
+
+	writer := new(bytes.Buffer)
+	opts := convertOptions{Delimiter: ',', YamlStream: true}
+	if err := dispatchConversion("json", "yaml", strings.NewReader(jsonInput), writer, opts); err != nil {
+		t.Fatalf("Error converting JSON array to a YAML stream: %v", err)
+	}
+	if writer.String() != expectedYamlOutput {
+		t.Errorf("Unexpected YAML stream:\nExpected:\n%s\nGot:\n%s", expectedYamlOutput, writer.String())
+	}
+
+	docs, err := parseYamlDocuments(strings.NewReader(writer.String()), nil)
+	if err != nil || len(docs) != 2 {
+		t.Errorf("Expected the stream to parse back into 2 documents, got %d (%v)", len(docs), err)
+	}
+}
+
+func TestConvertJsonToXml_KeyOrder(t *testing.T) {
+	jsonInput := `{"name": "Alice", "age": 30, "city": "Lisboa", "active": true}`
+
+	expectedXmlOutput := `<?xml version="1.0" encoding="UTF-8"?>
+<root>
+  <name>Alice</name>
+  <age>30</age>
+  <city>Lisboa</city>
+  <active>true</active>
+</root>`
+
+	for i := 0; i < 5; i++ {
+		writer := new(bytes.Buffer)
+		if err := dispatchConversion("json", "xml", strings.NewReader(jsonInput), writer, convertOptions{RootName: "root", AttrPrefix: "@"}); err != nil {
+			t.Fatalf("Error converting JSON to XML: %v", err)
+		}
+		if writer.String() != expectedXmlOutput {
+			t.Fatalf("Unexpected XML output:\nExpected:\n%s\nGot:\n%s", expectedXmlOutput, writer.String())
+		}
+	}
+}
+
+func TestConvertYamlToJson_MergeKeyOrder(t *testing.T) {
+	yamlInput := `
+defaults: &defaults
+  adapter: postgres
+  host: localhost
+development:
+  database: dev
+  <<: *defaults
+  host: dev.local
+`
+
+	expectedJsonOutput := `{
+  "defaults": {
+    "adapter": "postgres",
+    "host": "localhost"
+  },
+  "development": {
+    "database": "dev",
+    "adapter": "postgres",
+    "host": "dev.local"
+  }
+}`
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("yaml", "json", strings.NewReader(yamlInput), writer, convertOptions{Delimiter: ','}); err != nil {
+		t.Fatalf("Error converting YAML to JSON: %v", err)
+	}
+	if writer.String() != expectedJsonOutput {
+		t.Errorf("Unexpected JSON output:\nExpected:\n%s\nGot:\n%s", expectedJsonOutput, writer.String())
+	}
+}
+
+func TestConvertNdjsonToCsv(t *testing.T) {
+	ndjsonInput := "{\"id\": 1, \"level\": \"info\"}\n\n{\"id\": 2, \"level\": \"error\"}\r\n"
+
+	expectedCsvOutput := "id,level\n1,info\n2,error\n"
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("jsonl", "csv", strings.NewReader(ndjsonInput), writer, convertOptions{Delimiter: ','}); err != nil {
+		t.Fatalf("Error converting NDJSON to CSV: %v", err)
+	}
+	if writer.String() != expectedCsvOutput {
+		t.Errorf("Unexpected CSV output:\nExpected:\n%s\nGot:\n%s", expectedCsvOutput, writer.String())
+	}
+}
+
+func TestConvertNdjson_BadLines(t *testing.T) {
+	ndjsonInput := "{\"id\": 1}\n{\"id\": 2,}\n{\"id\": 3}\nnot json\n"
+
+	err := dispatchConversion("ndjson", "json", strings.NewReader(ndjsonInput), new(bytes.Buffer), convertOptions{})
+	lineErr, ok := err.(*ndjsonLineError)
+	if !ok {
+		t.Fatalf("Expected *ndjsonLineError, got %T (%v)", err, err)
+	}
+	if lineErr.Line != 2 || lineErr.Offset != 10 {
+		t.Errorf("Expected line 2 at byte offset 10, got line %d at byte offset %d", lineErr.Line, lineErr.Offset)
+	}
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("ndjson", "json", strings.NewReader(ndjsonInput), writer, convertOptions{OnBadLine: badLineSkip}); err != nil {
+		t.Fatalf("Error converting NDJSON with skipped lines: %v", err)
+	}
+	expectedJsonOutput := "[\n  {\n    \"id\": 1\n  },\n  {\n    \"id\": 3\n  }\n]"
+	if writer.String() != expectedJsonOutput {
+		t.Errorf("Unexpected JSON output:\nExpected:\n%s\nGot:\n%s", expectedJsonOutput, writer.String())
+	}
+
+	log := &badLineLog{}
+	opts := convertOptions{OnBadLine: badLineCollect, BadLines: log}
+	if err := dispatchConversion("ndjson", "json", strings.NewReader(ndjsonInput), new(bytes.Buffer), opts); err != nil {
+		t.Fatalf("Error converting NDJSON with collected lines: %v", err)
+	}
+	if len(log.Lines) != 2 || log.Lines[0].Text != "{\"id\": 2,}" || log.Lines[1].Err.Line != 4 || log.Lines[1].Err.Offset != 31 {
+		t.Errorf("Unexpected collected lines: %+v", log.Lines)
+	}
+}
+
+func TestConvertJsonToNdjson(t *testing.T) {
+	jsonInput := `[{"b": 1, "a": [1, 2]}, "plain", null]`
+
+	expectedNdjson := "{\"b\":1,\"a\":[1,2]}\n\"plain\"\nnull\n"
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("json", "ndjson", strings.NewReader(jsonInput), writer, convertOptions{}); err != nil {
+		t.Fatalf("Error converting JSON to NDJSON: %v", err)
+	}
+	if writer.String() != expectedNdjson {
+		t.Errorf("Unexpected NDJSON output:\nExpected:\n%s\nGot:\n%s", expectedNdjson, writer.String())
+	}
+}
+
+func TestConvertJsonRejectsTrailingData(t *testing.T) {
+	for _, target := range []string{"ndjson", "csv", "yaml"} {
+		err := dispatchConversion("json", target, strings.NewReader("{\"a\":1}\n{\"a\":2}"), new(bytes.Buffer), convertOptions{})
+		if err == nil || !strings.Contains(err.Error(), "unexpected data after top-level value") {
+			t.Errorf("json -> %s: expected trailing data error, got %v", target, err)
+		}
+	}
+}
+
+func TestConvertJsonToCsv_Flatten(t *testing.T) {
+	jsonInput := `[
+		{"id": 1, "user": {"name": "John", "a.b": true}, "tags": ["go", "test"], "meta": {}},
+		{"id": 2, "user": {"name": "Jane"}, "tags": []}
+	]`
+
+	expectedCsvOutput := "id,user.name,user.a\\.b,tags[0],tags[1],meta,tags\n" +
+		"1,John,true,go,test,{},\n" +
+		"2,Jane,,,,,[]\n"
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("json", "csv", strings.NewReader(jsonInput), writer, convertOptions{Delimiter: ',', Flatten: true}); err != nil {
+		t.Fatalf("Error converting JSON to flattened CSV: %v", err)
+	}
+	if writer.String() != expectedCsvOutput {
+		t.Errorf("Unexpected CSV output:\nExpected:\n%s\nGot:\n%s", expectedCsvOutput, writer.String())
+	}
+}
+
+func TestConvertJsonCsvJson_FlattenRoundTrip(t *testing.T) {
+	jsonInput := `[
+  {
+    "id": 1,
+    "address": {
+      "city": "Recife",
+      "geo": {
+        "lat": -8.05
+      }
+    },
+    "tags": [
+      "a",
+      "b"
+    ],
+    "orders": [
+      {
+        "sku": "x",
+        "qty": 2
+      }
+    ],
+    "notes": {}
+  },
+  {
+    "id": 2,
+    "address": {
+      "city": "Natal"
+    },
+    "tags": [],
+    "orders": [
+      {
+        "sku": "y",
+        "qty": 1
+      },
+      {
+        "sku": "z",
+        "qty": 3
+      }
+    ],
+    "notes": {}
+  }
+]`
+
+	csvOutput := new(bytes.Buffer)
+	if err := dispatchConversion("json", "csv", strings.NewReader(jsonInput), csvOutput, convertOptions{Delimiter: ',', Flatten: true}); err != nil {
+		t.Fatalf("Error converting JSON to flattened CSV: %v", err)
+	}
+
+	jsonOutput := new(bytes.Buffer)
+	if err := dispatchConversion("csv", "json", bytes.NewReader(csvOutput.Bytes()), jsonOutput, convertOptions{Delimiter: ',', Unflatten: true}); err != nil {
+		t.Fatalf("Error converting flattened CSV to JSON: %v", err)
+	}
+	if jsonOutput.String() != jsonInput {
+		t.Errorf("Round trip changed the data:\nExpected:\n%s\nGot:\n%s\nCSV:\n%s", jsonInput, jsonOutput.String(), csvOutput.String())
+	}
+}
+
+func TestUnflattenRecord_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		csv  string
+	}{
+		{"value and object at same path", "a,a.b\n1,2\n"},
+		{"object indexed as array", "a.b,a[0]\n1,2\n"},
+		{"unclosed bracket", "a[0\n1\n"},
+		{"empty field name", "a..b\n1\n"},
+		{"index above the limit", "tags[999999999]\nx\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dispatchConversion("csv", "json", strings.NewReader(tt.csv), new(bytes.Buffer), convertOptions{Delimiter: ',', Unflatten: true})
+			if err == nil {
+				t.Error("Expected error for conflicting flattened keys")
+			}
+		})
+	}
+}
+
+func TestConvert_LossyEvents(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		input    string
+		opts     convertOptions
+		expected []lossyEvent
+	}{
+		{
+			"nested values in csv cells", "json", "csv",
+			`[{"id": 1, "user": {"name": "John"}, "tags": ["a/b"]}, "plain"]`, convertOptions{Delimiter: ','},
+			[]lossyEvent{
+				{Path: "/0/user", Reason: "object flattened into one cell (use --flatten to keep the structure)"},
+				{Path: "/0/tags", Reason: "array flattened into one cell (use --flatten to keep the structure)"},
+				{Path: "/1", Reason: "string is not a record and is skipped"},
+			},
+		},
+		{
+			"flatten keeps the structure", "json", "csv",
+			`[{"id": 1, "user": {"name": "John"}}]`, convertOptions{Delimiter: ',', Flatten: true},
+			nil,
+		},
+		{
+			"ragged csv rows padded", "csv", "json",
+			"id,name\n1,Ann,x,y\n2\n", convertOptions{Delimiter: ',', RaggedRows: raggedPad},
+			[]lossyEvent{{Path: "line 2", Reason: "2 field(s) beyond the header dropped"}},
+		},
+		{
+			"xml mixed text, order and number text", "xml", "json",
+			`<a><b>007</b>note<c/><b>2</b></a>`, convertOptions{AttrPrefix: "@", Types: &typeInference{Mode: inferAggressive}},
+			[]lossyEvent{
+				{Path: "/a", Reason: "interleaved child elements regrouped by name; their relative order is lost"},
+				{Path: "/a", Reason: "text mixed with child elements is dropped"},
+				{Path: "/a/b/0", Reason: `text "007" read as number 7`},
+			},
+		},
+		{
+			"xml merged attribute collides with child", "xml", "json",
+			`<a id="1"><id>2</id></a>`, convertOptions{AttrPrefix: ""},
+			[]lossyEvent{{Path: "/a/id", Reason: "attribute overwritten by the child element with the same name"}},
+		},
+		{
+			"null and nested arrays in xml", "json", "xml",
+			`{"a": null, "b": [[1, 2]]}`, convertOptions{RootName: "root", AttrPrefix: "@"},
+			[]lossyEvent{
+				{Path: "/a", Reason: "null written as an empty element"},
+				{Path: "/b/0", Reason: "nested array flattened into repeated <b> elements"},
+			},
+		},
+		{
+			"fixed-width truncation and unknown fields", "json", "fixed",
+			`[{"id": 1, "name": "Maximiliano Souza", "email": "m@x.com"}]`, convertOptions{Fixed: sampleFixedSpec},
+			[]lossyEvent{
+				{Path: "/0/email", Reason: "field not in the fixed-width spec dropped"},
+				{Path: "/0/name", Reason: `value "Maximiliano Souza" truncated to 10 character(s)`},
+			},
+		},
+		{
+			"fixed-width text beyond the last column", "fixed", "json",
+			"    1Alice     extra\n", convertOptions{Fixed: sampleFixedSpec},
+			[]lossyEvent{{Path: "line 1", Reason: "5 character(s) beyond the last column dropped"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log := &lossyLog{}
+			tt.opts.Lossy = log
+			if err := dispatchConversion(tt.from, tt.to, strings.NewReader(tt.input), new(bytes.Buffer), tt.opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(log.Events, tt.expected) {
+				t.Errorf("Unexpected lossy events:\nExpected: %v\nGot:      %v", tt.expected, log.Events)
+			}
+
+			tt.opts.Strict = true
+			tt.opts.Lossy = nil
+			err := dispatchConversion(tt.from, tt.to, strings.NewReader(tt.input), new(bytes.Buffer), tt.opts)
+			if lossy := errors.Is(err, ErrLossyConversion); lossy != (len(tt.expected) > 0) {
+				t.Errorf("Unexpected strict result: %v", err)
+			}
+		})
+	}
+}
+
+func TestConvert_TypeInference(t *testing.T) {
+	csvInput := "zip,age,score,ok,id,ratio\n00123,42,1.50,TRUE,9007199254740993,1e3\n"
+
+	tests := []struct {
+		name     string
+		from     string
+		input    string
+		types    *typeInference
+		expected string
+	}{
+		{
+			"csv safe by default", "csv", csvInput, nil,
+			`{"zip":"00123","age":42,"score":1.50,"ok":"TRUE","id":9007199254740993,"ratio":1e3}` + "\n",
+		},
+		{
+			"csv none", "csv", csvInput, &typeInference{Mode: inferNone},
+			`{"zip":"00123","age":"42","score":"1.50","ok":"TRUE","id":"9007199254740993","ratio":"1e3"}` + "\n",
+		},
+		{
+			"csv aggressive", "csv", csvInput, &typeInference{Mode: inferAggressive},
+			`{"zip":123,"age":42,"score":1.5,"ok":true,"id":9007199254740993,"ratio":1000}` + "\n",
+		},
+		{
+			"csv column overrides", "csv", csvInput,
+			&typeInference{Mode: inferAggressive, Columns: map[string]string{"zip": "string", "score": "number", "ok": "boolean"}},
+			`{"zip":"00123","age":42,"score":1.50,"ok":true,"id":9007199254740993,"ratio":1000}` + "\n",
+		},
+		{
+			"yaml safe by default", "yaml", "- zip: 00123\n  n: 7\n  v: 1.10\n  on: True\n  e:\n", nil,
+			`{"zip":"00123","n":7,"v":1.10,"on":"True","e":null}` + "\n",
+		},
+		{
+			"yaml safe uses the csv rules", "yaml", "- {a: TRUE, b: 0x1F, c: .inf, d: 1e3, e: ~, f: Null, g: +1}\n", nil,
+			`{"a":"TRUE","b":"0x1F","c":".inf","d":1e3,"e":null,"f":"Null","g":"+1"}` + "\n",
+		},
+		{
+			"yaml aggressive follows the core schema", "yaml", "- zip: 00123\n  v: 1.10\n  big: 99999999999999999999\n", &typeInference{Mode: inferAggressive},
+			`{"zip":123,"v":1.1,"big":99999999999999999999}` + "\n",
+		},
+		{
+			"yaml column override", "yaml", "- zip: 123\n  tags: [1, 2]\n",
+			&typeInference{Columns: map[string]string{"zip": "string", "tags": "string"}},
+			`{"zip":"123","tags":["1","2"]}` + "\n",
+		},
+		{
+			"xml element and attribute overrides", "xml", `<r><item code="007"><zip>01234</zip><n>12</n></item></r>`,
+			&typeInference{Mode: inferAggressive, Columns: map[string]string{"code": "string", "zip": "string"}},
+			`{"r":{"item":{"@code":"007","zip":"01234","n":12}}}` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := new(bytes.Buffer)
+			opts := convertOptions{Delimiter: ',', AttrPrefix: "@", Types: tt.types}
+			if err := dispatchConversion(tt.from, "ndjson", strings.NewReader(tt.input), writer, opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if writer.String() != tt.expected {
+				t.Errorf("Unexpected output:\nExpected: %s\nGot:      %s", tt.expected, writer.String())
+			}
+		})
+	}
+}
+
+func TestConvert_TypeInferenceErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  string
+		input string
+	}{
+		{"csv", "csv", "age\nabc\n"},
+		{"yaml", "yaml", "age: abc\n"},
+		{"xml", "xml", "<r><age>abc</age></r>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := convertOptions{Delimiter: ',', AttrPrefix: "@", Types: &typeInference{Columns: map[string]string{"age": "integer"}}}
+			err := dispatchConversion(tt.from, "json", strings.NewReader(tt.input), new(bytes.Buffer), opts)
+			if err == nil || !strings.Contains(err.Error(), `column "age"`) {
+				t.Errorf("Expected an error for the forced column type, got: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadTypeInference(t *testing.T) {
+	schemaPath := filepath.Join(t.TempDir(), "types.json")
+	schema := `{"type": "array", "items": {"type": "object", "properties": {
+		"zip": {"type": "string"},
+		"age": {"type": ["integer", "null"]},
+		"tags": {"type": "array", "items": {"type": "string"}},
+		"address": {"type": "object", "properties": {"number": {"type": "integer"}}}
+	}}}`
+	if err := os.WriteFile(schemaPath, []byte(schema), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	types, err := loadTypeInference(inferSafe, "age=string", schemaPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"zip": "string", "age": "string", "number": "integer"}
+	if !reflect.DeepEqual(types.Columns, expected) {
+		t.Errorf("Unexpected column types:\nExpected: %v\nGot:      %v", expected, types.Columns)
+	}
+
+	if _, err := loadTypeInference("loose", "", ""); err == nil {
+		t.Error("Expected error for an unknown inference mode")
+	}
+	if _, err := loadTypeInference(inferSafe, "zip=text", ""); err == nil {
+		t.Error("Expected error for an unknown column type")
+	}
+}
+
+func TestConvert_PreservesNumbers(t *testing.T) {
+	jsonInput := `[{"id": 9007199254740993, "price": 0.10, "rate": 1.5e-8}]`
+
+	tests := []struct {
+		to       string
+		expected string
+	}{
+		{"ndjson", `{"id":9007199254740993,"price":0.10,"rate":1.5e-8}` + "\n"},
+		{"csv", "id,price,rate\n9007199254740993,0.10,1.5e-8\n"},
+		{"yaml", "- \n  id: 9007199254740993\n  price: 0.10\n  rate: 1.5e-8"},
+		{"xml", `<?xml version="1.0" encoding="UTF-8"?>
+<root>
+  <root>
+    <id>9007199254740993</id>
+    <price>0.10</price>
+    <rate>1.5e-8</rate>
+  </root>
+</root>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.to, func(t *testing.T) {
+			writer := new(bytes.Buffer)
+			opts := convertOptions{Delimiter: ',', RootName: "root", AttrPrefix: "@"}
+			if err := dispatchConversion("json", tt.to, strings.NewReader(jsonInput), writer, opts); err != nil {
+				t.Fatalf("Error converting JSON to %s: %v", tt.to, err)
+			}
+			if writer.String() != tt.expected {
+				t.Errorf("Unexpected output:\nExpected:\n%s\nGot:\n%s", tt.expected, writer.String())
+			}
+		})
+	}
+
+	// JSON → YAML → JSON devolve os mesmos números.
+	yamlOutput := new(bytes.Buffer)
+	if err := dispatchConversion("json", "yaml", strings.NewReader(jsonInput), yamlOutput, convertOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip := new(bytes.Buffer)
+	if err := dispatchConversion("yaml", "ndjson", yamlOutput, roundTrip, convertOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := tests[0].expected; roundTrip.String() != expected {
+		t.Errorf("Unexpected YAML round trip:\nExpected: %s\nGot:      %s", expected, roundTrip.String())
+	}
+}
+
+func TestConvertCsv_HeaderOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		opts     convertOptions
+		expected string
+	}{
+		{
+			"duplicate and empty header names", "id,,id,id_2\n1,2,3,4\n", convertOptions{},
+			`{"id":1,"col2":2,"id_3":3,"id_2":4}` + "\n",
+		},
+		{
+			"no header", "1,Ann\n2,Bob\n", convertOptions{NoHeader: true},
+			`{"col1":1,"col2":"Ann"}` + "\n" + `{"col1":2,"col2":"Bob"}` + "\n",
+		},
+		{
+			"custom header replaces the first line", "a,b\n1,Ann\n", convertOptions{Header: []string{"id", "name"}, SkipRows: 1},
+			`{"id":1,"name":"Ann"}` + "\n",
+		},
+		{
+			"preamble and comments", "Relatório de vendas\nGerado em 2024-01-01\nid,total\n# parcial\n1,10\n", convertOptions{SkipRows: 2, Comment: '#'},
+			`{"id":1,"total":10}` + "\n",
+		},
+		{
+			"ragged rows padded", "id,name\n1\n2,Bob,x\n", convertOptions{RaggedRows: raggedPad},
+			`{"id":1,"name":null}` + "\n" + `{"id":2,"name":"Bob"}` + "\n",
+		},
+		{
+			"ragged rows with extra field", "id,name\n1,Ann,x,4\n", convertOptions{RaggedRows: raggedExtra},
+			`{"id":1,"name":"Ann","_extra":["x",4]}` + "\n",
+		},
+		{
+			"header only", "id,name\n", convertOptions{},
+			"",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.opts.Delimiter = ','
+			writer := new(bytes.Buffer)
+			if err := dispatchConversion("csv", "ndjson", strings.NewReader(tt.input), writer, tt.opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if writer.String() != tt.expected {
+				t.Errorf("Unexpected output:\nExpected: %s\nGot:      %s", tt.expected, writer.String())
+			}
+		})
+	}
+}
+
+func TestConvertCsv_RaggedRowsError(t *testing.T) {
+	input := "Relatório\nid,name\n1,Ann\n2,Bob,x\n"
+	err := dispatchConversion("csv", "json", strings.NewReader(input), new(bytes.Buffer), convertOptions{Delimiter: ',', SkipRows: 1})
+	if err == nil || !strings.Contains(err.Error(), "line 4: 3 field(s), header has 2") {
+		t.Errorf("Expected a ragged row error pointing at line 4, got: %v", err)
+	}
+}
+
+func TestConvertJsonToCsv_Dialect(t *testing.T) {
+	jsonInput := `[{"id": 1, "name": "Ana \"Bia\"", "note": null, "ok": true, "path": "a;b\\c"}, {"id": 2, "name": "\\N", "note": "", "ok": false, "path": "x\ny"}]`
+
+	tests := []struct {
+		name     string
+		dialect  csvDialect
+		expected string
+	}{
+		{
+			"default", csvDialect{},
+			"id;name;note;ok;path\n1;\"Ana \"\"Bia\"\"\";;true;\"a;b\\c\"\n2;\\N;;false;\"x\ny\"\n",
+		},
+		{
+			"quote all with crlf and bom", csvDialect{Quote: csvQuoteAll, CRLF: true, BOM: true},
+			"\ufeff\"id\";\"name\";\"note\";\"ok\";\"path\"\r\n\"1\";\"Ana \"\"Bia\"\"\";;\"true\";\"a;b\\c\"\r\n\"2\";\"\\N\";\"\";\"false\";\"x\ny\"\r\n",
+		},
+		{
+			"nonnumeric with null marker", csvDialect{Quote: csvQuoteNonNumeric, Null: `\N`},
+			"\"id\";\"name\";\"note\";\"ok\";\"path\"\n1;\"Ana \"\"Bia\"\"\";\\N;true;\"a;b\\c\"\n2;\"\\N\";\"\";false;\"x\ny\"\n",
+		},
+		{
+			"backslash escape", csvDialect{Escape: csvEscapeBackslash},
+			"id;name;note;ok;path\n1;\"Ana \\\"Bia\\\"\";;true;\"a;b\\\\c\"\n2;\"\\\\N\";;false;\"x\ny\"\n",
+		},
+		{
+			"no quotes", csvDialect{Quote: csvQuoteNone, Null: `\N`},
+			"id;name;note;ok;path\n1;Ana \"Bia\";\\N;true;a\\;b\\\\c\n2;\\\\N;;false;x\\ny\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := new(bytes.Buffer)
+			opts := convertOptions{Delimiter: ';', Dialect: tt.dialect}
+			if err := dispatchConversion("json", "csv", strings.NewReader(jsonInput), writer, opts); err != nil {
+				t.Fatalf("Error converting JSON to CSV: %v", err)
+			}
+			if writer.String() != tt.expected {
+				t.Errorf("Unexpected CSV output:\nExpected: %q\nGot:      %q", tt.expected, writer.String())
+			}
+		})
+	}
+}
+
+func TestConvertCsv_ReadsBOM(t *testing.T) {
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("csv", "ndjson", strings.NewReader("\ufeffid,nome\n1,João\n"), writer, convertOptions{Delimiter: ','}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := `{"id":1,"nome":"João"}` + "\n"; writer.String() != expected {
+		t.Errorf("Unexpected output:\nExpected: %s\nGot:      %s", expected, writer.String())
+	}
+}
+
+func TestConvertTsv(t *testing.T) {
+	writer := new(bytes.Buffer)
+	// O delimitador de --delimiter não vale para tsv.
+	if err := dispatchConversion("json", "tsv", strings.NewReader(`[{"id": 1, "note": "a,b"}]`), writer, convertOptions{Delimiter: ','}); err != nil {
+		t.Fatalf("Error converting JSON to TSV: %v", err)
+	}
+	if expected := "id\tnote\n1\ta,b\n"; writer.String() != expected {
+		t.Errorf("Unexpected TSV output:\nExpected: %q\nGot:      %q", expected, writer.String())
+	}
+
+	writer.Reset()
+	if err := dispatchConversion("tsv", "ndjson", strings.NewReader("id\tnote\n1\ta,b\n"), writer, convertOptions{Delimiter: ','}); err != nil {
+		t.Fatalf("Error converting TSV to NDJSON: %v", err)
+	}
+	if expected := `{"id":1,"note":"a,b"}` + "\n"; writer.String() != expected {
+		t.Errorf("Unexpected NDJSON output:\nExpected: %s\nGot:      %s", expected, writer.String())
+	}
+
+	// TSV não tem aspas: " é um caractere comum e tab, quebra de linha e
+	// "\" são escapados.
+	jsonInput := `[{"id": 1, "note": "diz \"oi\"", "text": "a\tb\nc\\d"}]`
+	expectedTsv := "id\tnote\ttext\n1\tdiz \"oi\"\ta\\tb\\nc\\\\d\n"
+	writer.Reset()
+	if err := dispatchConversion("json", "tsv", strings.NewReader(jsonInput), writer, convertOptions{}); err != nil {
+		t.Fatalf("Error converting JSON to TSV: %v", err)
+	}
+	if writer.String() != expectedTsv {
+		t.Errorf("Unexpected TSV output:\nExpected: %q\nGot:      %q", expectedTsv, writer.String())
+	}
+
+	tree, err := tsvFormat{}.Read(strings.NewReader(expectedTsv+"2\t\"x\t\\q\r\n"), convertOptions{})
+	if err != nil {
+		t.Fatalf("Error reading TSV: %v", err)
+	}
+	rows := tree.([]interface{})
+	if text, _ := rows[0].(*document.Object).Get("text"); text != "a\tb\nc\\d" {
+		t.Errorf("Escapes were not undone: %q", text)
+	}
+	if note, _ := rows[1].(*document.Object).Get("note"); note != `"x` {
+		t.Errorf("Unexpected field with a bare quote: %q", note)
+	}
+	if text, _ := rows[1].(*document.Object).Get("text"); text != `\q` {
+		t.Errorf("Unknown escape should be kept: %q", text)
+	}
+}
+
+func TestConvertFixedWidth(t *testing.T) {
+	spec, err := parseFixedSpec([]interface{}{
+		fixedSpecColumn("id", 1, 5, "integer", ""),
+		fixedSpecColumn("nome", 7, 8, "", ""),
+		fixedSpecColumn("saldo", nil, 8, "number", ""),
+		fixedSpecColumn("ativo", nil, 5, "boolean", "right"),
+	})
+	if err != nil {
+		t.Fatalf("Error parsing spec: %v", err)
+	}
+
+	jsonInput := `[{"id": 7, "nome": "Ana", "saldo": 10.50, "ativo": true}, {"id": 42, "nome": "João", "saldo": null, "ativo": false}]`
+	expected := "    7 Ana        10.50 true\n   42 João            false\n"
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("json", "fixed", strings.NewReader(jsonInput), writer, convertOptions{Fixed: spec}); err != nil {
+		t.Fatalf("Error converting JSON to fixed-width: %v", err)
+	}
+	if writer.String() != expected {
+		t.Errorf("Unexpected fixed-width output:\nExpected: %q\nGot:      %q", expected, writer.String())
+	}
+
+	writer.Reset()
+	if err := dispatchConversion("fixed", "ndjson", strings.NewReader("ID   NOME    SALDO   ATIVO\n"+expected+"\n"), writer, convertOptions{Fixed: spec, SkipRows: 1}); err != nil {
+		t.Fatalf("Error converting fixed-width to NDJSON: %v", err)
+	}
+	expectedNdjson := `{"id":7,"nome":"Ana","saldo":10.50,"ativo":true}` + "\n" + `{"id":42,"nome":"João","saldo":null,"ativo":false}` + "\n"
+	if writer.String() != expectedNdjson {
+		t.Errorf("Unexpected NDJSON output:\nExpected: %s\nGot:      %s", expectedNdjson, writer.String())
+	}
+
+	if err := dispatchConversion("json", "fixed", strings.NewReader(jsonInput), new(bytes.Buffer), convertOptions{}); !errors.Is(err, errMissingFixedSpec) {
+		t.Errorf("Expected missing spec error, got %v", err)
+	}
+}
+
+func TestParseFixedSpecErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []interface{}
+		message string
+	}{
+		{"missing width", []interface{}{fixedSpecColumn("id", 1, nil, "", "")}, "width must be a positive integer"},
+		{"overlap", []interface{}{fixedSpecColumn("a", 1, 5, "", ""), fixedSpecColumn("b", 3, 5, "", "")}, "inside the previous column"},
+		{"duplicate name", []interface{}{fixedSpecColumn("a", nil, 1, "", ""), fixedSpecColumn("a", nil, 1, "", "")}, "declared twice"},
+		{"bad type", []interface{}{fixedSpecColumn("a", nil, 1, "date", "")}, "unsupported type"},
+		{"bad align", []interface{}{fixedSpecColumn("a", nil, 1, "", "center")}, "unsupported align"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFixedSpec(tt.columns)
+			if err == nil || !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Expected error containing %q, got %v", tt.message, err)
+			}
+		})
+	}
+}
+
+func TestLoadFixedSpec(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.yaml")
+	content := "columns:\n  - name: id\n    width: 3\n  - name: nome\n    start: 5\n    width: 4\n    align: right\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := loadFixedSpec(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []fixedColumn{
+		{Name: "id", Start: 0, Width: 3, Type: columnAuto, Align: fixedAlignLeft},
+		{Name: "nome", Start: 4, Width: 4, Type: columnAuto, Align: fixedAlignRight},
+	}
+	if !reflect.DeepEqual(spec.Columns, expected) {
+		t.Errorf("Unexpected columns:\nExpected: %+v\nGot:      %+v", expected, spec.Columns)
+	}
+}
+
+// fixedSpecColumn monta uma coluna do spec como um leitor a entregaria;
+// valores nil ou vazios ficam de fora.
+func fixedSpecColumn(name string, start, width interface{}, kind, align string) *document.Object {
+	column := document.NewObject()
+	column.Set("name", name)
+	if start != nil {
+		column.Set("start", start)
+	}
+	if width != nil {
+		column.Set("width", width)
+	}
+	if kind != "" {
+		column.Set("type", kind)
+	}
+	if align != "" {
+		column.Set("align", align)
+	}
+	return column
+}
+
+func TestConvert_Encodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		encoding string
+		detected string
+	}{
+		{"latin1 guessed", "nome\nJo\xe3o\n", encodingAuto, "latin1"},
+		{"windows-1252 guessed", "nome\n\x93Jo\xe3o\x94\n", encodingAuto, "windows-1252"},
+		{"utf-16le bom", "\xff\xfen\x00o\x00m\x00e\x00\n\x00J\x00o\x00\xe3\x00o\x00\n\x00", encodingAuto, "utf-16le"},
+		{"explicit latin1", "nome\nJo\xe3o\n", "latin1", "latin1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader, encoding, err := decodeInput(bufio.NewReaderSize(strings.NewReader(tt.input), detectPeekSize), tt.encoding)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if encoding != tt.detected {
+				t.Errorf("Expected encoding %s, got %s", tt.detected, encoding)
+			}
+
+			writer := new(bytes.Buffer)
+			if err := dispatchConversion("csv", "ndjson", reader, writer, convertOptions{Delimiter: ','}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := `{"nome":"João"}` + "\n"
+			if tt.detected == "windows-1252" {
+				expected = `{"nome":"“João”"}` + "\n"
+			}
+			if writer.String() != expected {
+				t.Errorf("Unexpected output:\nExpected: %s\nGot:      %s", expected, writer.String())
+			}
+		})
+	}
+
+	// Entradas zip (xlsx) passam sem conversão.
+	zip := formatSamples["xlsx"]
+	reader, encoding, err := decodeInput(bufio.NewReaderSize(strings.NewReader(zip), detectPeekSize), encodingAuto)
+	if err != nil || encoding != "" {
+		t.Fatalf("Expected zip input untouched, got %q, %v", encoding, err)
+	}
+	if rest, _ := io.ReadAll(reader); string(rest) != zip {
+		t.Error("Zip input was changed")
+	}
+}
+
+func TestDetectFileEncoding(t *testing.T) {
+	// O "ç" fica cortado no limite da amostra: o resto do arquivo não é lido.
+	large := strings.Repeat("a", detectPeekSize-1) + "ç" + strings.Repeat("b", detectPeekSize)
+	tests := []struct {
+		name     string
+		content  string
+		encoding string
+		bom      bool
+	}{
+		{"utf-8 cut at the sample limit", large, "utf-8", false},
+		{"latin1", "nome\nJo\xe3o\n", "latin1", false},
+		{"utf-16le bom", "\xff\xfea\x00", "utf-16le", true},
+		{"zip", formatSamples["xlsx"], "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "input")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			encoding, bom, err := detectFileEncoding(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if encoding != tt.encoding || bom != tt.bom {
+				t.Errorf("Expected %q (bom %v), got %q (bom %v)", tt.encoding, tt.bom, encoding, bom)
+			}
+		})
+	}
+}
+
+func TestConvertXml_DeclaredEncoding(t *testing.T) {
+	input := "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?>\n<pessoa><nome>Jo\xe3o</nome></pessoa>"
+	reader, _, err := decodeInput(bufio.NewReaderSize(strings.NewReader(input), detectPeekSize), "latin1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("xml", "json", reader, writer, convertOptions{AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(writer.String(), `"nome": "João"`) {
+		t.Errorf("Unexpected output: %s", writer.String())
+	}
+
+	path := filepath.Join(t.TempDir(), "latin1.xml")
+	if err := os.WriteFile(path, []byte(input), 0644); err != nil {
+		t.Fatal(err)
+	}
+	issues, err := validateFileXML(path)
+	if err != nil || len(issues) != 0 {
+		t.Errorf("Expected valid XML, got %v, %v", issues, err)
+	}
+}
+
+func TestConvertXml_OutputEncodingRoundTrip(t *testing.T) {
+	for _, encoding := range []string{"latin1", "windows-1252", "utf-16le", "utf-16be"} {
+		t.Run(encoding, func(t *testing.T) {
+			output := new(bytes.Buffer)
+			encoder := newOutputEncoder(output, encoding, nil)
+			opts := convertOptions{RootName: "root", AttrPrefix: "@", Encoding: encoding}
+			if err := dispatchConversion("json", "xml", strings.NewReader(`{"nome": "João"}`), encoder, opts); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if err := encoder.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			reader, _, err := decodeInput(bufio.NewReaderSize(bytes.NewReader(output.Bytes()), detectPeekSize), encodingAuto)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			writer := new(bytes.Buffer)
+			if err := dispatchConversion("xml", "json", reader, writer, convertOptions{AttrPrefix: "@"}); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(writer.String(), `"nome": "João"`) {
+				t.Errorf("Unexpected output: %s", writer.String())
+			}
+		})
+	}
+
+	if declaration := xmlDeclaration("latin1"); declaration != `<?xml version="1.0" encoding="ISO-8859-1"?>`+"\n" {
+		t.Errorf("Unexpected declaration: %s", declaration)
+	}
+}
+
+func TestNewOutputEncoder(t *testing.T) {
+	log := &lossyLog{}
+	output := new(bytes.Buffer)
+	encoder := newOutputEncoder(output, "latin1", log)
+	if err := dispatchConversion("json", "csv", strings.NewReader(`[{"preço": "€1"}, {"preço": "€2"}]`), encoder, convertOptions{Delimiter: ','}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := "pre\xe7o\n?1\n?2\n"; output.String() != expected {
+		t.Errorf("Unexpected output: %q", output.String())
+	}
+	expected := []lossyEvent{{Path: "", Reason: `character '€' has no latin1 representation and is written as "?"`}}
+	if !reflect.DeepEqual(log.Events, expected) {
+		t.Errorf("Unexpected lossy events: %v", log.Events)
+	}
+}
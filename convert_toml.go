@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"cli-convert/document"
+)
+
+// tomlFormat lê e escreve documentos TOML 1.0.
+type tomlFormat struct{}
+
+func (tomlFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	return parseToml(input)
+}
+
+func (tomlFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	return writeToml(data, output)
+}
+
+// writeToml escreve a árvore como TOML. Valores sem representação em TOML
+// (documento que não é tabela, null) geram erro com o caminho da chave.
+func writeToml(data interface{}, output io.Writer) error {
+	root, ok := data.(*document.Object)
+	if !ok {
+		return fmt.Errorf("toml: top-level value must be a table, got %s", tomlValueKind(data))
+	}
+
+	var buf bytes.Buffer
+	if err := writeTomlTable(&buf, root, nil); err != nil {
+		return err
+	}
+
+	if _, err := output.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+// writeTomlTable escreve os pares chave/valor da tabela e, em seguida, suas
+// subtabelas e arrays de tabelas, como o TOML exige.
+func writeTomlTable(buf *bytes.Buffer, table *document.Object, path []string) error {
+	var subtables, arrayTables []string
+
+	for _, key := range table.Keys() {
+		value, _ := table.Get(key)
+		switch v := value.(type) {
+		case *document.Object:
+			subtables = append(subtables, key)
+			continue
+		case []interface{}:
+			if isTomlArrayOfTables(v) {
+				arrayTables = append(arrayTables, key)
+				continue
+			}
+		}
+
+		text, err := formatTomlValue(value, appendTomlPath(path, key))
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "%s = %s\n", formatTomlKey(key), text)
+	}
+
+	for _, key := range subtables {
+		value, _ := table.Get(key)
+		child := value.(*document.Object)
+		childPath := appendTomlPath(path, key)
+
+		if hasTomlValues(child) || child.Len() == 0 {
+			writeTomlHeader(buf, "[%s]", childPath)
+		}
+		if err := writeTomlTable(buf, child, childPath); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range arrayTables {
+		value, _ := table.Get(key)
+		childPath := appendTomlPath(path, key)
+		for _, item := range value.([]interface{}) {
+			writeTomlHeader(buf, "[[%s]]", childPath)
+			if err := writeTomlTable(buf, item.(*document.Object), childPath); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeTomlHeader(buf *bytes.Buffer, format string, path []string) {
+	if buf.Len() > 0 {
+		buf.WriteString("\n")
+	}
+	keys := make([]string, len(path))
+	for i, key := range path {
+		keys[i] = formatTomlKey(key)
+	}
+	fmt.Fprintf(buf, format+"\n", strings.Join(keys, "."))
+}
+
+func appendTomlPath(path []string, key string) []string {
+	return append(append([]string(nil), path...), key)
+}
+
+// hasTomlValues indica se a tabela tem pares chave/valor próprios, que
+// precisam de um cabeçalho para serem escritos.
+func hasTomlValues(table *document.Object) bool {
+	for _, key := range table.Keys() {
+		value, _ := table.Get(key)
+		switch v := value.(type) {
+		case *document.Object:
+			continue
+		case []interface{}:
+			if isTomlArrayOfTables(v) {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isTomlArrayOfTables(list []interface{}) bool {
+	if len(list) == 0 {
+		return false
+	}
+	for _, item := range list {
+		if _, ok := item.(*document.Object); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// formatTomlValue formata um valor em uma linha (tabelas viram tabelas
+// inline).
+func formatTomlValue(value interface{}, path []string) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", fmt.Errorf("toml: %s: null values cannot be represented", strings.Join(path, "."))
+	case string:
+		return quoteTomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return formatTomlFloat(v), nil
+	case json.Number:
+		return formatTomlNumber(v, path)
+	case document.DateTime:
+		return v.Text, nil
+
+	case *document.Object:
+		if v.Len() == 0 {
+			return "{}", nil
+		}
+		parts := make([]string, 0, v.Len())
+		for _, key := range v.Keys() {
+			item, _ := v.Get(key)
+			text, err := formatTomlValue(item, appendTomlPath(path, key))
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, formatTomlKey(key)+" = "+text)
+		}
+		return "{ " + strings.Join(parts, ", ") + " }", nil
+
+	case []interface{}:
+		// TOML 1.0 aceita arrays com tipos misturados.
+		parts := make([]string, 0, len(v))
+		for i, item := range v {
+			text, err := formatTomlValue(item, appendTomlPath(path, fmt.Sprintf("[%d]", i)))
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, text)
+		}
+		return "[" + strings.Join(parts, ", ") + "]", nil
+	}
+	return quoteTomlString(fmt.Sprintf("%v", value)), nil
+}
+
+// formatTomlNumber escreve o número com o texto original. Os números JSON
+// também são números TOML válidos; só inteiros fora do int64 não cabem.
+func formatTomlNumber(n json.Number, path []string) (string, error) {
+	text := string(n)
+	if !strings.ContainsAny(text, ".eE") {
+		if _, err := strconv.ParseInt(text, 10, 64); err != nil {
+			return "", fmt.Errorf("toml: %s: integer %s is out of the 64-bit range", strings.Join(path, "."), text)
+		}
+	}
+	return text, nil
+}
+
+// formatTomlFloat escreve floats com valor inteiro como inteiros (ex.: os
+// números lidos de planilhas e do YAML como float64).
+func formatTomlFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == math.Trunc(f) && math.Abs(f) < 1<<53:
+		return strconv.FormatInt(int64(f), 10)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// tomlValueKind nomeia o tipo de um valor nas mensagens de erro.
+func tomlValueKind(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int, int64, float64, json.Number:
+		return "number"
+	case document.DateTime:
+		return "datetime"
+	case *document.Object:
+		return "table"
+	case []interface{}:
+		return "array"
+	}
+	return "string"
+}
+
+func formatTomlKey(key string) string {
+	if tomlBareKeyPattern.MatchString(key) {
+		return key
+	}
+	return quoteTomlString(key)
+}
+
+func quoteTomlString(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\r':
+			sb.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\u%04X`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
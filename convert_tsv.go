@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// tsvFormat lê e escreve valores separados por tab. Ao contrário do CSV, o
+// TSV não tem aspas: cada linha é um registro e cada tab separa um campo.
+// Tab, quebras de linha e "\" dentro de um campo são escritos como \t, \n,
+// \r e \\ (a convenção do "linear TSV"), e a leitura desfaz esses escapes.
+type tsvFormat struct{}
+
+func (tsvFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	buffered, err := skipCsvPreamble(input, opts)
+	if err != nil {
+		return nil, err
+	}
+	return readRecordDocument(newTsvReader(buffered, opts.Comment), opts)
+}
+
+func (tsvFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	writer := newTSVWriter(output, opts.Dialect)
+	if err := writeDataAsCSV(writer, data, opts.Lossy); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+func (tsvFormat) WriteStream(next recordStream, output io.Writer, opts convertOptions) error {
+	writer := newTSVWriter(output, opts.Dialect)
+	if err := streamDataAsCSV(writer, next, csvHeaderSample, opts.Lossy); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// newTSVWriter devolve um csvWriter sem aspas, separado por tab. Do
+// dialeto valem só o fim de linha, o BOM e o marcador de null.
+func newTSVWriter(output io.Writer, dialect csvDialect) *csvWriter {
+	dialect.Quote = csvQuoteNone
+	return newCSVWriter(output, '\t', dialect)
+}
+
+// tsvReader separa as linhas em campos pelo tab. Linhas vazias e, com
+// comment, linhas que começam por ele são ignoradas, como no encoding/csv.
+type tsvReader struct {
+	input   *bufio.Reader
+	comment rune
+	line    int
+	starts  []int
+}
+
+func newTsvReader(input *bufio.Reader, comment rune) *tsvReader {
+	return &tsvReader{input: input, comment: comment}
+}
+
+// Read devolve os campos da próxima linha, ou io.EOF no fim da entrada.
+func (r *tsvReader) Read() ([]string, error) {
+	for {
+		text, err := r.input.ReadString('\n')
+		if err != nil && (err != io.EOF || text == "") {
+			return nil, err
+		}
+		r.line++
+		text = strings.TrimSuffix(strings.TrimSuffix(text, "\n"), "\r")
+		if text == "" || (r.comment != 0 && strings.HasPrefix(text, string(r.comment))) {
+			continue
+		}
+
+		fields := strings.Split(text, "\t")
+		r.starts = r.starts[:0]
+		offset := 0
+		for i, field := range fields {
+			r.starts = append(r.starts, offset+1)
+			offset += len(field) + 1
+			fields[i] = unescapeTsvField(field)
+		}
+		return fields, nil
+	}
+}
+
+// FieldPos devolve a linha e a coluna (em bytes, a partir de 1) do campo
+// indicado na última linha lida.
+func (r *tsvReader) FieldPos(field int) (line, column int) {
+	if field < 0 || field >= len(r.starts) {
+		return r.line, 0
+	}
+	return r.line, r.starts[field]
+}
+
+// unescapeTsvField desfaz \t, \n, \r e \\; outras barras ficam como estão.
+func unescapeTsvField(field string) string {
+	if !strings.Contains(field, `\`) {
+		return field
+	}
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		if field[i] != '\\' || i+1 == len(field) {
+			b.WriteByte(field[i])
+			continue
+		}
+		switch field[i+1] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case '\\':
+			b.WriteByte('\\')
+		default:
+			b.WriteByte('\\')
+			continue
+		}
+		i++
+	}
+	return b.String()
+}
@@ -5,125 +5,169 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
-	"sort"
-	"strconv"
 	"strings"
+
+	"cli-convert/document"
 )
 
-var ErrLossyConversion = errors.New("data was flattened; hierarchical structure is lost")
+// ErrLossyConversion indica, no modo strict, que a conversão perdeu dados;
+// os detalhes ficam em convertOptions.Lossy.
+var ErrLossyConversion = errors.New("conversion loses data")
 
-func parseValue(s string) interface{} {
-	s = strings.TrimSpace(s)
+// ensureOutputExtension mantém filename quando a extensão já é uma das do
+// formato de destino (ex.: ".jsonl" para ndjson) e, caso contrário, troca
+// a extensão pela primeira da lista.
+func ensureOutputExtension(filename string, extensions []string) string {
+	filename = strings.TrimSpace(filename)
 
-	if (strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`)) ||
-		(strings.HasPrefix(s, `'`) && strings.HasSuffix(s, `'`)) {
-		if len(s) > 1 {
-			return s[1 : len(s)-1]
+	ext := strings.ToLower(filepath.Ext(filename))
+	for _, candidate := range extensions {
+		if ext == candidate {
+			return filename
 		}
-		return ""
-	}
-	if s == "" || s == "null" {
-		return nil
-	}
-	if s == "true" {
-		return true
 	}
-	if s == "false" {
-		return false
-	}
-
-	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") ||
-		strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+	return strings.TrimSuffix(filename, filepath.Ext(filename)) + extensions[0]
+}
 
-		var jsonData interface{}
-		if err := json.Unmarshal([]byte(s), &jsonData); err == nil {
-			return jsonData
-		}
-	}
+// convertOptions reúne os ajustes de conversão vindos da linha de comando.
+type convertOptions struct {
+	Delimiter rune
+	// NoHeader e Header indicam um CSV sem linha de cabeçalho; os nomes
+	// vêm de Header ou são gerados (col1, col2, ...). SkipRows descarta
+	// linhas do início, Comment marca linhas de comentário e RaggedRows
+	// define o que fazer com linhas de tamanho diferente do cabeçalho
+	// ("error", "pad" ou "extra").
+	NoHeader   bool
+	Header     []string
+	SkipRows   int
+	Comment    rune
+	RaggedRows string
+	// Dialect controla aspas, fim de linha, BOM e null na escrita CSV.
+	Dialect    csvDialect
+	RootName   string
+	AttrPrefix string
+	// Documents define como um stream YAML com vários documentos é
+	// entregue: "array", "ndjson" ou "split" (um arquivo por documento).
+	Documents  string
+	YamlStream bool
+	// OnBadLine define o que fazer com linhas NDJSON inválidas: "abort",
+	// "skip" ou "collect" (guardadas em BadLines).
+	OnBadLine string
+	BadLines  *badLineLog
+	// Sheet escolhe a planilha xlsx pelo nome ou posição (a partir de 1);
+	// "*" lê todas. Na escrita, dá nome à planilha única.
+	Sheet string
+	// Flatten achata cada registro em chaves pontilhadas ("address.city",
+	// "tags[0]"); Unflatten reconstrói a estrutura a partir delas.
+	Flatten   bool
+	Unflatten bool
+	// Lossy recebe os dados que a conversão não conseguiu preservar; com
+	// Strict, qualquer evento faz a conversão falhar com ErrLossyConversion.
+	Lossy  *lossyLog
+	Strict bool
+	// Types decide o tipo dos valores lidos como texto (CSV, XML e YAML);
+	// nil usa o modo safe.
+	Types *typeInference
+	// Fixed descreve as colunas do formato de largura fixa (--fixed-spec).
+	Fixed *fixedSpec
+	// Encoding é a codificação da saída (--output-encoding), usada por
+	// formatos que a declaram no próprio arquivo, como o XML; vazio é UTF-8.
+	Encoding string
+}
 
-	if i, err := strconv.Atoi(s); err == nil {
-		return i
+// dispatchConversion resolve os formatos de origem e destino no registro e
+// executa a conversão.
+func dispatchConversion(from, to string, input io.Reader, output io.Writer, opts convertOptions) error {
+	if opts.Strict && opts.Lossy == nil {
+		opts.Lossy = &lossyLog{}
 	}
-	if f, err := strconv.ParseFloat(s, 64); err == nil {
-		return f
+	if err := convertFormats(from, to, input, output, opts); err != nil {
+		return err
 	}
-	return s
+	return checkLossy(opts)
 }
 
-func ensureOutputExtension(filename, desiredExt string) string {
-	filename = strings.TrimSpace(filename)
-	desiredExt = strings.ToLower(strings.TrimPrefix(desiredExt, "."))
-
-	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
-	if ext != desiredExt {
-		filename = strings.TrimSuffix(filename, filepath.Ext(filename))
-		filename += "." + desiredExt
+// checkLossy transforma os eventos de perda em erro no modo strict.
+func checkLossy(opts convertOptions) error {
+	if opts.Strict && opts.Lossy.count() > 0 {
+		return fmt.Errorf("%w in %d place(s)", ErrLossyConversion, opts.Lossy.count())
 	}
-	return filename
+	return nil
 }
 
-// dispatchConversion roteia a conversão com base nos formatos de origem e destino.
-func dispatchConversion(from, to string, input io.Reader, output io.Writer, delimiter rune, rootName string) error {
-	if from == to {
-		return fmt.Errorf("source and destination formats are the same: %s", from)
+func convertFormats(from, to string, input io.Reader, output io.Writer, opts convertOptions) error {
+	source, err := sourceFormat(from)
+	if err != nil {
+		return err
+	}
+	target, err := targetFormat(to)
+	if err != nil {
+		return err
+	}
+	if source == target {
+		return fmt.Errorf("source and destination formats are the same: %s", source.Name)
 	}
 
-	key := from + "→" + to
-
-	switch key {
-	case "json→csv":
-		return convertJsonToCsv(input, output, delimiter)
-	case "json→xml":
-		return convertJsonToXml(input, output, rootName)
-	case "json→yaml":
-		return convertJsonToYaml(input, output)
-
-	case "csv→json":
-		return convertCsvToJson(input, output, delimiter)
-	case "csv→xml":
-		return convertCsvToXml(input, output, delimiter, rootName)
-	case "csv→yaml":
-		return convertCsvToYaml(input, output, delimiter)
-
-	case "xml→json":
-		return convertXmlToJson(input, output)
-	case "xml→csv":
-		return convertXmlToCsv(input, output, delimiter)
-	case "xml→yaml":
-		return convertXmlToYaml(input, output)
-
-	case "yaml→json":
-		return convertYamlToJson(input, output)
-	case "yaml→csv":
-		return convertYamlToCsv(input, output, delimiter)
-	case "yaml→xml":
-		return convertYamlToXml(input, output, rootName)
-
-	default:
-		return fmt.Errorf("unsupported conversion: %s → %s", from, to)
+	if opts.Documents != "" && opts.Documents != "array" && source.Name != "yaml" {
+		return fmt.Errorf("--documents %s requires YAML input", opts.Documents)
+	}
+	if opts.Documents == "ndjson" {
+		// Um documento por linha: --to json continua aceito e escreve NDJSON.
+		if target.Name == "json" {
+			target, _ = lookupFormat("ndjson")
+		}
+		if target.Name != "ndjson" {
+			return fmt.Errorf("--documents ndjson requires --to ndjson")
+		}
 	}
+
+	return convertBetween(source, target, input, output, opts)
+}
+
+// numberedOutputPath gera o nome do n-ésimo arquivo de saída no modo split
+// (dados.json → dados-1.json).
+func numberedOutputPath(path string, n int) string {
+	ext := filepath.Ext(path)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(path, ext), n, ext)
 }
 
 func WriteAsYaml(data interface{}, output io.Writer) error {
 	return writeYamlRecursive(output, data, 0)
 }
 
+// WriteAsYamlStream escreve cada item de um array como um documento YAML
+// separado por "---". Outros valores viram um único documento.
+func WriteAsYamlStream(data interface{}, output io.Writer) error {
+	docs, ok := data.([]interface{})
+	if !ok {
+		docs = []interface{}{data}
+	}
+
+	for _, doc := range docs {
+		if _, err := fmt.Fprint(output, "---\n"); err != nil {
+			return err
+		}
+		if err := WriteAsYaml(doc, output); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprint(output, "\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func writeYamlRecursive(writer io.Writer, data interface{}, indentLevel int) error {
 	var err error
 
 	switch v := data.(type) {
-	case map[string]interface{}:
+	case *document.Object:
 		indent := strings.Repeat("  ", indentLevel)
 
-		keys := make([]string, 0, len(v))
-		for k := range v {
-			keys = append(keys, k)
-		}
-		sort.Strings(keys)
-
-		for i, key := range keys {
-			value := v[key]
+		for i, key := range v.Keys() {
+			value, _ := v.Get(key)
 
 			if i > 0 {
 				_, err = fmt.Fprint(writer, "\n")
@@ -132,12 +176,12 @@ func writeYamlRecursive(writer io.Writer, data interface{}, indentLevel int) err
 				}
 			}
 
-			_, err = fmt.Fprintf(writer, "%s%s: ", indent, key)
+			_, err = fmt.Fprintf(writer, "%s%s: ", indent, formatYamlKey(key))
 			if err != nil {
 				return err
 			}
 
-			_, isMap := value.(map[string]interface{})
+			_, isMap := value.(*document.Object)
 			_, isSlice := value.([]interface{})
 
 			if isMap || isSlice {
@@ -174,7 +218,7 @@ func writeYamlRecursive(writer io.Writer, data interface{}, indentLevel int) err
 				return err
 			}
 
-			_, isMap := item.(map[string]interface{})
+			_, isMap := item.(*document.Object)
 			_, isSlice := item.([]interface{})
 
 			if isMap || isSlice {
@@ -196,6 +240,11 @@ func writeYamlRecursive(writer io.Writer, data interface{}, indentLevel int) err
 		}
 
 	default:
+		if f, ok := v.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+			_, err = fmt.Fprint(writer, formatYamlSpecialFloat(f))
+			return err
+		}
+
 		jsonValue, err := json.Marshal(v)
 		if err != nil {
 			return fmt.Errorf("failed to marshal simple value: %v", err)
@@ -208,3 +257,35 @@ func writeYamlRecursive(writer io.Writer, data interface{}, indentLevel int) err
 	}
 	return nil
 }
+
+// formatYamlKey devolve a chave como escalar simples quando isso é seguro e
+// entre aspas quando ela seria lida com outro tipo ou quebraria a sintaxe.
+func formatYamlKey(key string) string {
+	plainSafe := key != "" &&
+		!strings.ContainsAny(key[:1], "-?:,[]{}#&*!|>'\"%@` \t") &&
+		!strings.ContainsAny(key, "\n\r\t") &&
+		!strings.Contains(key, ": ") &&
+		!strings.Contains(key, " #") &&
+		!strings.HasSuffix(key, ":") &&
+		!strings.HasSuffix(key, " ")
+
+	if plainSafe {
+		if _, isString := resolveYamlCore(key).(string); isString {
+			return key
+		}
+	}
+
+	quoted, _ := json.Marshal(key)
+	return string(quoted)
+}
+
+func formatYamlSpecialFloat(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return ".nan"
+	case math.IsInf(v, -1):
+		return "-.inf"
+	default:
+		return ".inf"
+	}
+}
@@ -0,0 +1,840 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"cli-convert/document"
+)
+
+// xlsxAllSheets em --sheet lê todas as planilhas como um objeto
+// {nome: registros}.
+const xlsxAllSheets = "*"
+
+// xlsxFormat lê uma planilha de um arquivo .xlsx como registros (a primeira
+// linha é o cabeçalho) e escreve registros em uma pasta de trabalho nova.
+type xlsxFormat struct{}
+
+func (xlsxFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %v", err)
+	}
+	book, err := openXlsxWorkbook(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Sheet == xlsxAllSheets {
+		result := document.NewObject()
+		for _, sheet := range book.sheets {
+			records, err := book.readSheetRecords(sheet)
+			if err != nil {
+				return nil, err
+			}
+			result.Set(sheet.Name, records)
+		}
+		return result, nil
+	}
+
+	sheet, err := book.findSheet(opts.Sheet)
+	if err != nil {
+		return nil, err
+	}
+	return book.readSheetRecords(sheet)
+}
+
+func (xlsxFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	return writeXlsx(data, output, opts.Sheet, opts.Lossy)
+}
+
+// ──────────────────────────────────────────────
+//  Leitura
+// ──────────────────────────────────────────────
+
+type xlsxWorkbookXML struct {
+	Properties struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []xlsxSheetRef `xml:"sheets>sheet"`
+}
+
+type xlsxSheetRef struct {
+	Name  string `xml:"name,attr"`
+	RelID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+}
+
+type xlsxRelationships struct {
+	Items []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r xlsxRichText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var sb strings.Builder
+	sb.WriteString(r.Text)
+	for _, run := range r.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxRichText `xml:"si"`
+}
+
+type xlsxStyleSheet struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			Ref    string        `xml:"r,attr"`
+			Type   string        `xml:"t,attr"`
+			Style  int           `xml:"s,attr"`
+			Value  string        `xml:"v"`
+			Inline *xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+type xlsxSheet struct {
+	Name string
+	Path string
+}
+
+// xlsxWorkbook guarda as partes compartilhadas entre as planilhas.
+type xlsxWorkbook struct {
+	files    map[string]*zip.File
+	sheets   []xlsxSheet
+	strings  []string
+	dateKind map[int]document.DateTimeKind // estilo de célula → tipo de data
+	date1904 bool
+}
+
+func openXlsxWorkbook(data []byte) (*xlsxWorkbook, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: not a valid workbook: %v", err)
+	}
+
+	book := &xlsxWorkbook{files: make(map[string]*zip.File), dateKind: make(map[int]document.DateTimeKind)}
+	for _, file := range archive.File {
+		book.files[strings.TrimPrefix(file.Name, "/")] = file
+	}
+
+	var workbook xlsxWorkbookXML
+	if err := book.decodePart("xl/workbook.xml", &workbook, true); err != nil {
+		return nil, err
+	}
+	book.date1904 = workbook.Properties.Date1904 == "1" || workbook.Properties.Date1904 == "true"
+
+	var rels xlsxRelationships
+	if err := book.decodePart("xl/_rels/workbook.xml.rels", &rels, true); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Items))
+	for _, rel := range rels.Items {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+	for _, ref := range workbook.Sheets {
+		target, ok := targets[ref.RelID]
+		if !ok {
+			return nil, fmt.Errorf("xlsx: sheet %q has no worksheet part", ref.Name)
+		}
+		book.sheets = append(book.sheets, xlsxSheet{Name: ref.Name, Path: target})
+	}
+	if len(book.sheets) == 0 {
+		return nil, fmt.Errorf("xlsx: workbook has no sheets")
+	}
+
+	var shared xlsxSharedStrings
+	if err := book.decodePart("xl/sharedStrings.xml", &shared, false); err != nil {
+		return nil, err
+	}
+	for _, item := range shared.Items {
+		book.strings = append(book.strings, item.String())
+	}
+
+	var styles xlsxStyleSheet
+	if err := book.decodePart("xl/styles.xml", &styles, false); err != nil {
+		return nil, err
+	}
+	customFormats := make(map[int]string, len(styles.NumFmts))
+	for _, numFmt := range styles.NumFmts {
+		customFormats[numFmt.ID] = numFmt.Code
+	}
+	for i, xf := range styles.CellXfs {
+		if kind, ok := xlsxDateFormat(xf.NumFmtID, customFormats[xf.NumFmtID]); ok {
+			book.dateKind[i] = kind
+		}
+	}
+	return book, nil
+}
+
+// decodePart decodifica uma parte XML do pacote. Partes opcionais ausentes
+// deixam target vazio.
+func (b *xlsxWorkbook) decodePart(name string, target interface{}, required bool) error {
+	file, ok := b.files[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("xlsx: missing part %s", name)
+		}
+		return nil
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("xlsx: failed to open %s: %v", name, err)
+	}
+	defer reader.Close()
+
+	if err := xml.NewDecoder(reader).Decode(target); err != nil {
+		return fmt.Errorf("xlsx: failed to parse %s: %v", name, err)
+	}
+	return nil
+}
+
+// findSheet escolhe a planilha pelo nome ou pela posição (a partir de 1).
+// Sem seleção, usa a primeira.
+func (b *xlsxWorkbook) findSheet(selector string) (xlsxSheet, error) {
+	if selector == "" {
+		return b.sheets[0], nil
+	}
+	for _, sheet := range b.sheets {
+		if sheet.Name == selector {
+			return sheet, nil
+		}
+	}
+	if index, err := strconv.Atoi(selector); err == nil {
+		if index >= 1 && index <= len(b.sheets) {
+			return b.sheets[index-1], nil
+		}
+		return xlsxSheet{}, fmt.Errorf("xlsx: sheet index %d out of range (workbook has %d sheets)", index, len(b.sheets))
+	}
+
+	names := make([]string, len(b.sheets))
+	for i, sheet := range b.sheets {
+		names[i] = sheet.Name
+	}
+	return xlsxSheet{}, fmt.Errorf("xlsx: sheet %q not found (available: %s)", selector, strings.Join(names, ", "))
+}
+
+// readSheetRecords devolve as linhas da planilha como registros, usando a
+// primeira linha como cabeçalho.
+func (b *xlsxWorkbook) readSheetRecords(sheet xlsxSheet) ([]interface{}, error) {
+	var worksheet xlsxWorksheet
+	if err := b.decodePart(sheet.Path, &worksheet, true); err != nil {
+		return nil, err
+	}
+
+	// Linhas em branco são ignoradas, como no leitor CSV; o cabeçalho é a
+	// primeira linha com conteúdo.
+	var lines [][]interface{}
+	for _, row := range worksheet.Rows {
+		var values []interface{}
+		column := 0
+		for _, cell := range row.Cells {
+			if cell.Ref != "" {
+				index, err := xlsxColumnIndex(cell.Ref)
+				if err != nil {
+					return nil, fmt.Errorf("xlsx: sheet %q: %v", sheet.Name, err)
+				}
+				column = index
+			}
+			value, err := b.cellValue(cell.Type, cell.Style, cell.Value, cell.Inline)
+			if err != nil {
+				return nil, fmt.Errorf("xlsx: sheet %q, cell %s: %v", sheet.Name, cell.Ref, err)
+			}
+			if value != nil {
+				for len(values) <= column {
+					values = append(values, nil)
+				}
+				values[column] = value
+			}
+			column++
+		}
+		if len(values) > 0 {
+			lines = append(lines, values)
+		}
+	}
+
+	records := []interface{}{}
+	if len(lines) == 0 {
+		return records, nil
+	}
+
+	// Os nomes passam pelas mesmas regras do CSV: repetidos ganham sufixo
+	// e vazios, ou além do cabeçalho, viram colN.
+	width := 0
+	for _, values := range lines {
+		width = max(width, len(values))
+	}
+	names := make([]string, width)
+	for i, value := range lines[0] {
+		names[i] = xlsxHeaderName(value)
+	}
+	header := dedupeCsvHeader(names)
+
+	for _, values := range lines[1:] {
+		row := document.NewObject()
+		for i, name := range header {
+			var value interface{}
+			if i < len(values) {
+				value = values[i]
+			}
+			row.Set(name, value)
+		}
+		records = append(records, row)
+	}
+	return records, nil
+}
+
+func xlsxHeaderName(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v", value))
+}
+
+func (b *xlsxWorkbook) cellValue(cellType string, style int, raw string, inline *xlsxRichText) (interface{}, error) {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil || index < 0 || index >= len(b.strings) {
+			return nil, fmt.Errorf("invalid shared string index %q", raw)
+		}
+		return b.strings[index], nil
+	case "inlineStr":
+		if inline == nil {
+			return nil, nil
+		}
+		return inline.String(), nil
+	case "str", "e":
+		return raw, nil
+	case "b":
+		return raw == "1" || raw == "true", nil
+	case "d":
+		if value, ok := parseTomlDateTime(raw); ok {
+			return value, nil
+		}
+		return raw, nil
+	}
+
+	if raw == "" {
+		return nil, nil
+	}
+	number, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q", raw)
+	}
+	if kind, isDate := b.dateKind[style]; isDate {
+		return xlsxSerialToDateTime(number, kind, b.date1904), nil
+	}
+	if number == math.Trunc(number) && math.Abs(number) < 1<<53 {
+		return int(number), nil
+	}
+	// O texto gravado na planilha é mantido, para que decimais voltem
+	// iguais na saída.
+	if raw = strings.TrimSpace(raw); jsonNumberPattern.MatchString(raw) {
+		return json.Number(raw), nil
+	}
+	return number, nil
+}
+
+// xlsxColumnIndex converte a referência "AB12" no índice de coluna 27.
+func xlsxColumnIndex(ref string) (int, error) {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r >= 'A' && r <= 'Z' {
+			index = index*26 + int(r-'A'+1)
+			letters++
+		} else if r >= 'a' && r <= 'z' {
+			index = index*26 + int(r-'a'+1)
+			letters++
+		} else {
+			break
+		}
+	}
+	if letters == 0 {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return index - 1, nil
+}
+
+func xlsxColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxDateFormat identifica formatos numéricos de data/hora: os embutidos do
+// Excel e os personalizados que usam códigos de data ou hora.
+func xlsxDateFormat(id int, code string) (document.DateTimeKind, bool) {
+	switch {
+	case id >= 14 && id <= 17, id >= 27 && id <= 36, id >= 50 && id <= 58:
+		return document.LocalDate, true
+	case id == 22:
+		return document.LocalDateTime, true
+	case id >= 18 && id <= 21, id >= 45 && id <= 47:
+		return document.LocalTime, true
+	}
+	if code == "" {
+		return 0, false
+	}
+
+	var cleaned strings.Builder
+	inQuotes := false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case c == '\\' || c == '_' || c == '*':
+			i++
+		case c == '[':
+			end := strings.IndexByte(code[i:], ']')
+			if end < 0 {
+				i = len(code)
+				break
+			}
+			section := strings.ToLower(code[i+1 : i+end])
+			if strings.Trim(section, "hms") == "" {
+				cleaned.WriteString(section)
+			}
+			i += end
+		default:
+			cleaned.WriteByte(c)
+		}
+	}
+
+	lower := strings.ToLower(cleaned.String())
+	if strings.Contains(lower, "general") {
+		return 0, false
+	}
+	hasDate := strings.ContainsAny(lower, "yd")
+	hasTime := strings.ContainsAny(lower, "hs")
+	switch {
+	case hasDate && hasTime:
+		return document.LocalDateTime, true
+	case hasDate, strings.Contains(lower, "m") && !hasTime:
+		return document.LocalDate, true
+	case hasTime:
+		return document.LocalTime, true
+	}
+	return 0, false
+}
+
+// xlsxEpoch devolve a data correspondente ao número de série 0.
+func xlsxEpoch(date1904 bool) time.Time {
+	if date1904 {
+		return time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+}
+
+func xlsxSerialToDateTime(serial float64, kind document.DateTimeKind, date1904 bool) document.DateTime {
+	epoch := xlsxEpoch(date1904)
+	// O Excel considera 1900 bissexto: antes de 1º de março de 1900 as
+	// datas ficam um dia adiantadas.
+	if !date1904 && serial < 61 {
+		epoch = epoch.AddDate(0, 0, 1)
+	}
+
+	millis := math.Round(serial * 24 * 60 * 60 * 1000)
+	t := epoch.Add(time.Duration(millis) * time.Millisecond)
+
+	switch kind {
+	case document.LocalDate:
+		return document.DateTime{Kind: kind, Text: t.Format("2006-01-02")}
+	case document.LocalTime:
+		return document.DateTime{Kind: kind, Text: t.Format("15:04:05.999")}
+	}
+	return document.DateTime{Kind: document.LocalDateTime, Text: t.Format("2006-01-02T15:04:05.999")}
+}
+
+// ──────────────────────────────────────────────
+//  Escrita
+// ──────────────────────────────────────────────
+
+// Índices em cellXfs do styles.xml gerado.
+const (
+	xlsxStyleDate     = 1
+	xlsxStyleDateTime = 2
+	xlsxStyleTime     = 3
+)
+
+const xlsxStylesXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="3"><numFmt numFmtId="164" formatCode="yyyy-mm-dd"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="166" formatCode="hh:mm:ss"/></numFmts>` +
+	`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="166" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+const xlsxRootRelsXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+// xlsxOutputSheet é uma planilha a ser escrita.
+type xlsxOutputSheet struct {
+	Name string
+	Rows []interface{}
+	Path string // JSON Pointer dos registros na árvore de entrada
+}
+
+// writeXlsx escreve os registros em uma pasta de trabalho. Um objeto cujos
+// valores são todos arrays de objetos vira uma planilha por chave.
+func writeXlsx(data interface{}, output io.Writer, sheetName string, lossy *lossyLog) error {
+	if sheetName == "" || sheetName == xlsxAllSheets {
+		sheetName = "Sheet1"
+	}
+
+	var sheets []xlsxOutputSheet
+	if obj, ok := data.(*document.Object); ok && isXlsxSheetMap(obj) {
+		used := make(map[string]bool)
+		for i, key := range obj.Keys() {
+			value, _ := obj.Get(key)
+			name := uniqueXlsxSheetName(key, i+1, used)
+			sheets = append(sheets, xlsxOutputSheet{Name: name, Rows: value.([]interface{}), Path: pointerChild("", key)})
+		}
+	} else {
+		rows, err := tabularRows(data)
+		if err != nil {
+			return err
+		}
+		used := make(map[string]bool)
+		sheets = append(sheets, xlsxOutputSheet{Name: uniqueXlsxSheetName(sheetName, 1, used), Rows: rows})
+	}
+
+	archive := zip.NewWriter(output)
+
+	if err := writeZipPart(archive, "[Content_Types].xml", xlsxContentTypes(len(sheets))); err != nil {
+		return err
+	}
+	if err := writeZipPart(archive, "_rels/.rels", xlsxRootRelsXML); err != nil {
+		return err
+	}
+	if err := writeZipPart(archive, "xl/workbook.xml", xlsxWorkbookPart(sheets)); err != nil {
+		return err
+	}
+	if err := writeZipPart(archive, "xl/_rels/workbook.xml.rels", xlsxWorkbookRels(len(sheets))); err != nil {
+		return err
+	}
+	if err := writeZipPart(archive, "xl/styles.xml", xlsxStylesXML); err != nil {
+		return err
+	}
+	for i, sheet := range sheets {
+		part, err := xlsxSheetPart(sheet.Rows, sheet.Path, lossy)
+		if err != nil {
+			return fmt.Errorf("xlsx: sheet %q: %v", sheet.Name, err)
+		}
+		if err := writeZipPart(archive, fmt.Sprintf("xl/worksheets/sheet%d.xml", i+1), part); err != nil {
+			return err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+// tabularRows normaliza a árvore para uma lista de registros, como
+// writeDataAsCSV.
+func tabularRows(data interface{}) ([]interface{}, error) {
+	switch v := data.(type) {
+	case []interface{}:
+		return v, nil
+	case *document.Object:
+		if v.Len() == 0 {
+			return nil, fmt.Errorf("empty object")
+		}
+		return []interface{}{v}, nil
+	}
+	return nil, fmt.Errorf("format not supported")
+}
+
+func isXlsxSheetMap(obj *document.Object) bool {
+	if obj.Len() == 0 {
+		return false
+	}
+	for _, key := range obj.Keys() {
+		value, _ := obj.Get(key)
+		list, ok := value.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, item := range list {
+			if _, isObj := item.(*document.Object); !isObj {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// uniqueXlsxSheetName aplica as regras do Excel para nomes de planilha: até
+// 31 caracteres, sem []:*?/\ e sem repetição (ignorando maiúsculas).
+func uniqueXlsxSheetName(name string, position int, used map[string]bool) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	name = strings.Trim(name, "'")
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", position)
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+
+	candidate := name
+	for n := 2; used[strings.ToLower(candidate)]; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		runes := []rune(name)
+		if len(runes)+len(suffix) > 31 {
+			runes = runes[:31-len(suffix)]
+		}
+		candidate = string(runes) + suffix
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func writeZipPart(archive *zip.Writer, name string, content string) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	if _, err := io.WriteString(writer, content); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return nil
+}
+
+func xlsxContentTypes(sheetCount int) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">`)
+	sb.WriteString(`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>`)
+	sb.WriteString(`<Default Extension="xml" ContentType="application/xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>`)
+	sb.WriteString(`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&sb, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i)
+	}
+	sb.WriteString(`</Types>`)
+	return sb.String()
+}
+
+func xlsxWorkbookPart(sheets []xlsxOutputSheet) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	for i, sheet := range sheets {
+		fmt.Fprintf(&sb, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, xmlEscape(sheet.Name), i+1, i+1)
+	}
+	sb.WriteString(`</sheets></workbook>`)
+	return sb.String()
+}
+
+func xlsxWorkbookRels(sheetCount int) string {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i := 1; i <= sheetCount; i++ {
+		fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i, i)
+	}
+	fmt.Fprintf(&sb, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, sheetCount+1)
+	sb.WriteString(`</Relationships>`)
+	return sb.String()
+}
+
+// xlsxSheetPart monta a planilha com o cabeçalho na primeira linha. Textos
+// são gravados como inline strings, dispensando o sharedStrings.xml. path é
+// o JSON Pointer da lista de registros, usado nos eventos de perda.
+func xlsxSheetPart(rows []interface{}, path string, lossy *lossyLog) (string, error) {
+	var sb strings.Builder
+	sb.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	sb.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	headers := collectCSVHeaders(rows)
+	if len(headers) > 0 {
+		sb.WriteString(`<row r="1">`)
+		for i, header := range headers {
+			writeXlsxCell(&sb, xlsxColumnName(i)+"1", header)
+		}
+		sb.WriteString(`</row>`)
+	}
+
+	rowNumber := 1
+	for index, row := range rows {
+		obj, ok := row.(*document.Object)
+		if !ok {
+			lossy.add(pointerIndex(path, index), "%s is not a record and is skipped", valueKind(row))
+			continue
+		}
+		rowNumber++
+		fmt.Fprintf(&sb, `<row r="%d">`, rowNumber)
+		for i, header := range headers {
+			value, exists := obj.Get(header)
+			if !exists || value == nil {
+				continue
+			}
+			ref := xlsxColumnName(i) + strconv.Itoa(rowNumber)
+			switch v := value.(type) {
+			case *document.Object, []interface{}:
+				lossy.add(pointerChild(pointerIndex(path, index), header), "%s flattened into one cell (use --flatten to keep the structure)", valueKind(v))
+				writeXlsxCell(&sb, ref, flattenValues(v, " | "))
+			case document.DateTime:
+				if _, _, ok := xlsxDateTimeToSerial(v); ok && v.Kind == document.OffsetDateTime {
+					lossy.add(pointerChild(pointerIndex(path, index), header), "time zone offset of %s dropped (xlsx dates have no time zone)", v.Text)
+				}
+				writeXlsxCell(&sb, ref, v)
+			default:
+				writeXlsxCell(&sb, ref, v)
+			}
+		}
+		sb.WriteString(`</row>`)
+	}
+
+	sb.WriteString(`</sheetData></worksheet>`)
+	return sb.String(), nil
+}
+
+func writeXlsxCell(sb *strings.Builder, ref string, value interface{}) {
+	switch v := value.(type) {
+	case bool:
+		flag := 0
+		if v {
+			flag = 1
+		}
+		fmt.Fprintf(sb, `<c r="%s" t="b"><v>%d</v></c>`, ref, flag)
+	case int:
+		fmt.Fprintf(sb, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case int64:
+		fmt.Fprintf(sb, `<c r="%s"><v>%d</v></c>`, ref, v)
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			writeXlsxCell(sb, ref, formatYamlSpecialFloat(v))
+			return
+		}
+		fmt.Fprintf(sb, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'g', -1, 64))
+	case json.Number:
+		// O Excel guarda números como double: o que não cabe exatamente
+		// (inteiros acima de 2^53, decimais longos) vai como texto.
+		if !xlsxExactNumber(v) {
+			writeXlsxCell(sb, ref, string(v))
+			return
+		}
+		fmt.Fprintf(sb, `<c r="%s"><v>%s</v></c>`, ref, v)
+	case document.DateTime:
+		serial, style, ok := xlsxDateTimeToSerial(v)
+		if !ok {
+			writeXlsxCell(sb, ref, v.Text)
+			return
+		}
+		fmt.Fprintf(sb, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(serial, 'f', -1, 64))
+	default:
+		fmt.Fprintf(sb, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, xmlEscape(fmt.Sprintf("%v", v)))
+	}
+}
+
+// xlsxExactNumber indica se o número volta com o mesmo valor depois de
+// guardado como double (0.1 volta; 9007199254740993 não).
+func xlsxExactNumber(n json.Number) bool {
+	f, err := n.Float64()
+	if err != nil {
+		return false
+	}
+	exact, ok := new(big.Rat).SetString(string(n))
+	stored, _ := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return ok && exact.Cmp(stored) == 0
+}
+
+// xlsxDateTimeToSerial converte a data/hora no número de série do Excel.
+// Datas com fuso mantêm o horário local indicado, já que o Excel não guarda
+// fuso.
+func xlsxDateTimeToSerial(value document.DateTime) (float64, int, bool) {
+	layouts := map[document.DateTimeKind]string{
+		document.OffsetDateTime: "2006-01-02T15:04:05.999999999Z07:00",
+		document.LocalDateTime:  "2006-01-02T15:04:05.999999999",
+		document.LocalDate:      "2006-01-02",
+		document.LocalTime:      "15:04:05.999999999",
+	}
+	t, err := time.Parse(layouts[value.Kind], value.Text)
+	if err != nil {
+		return 0, 0, false
+	}
+
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	if value.Kind == document.LocalTime {
+		midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return wall.Sub(midnight).Hours() / 24, xlsxStyleTime, true
+	}
+
+	serial := wall.Sub(xlsxEpoch(false)).Hours() / 24
+	if serial < 61 {
+		serial--
+	}
+	if value.Kind == document.LocalDate {
+		return serial, xlsxStyleDate, true
+	}
+	return serial, xlsxStyleDateTime, true
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
@@ -1,57 +1,35 @@
 package main
 
 import (
-	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
 	"io"
 	"strconv"
 	"strings"
-)
-
-func convertXmlToJson(input io.Reader, output io.Writer) error {
-
-	rootElement, err := parseXmlToElement(input)
-	if err != nil {
-		return err
-	}
 
-	if rootElement == nil {
-		return fmt.Errorf("invalid or empty XML structure")
-	}
-
-	data := processXmlElement(*rootElement)
-
-	result := map[string]interface{}{
-		rootElement.XMLName.Local: data,
-	}
-
-	jsonBytes, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal JSON: %v", err)
-	}
+	"cli-convert/charset"
+	"cli-convert/document"
+)
 
-	if _, err := output.Write(jsonBytes); err != nil {
-		return fmt.Errorf("failed to write JSON output file: %v", err)
-	}
+// xmlFormat lê e escreve XML. Atributos seguem a convenção de
+// opts.AttrPrefix.
+type xmlFormat struct{}
 
-	return nil
+func (xmlFormat) Read(input io.Reader, opts convertOptions) (interface{}, error) {
+	return readXmlDocument(input, opts)
 }
 
-func convertXmlToCsv(input io.Reader, output io.Writer, delimiter rune) error {
-
+// ReadRecords trata os filhos do elemento raiz como registros quando todos
+// têm a mesma tag; caso contrário o elemento raiz inteiro é um registro.
+func (xmlFormat) ReadRecords(input io.Reader, opts convertOptions) (interface{}, error) {
 	rootElement, err := parseXmlToElement(input)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	var records []interface{}
-	if rootElement == nil {
-		return fmt.Errorf("invalid or empty XML structure")
-	}
-	allSameTag := true
-	if len(rootElement.Children) > 1 {
+	allSameTag := len(rootElement.Children) > 1
+	if allSameTag {
 		firstTag := rootElement.Children[0].XMLName.Local
 		for _, child := range rootElement.Children[1:] {
 			if child.XMLName.Local != firstTag {
@@ -59,67 +37,117 @@ func convertXmlToCsv(input io.Reader, output io.Writer, delimiter rune) error {
 				break
 			}
 		}
-	} else {
-		allSameTag = false
 	}
 
-	if allSameTag {
-		for _, rowElem := range rootElement.Children {
-			records = append(records, processXmlElement(rowElem))
+	if !allSameTag {
+		record, err := processXmlElement(*rootElement, "/0", opts)
+		if err != nil {
+			return nil, err
 		}
-	} else {
-		records = append(records, processXmlElement(*rootElement))
+		return []interface{}{record}, nil
 	}
 
-	writer := csv.NewWriter(output)
-	writer.Comma = delimiter
-
-	err = writeDataAsCSV(writer, records)
-	if err != nil {
-		return err
+	var records []interface{}
+	for i, rowElem := range rootElement.Children {
+		record, err := processXmlElement(rowElem, pointerIndex("", i), opts)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
 	}
+	return records, nil
+}
 
-	writer.Flush()
-	if err := writer.Error(); err != nil {
-		return fmt.Errorf("error flushing CSV writer: %v", err)
-	}
+func (xmlFormat) Write(data interface{}, output io.Writer, opts convertOptions) error {
+	return writeXmlDocument(data, output, opts)
+}
 
-	return nil
+func (xmlFormat) WriteStream(next recordStream, output io.Writer, opts convertOptions) error {
+	if _, err := io.WriteString(output, xmlDeclaration(opts.Encoding)); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
+	return streamXmlChildren(output, next, opts.RootName, opts.AttrPrefix, opts.Lossy)
 }
 
-func convertXmlToYaml(input io.Reader, output io.Writer) error {
+func readXmlDocument(input io.Reader, opts convertOptions) (interface{}, error) {
 	rootElement, err := parseXmlToElement(input)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	data := processXmlElement(*rootElement)
-
-	result := map[string]interface{}{
-		rootElement.XMLName.Local: data,
+	rootName := rootElement.XMLName.Local
+	value, err := processXmlElement(*rootElement, pointerChild("", rootName), opts)
+	if err != nil {
+		return nil, err
 	}
+	result := document.NewObject()
+	result.Set(rootName, value)
+	return result, nil
+}
 
-	if err := WriteAsYaml(result, output); err != nil {
-		return err
+func writeXmlDocument(data interface{}, output io.Writer, opts convertOptions) error {
+	xmlRoot := convertToXmlElement(data, opts.RootName, opts.AttrPrefix, "", opts.Lossy)
+
+	xmlData, err := xml.MarshalIndent(xmlRoot, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal XML: %v", err)
 	}
 
+	xmlHeader := []byte(xmlDeclaration(opts.Encoding))
+
+	if _, err := output.Write(append(xmlHeader, xmlData...)); err != nil {
+		return fmt.Errorf("failed to write output file: %v", err)
+	}
 	return nil
 }
 
+// xmlDeclaration monta o cabeçalho XML com o nome da codificação da saída.
+// UTF-16 é declarado sem a ordem dos bytes, que vem do BOM escrito pelo
+// charset.Writer.
+func xmlDeclaration(encoding string) string {
+	name := "UTF-8"
+	switch encoding {
+	case charset.Latin1:
+		name = "ISO-8859-1"
+	case charset.Windows1252:
+		name = "windows-1252"
+	case charset.UTF16LE, charset.UTF16BE:
+		name = "UTF-16"
+	}
+	return `<?xml version="1.0" encoding="` + name + `"?>` + "\n"
+}
+
 type XmlElement struct {
 	XMLName  xml.Name
 	Attrs    []xml.Attr   `xml:",any,attr"`
 	Children []XmlElement `xml:",any"`
 	Value    string       `xml:",chardata"`
+
+	// textParts conta os trechos de texto do elemento; só o último fica
+	// em Value.
+	textParts int
 }
 
-func parseXmlToElement(input io.Reader) (*XmlElement, error) {
+// newXmlDecoder lê XML que decodeInput já converteu para UTF-8: a
+// codificação declarada no cabeçalho (ex.: ISO-8859-1) é aceita sem nova
+// conversão.
+func newXmlDecoder(input io.Reader) *xml.Decoder {
 	decoder := xml.NewDecoder(input)
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+	return decoder
+}
+
+func parseXmlToElement(input io.Reader) (*XmlElement, error) {
+	decoder := newXmlDecoder(input)
 	var stack []*XmlElement
 	var rootElement *XmlElement
 
 	for {
-		token, err := decoder.Token()
+		// RawToken mantém os prefixos como escritos, sem trocá-los pela
+		// URL do namespace; o fechamento das tags é conferido abaixo.
+		token, err := decoder.RawToken()
 		if err != nil {
 			if err == io.EOF {
 				break
@@ -129,15 +157,18 @@ func parseXmlToElement(input io.Reader) (*XmlElement, error) {
 
 		switch t := token.(type) {
 		case xml.StartElement:
-			newElement := XmlElement{XMLName: t.Name}
+			// Como nos atributos, o prefixo fica no nome local ("ns:item")
+			// para virar parte da chave e ser reproduzido na escrita.
+			name := xml.Name{Local: xmlQualifiedName(t.Name)}
+			newElement := XmlElement{XMLName: name, Attrs: copyXmlAttrs(t.Attr)}
 			stack = append(stack, &newElement)
 		case xml.EndElement:
 			if len(stack) == 0 {
 				return nil, fmt.Errorf("unexpected end element %s", t.Name.Local)
 			}
 			current := stack[len(stack)-1]
-			if current.XMLName.Local != t.Name.Local {
-				return nil, fmt.Errorf("mismatched tags: expected %s, got %s", current.XMLName.Local, t.Name.Local)
+			if current.XMLName.Local != xmlQualifiedName(t.Name) {
+				return nil, fmt.Errorf("mismatched tags: expected %s, got %s", current.XMLName.Local, xmlQualifiedName(t.Name))
 			}
 
 			stack = stack[:len(stack)-1]
@@ -152,6 +183,7 @@ func parseXmlToElement(input io.Reader) (*XmlElement, error) {
 			if text != "" && len(stack) > 0 {
 				current := stack[len(stack)-1]
 				current.Value = text
+				current.textParts++
 			}
 		}
 	}
@@ -161,54 +193,158 @@ func parseXmlToElement(input io.Reader) (*XmlElement, error) {
 	return rootElement, nil
 }
 
-func processXmlElement(elem XmlElement) interface{} {
-	if len(elem.Children) == 0 {
-		return getJsonValue(elem.Value)
+// xmlTextKey guarda o texto de um elemento que também possui atributos.
+const xmlTextKey = "#text"
+
+// xmlQualifiedName escreve o nome com o prefixo, como no documento.
+func xmlQualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// copyXmlAttrs copia os atributos de um StartElement, já que o decoder
+// reutiliza a memória do token. Atributos com prefixo (xmlns:x, xml:lang,
+// xsi:type) mantêm o prefixo no nome para serem reproduzidos na volta.
+func copyXmlAttrs(attrs []xml.Attr) []xml.Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+	copied := make([]xml.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		name := attr.Name.Local
+		if attr.Name.Space != "" {
+			name = attr.Name.Space + ":" + name
+		}
+		copied = append(copied, xml.Attr{Name: xml.Name{Local: name}, Value: attr.Value})
+	}
+	return copied
+}
+
+// processXmlElement converte o elemento na árvore genérica. Atributos viram
+// chaves com opts.AttrPrefix (ex.: "@id"); com prefixo vazio são mesclados
+// como campos comuns. O texto e os atributos passam por opts.Types, com o
+// nome do elemento ou do atributo como coluna. path é o JSON Pointer do
+// elemento na árvore, usado nos eventos de perda.
+func processXmlElement(elem XmlElement, path string, opts convertOptions) (interface{}, error) {
+	lossy := opts.Lossy
+	name := elem.XMLName.Local
+	if len(elem.Children) == 0 && len(elem.Attrs) == 0 {
+		if elem.textParts > 1 {
+			lossy.add(path, "text split by comments or CDATA; only the last part is kept")
+		}
+		return xmlScalar(elem.Value, path, opts, name)
 	}
 
 	childrenGrouped := make(map[string][]XmlElement)
 	var orderedKeys []string
+	interleaved := false
 
-	for _, child := range elem.Children {
+	for i, child := range elem.Children {
 		key := child.XMLName.Local
 		if _, exists := childrenGrouped[key]; !exists {
 			orderedKeys = append(orderedKeys, key)
+		} else if elem.Children[i-1].XMLName.Local != key {
+			interleaved = true
 		}
 		childrenGrouped[key] = append(childrenGrouped[key], child)
 	}
-	if len(orderedKeys) == 1 {
+	if interleaved {
+		lossy.add(path, "interleaved child elements regrouped by name; their relative order is lost")
+	}
+	if elem.textParts > 0 && len(elem.Children) > 0 {
+		lossy.add(path, "text mixed with child elements is dropped")
+	}
+
+	if len(orderedKeys) == 1 && len(elem.Attrs) == 0 {
 		childrenList := childrenGrouped[orderedKeys[0]]
 		if len(childrenList) > 1 {
-			var list []interface{}
-			for _, item := range childrenList {
-				list = append(list, processXmlElement(item))
-			}
-			return list
+			return processXmlList(childrenList, path, opts)
+		}
+	}
+	obj := document.NewObject()
+	for _, attr := range elem.Attrs {
+		key := opts.AttrPrefix + attr.Name.Local
+		if _, exists := obj.Get(key); exists {
+			lossy.add(pointerChild(path, key), "attributes with the same local name; only the last is kept")
+		}
+		value, err := xmlScalar(attr.Value, pointerChild(path, key), opts, key, attr.Name.Local)
+		if err != nil {
+			return nil, err
 		}
+		obj.Set(key, value)
 	}
-	obj := make(map[string]interface{})
-	for key, childrenForKey := range childrenGrouped {
+	if len(elem.Children) == 0 && elem.Value != "" {
+		value, err := xmlScalar(elem.Value, pointerChild(path, xmlTextKey), opts, name)
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(xmlTextKey, value)
+	}
+	for _, key := range orderedKeys {
+		childPath := pointerChild(path, key)
+		if _, exists := obj.Get(key); exists {
+			lossy.add(childPath, "attribute overwritten by the child element with the same name")
+		}
+		childrenForKey := childrenGrouped[key]
+		var value interface{}
+		var err error
 		if len(childrenForKey) == 1 {
-			obj[key] = processXmlElement(childrenForKey[0])
+			value, err = processXmlElement(childrenForKey[0], childPath, opts)
 		} else {
-			var list []interface{}
-			for _, item := range childrenForKey {
-				list = append(list, processXmlElement(item))
-			}
-			obj[key] = list
+			value, err = processXmlList(childrenForKey, childPath, opts)
 		}
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(key, value)
 	}
-	return obj
+	return obj, nil
 }
 
-func getJsonValue(s string) interface{} {
-	if f, err := strconv.ParseFloat(s, 64); err == nil {
-		return f
+// processXmlList converte elementos irmãos com a mesma tag em um array.
+func processXmlList(elems []XmlElement, path string, opts convertOptions) (interface{}, error) {
+	list := make([]interface{}, 0, len(elems))
+	for i, item := range elems {
+		value, err := processXmlElement(item, pointerIndex(path, i), opts)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
 	}
+	return list, nil
+}
 
-	if b, err := strconv.ParseBool(s); err == nil {
-		return b
+// xmlScalar converte o texto com opts.Types e registra quando o valor lido
+// não reproduz o texto original (ex.: "007" vira 7 no modo aggressive).
+// columns são os nomes usados nas regras por coluna, do mais específico ao
+// mais genérico.
+func xmlScalar(text string, path string, opts convertOptions, columns ...string) (interface{}, error) {
+	value, err := opts.Types.value(text, columns...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
 	}
-
-	return s
+	var canonical string
+	switch v := value.(type) {
+	case int:
+		canonical = strconv.Itoa(v)
+	case float64:
+		canonical = strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		canonical = string(v)
+	case bool:
+		canonical = strconv.FormatBool(v)
+	case string:
+		if v != text {
+			opts.Lossy.add(path, "quotes removed from text %q", text)
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+	if canonical != text {
+		opts.Lossy.add(path, "text %q read as %s %s", text, valueKind(value), canonical)
+	}
+	return value, nil
 }
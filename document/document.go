@@ -0,0 +1,200 @@
+// Package document define o modelo de dados compartilhado por todos os
+// leitores e escritores de formatos: objetos que preservam a ordem original
+// das chaves, arrays ([]interface{}) e escalares.
+package document
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Object é um mapa de string para valor que mantém a ordem de inserção das
+// chaves.
+type Object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// NewObject cria um objeto vazio.
+func NewObject() *Object {
+	return &Object{values: make(map[string]interface{})}
+}
+
+// Set define o valor de uma chave. Uma chave nova vai para o fim; uma chave
+// existente mantém sua posição.
+func (o *Object) Set(key string, value interface{}) {
+	if _, exists := o.values[key]; !exists {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = value
+}
+
+// Get retorna o valor da chave e se ela existe.
+func (o *Object) Get(key string) (interface{}, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// Delete remove a chave, se existir.
+func (o *Object) Delete(key string) {
+	if _, exists := o.values[key]; !exists {
+		return
+	}
+	delete(o.values, key)
+	for i, k := range o.keys {
+		if k == key {
+			o.keys = append(o.keys[:i], o.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+// Keys retorna as chaves na ordem original. O slice não deve ser alterado.
+func (o *Object) Keys() []string {
+	return o.keys
+}
+
+// Len retorna a quantidade de chaves.
+func (o *Object) Len() int {
+	return len(o.keys)
+}
+
+// MarshalJSON escreve as chaves na ordem original.
+func (o *Object) MarshalJSON() ([]byte, error) {
+	if o == nil {
+		return []byte("null"), nil
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		keyBytes, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		valueBytes, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(keyBytes)
+		buf.WriteByte(':')
+		buf.Write(valueBytes)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// NewDecoder cria um decoder JSON que mantém os números como json.Number,
+// com o texto original: inteiros de 64 bits e decimais exatos passam pela
+// conversão sem virar float64.
+func NewDecoder(r io.Reader) *json.Decoder {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder
+}
+
+// IsInteger indica se o número não tem parte fracionária ("10", "1.0",
+// "1e3"). O valor exato é comparado, sem passar por float64.
+func IsInteger(n json.Number) bool {
+	value, ok := new(big.Rat).SetString(string(n))
+	return ok && value.IsInt()
+}
+
+// Decode lê o próximo valor JSON do decoder, usando Object para objetos.
+// Crie o decoder com NewDecoder para preservar o texto dos números.
+func Decode(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return token, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := NewObject()
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := keyToken.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected object key, got %v", keyToken)
+			}
+			value, err := Decode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(key, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+
+	case '[':
+		list := []interface{}{}
+		for decoder.More() {
+			value, err := Decode(decoder)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+	return nil, fmt.Errorf("unexpected delimiter %v", delim)
+}
+
+// Unmarshal decodifica um único valor JSON preservando a ordem das chaves
+// e o texto dos números.
+func Unmarshal(data []byte) (interface{}, error) {
+	decoder := NewDecoder(bytes.NewReader(data))
+	value, err := Decode(decoder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after top-level value")
+	}
+	return value, nil
+}
+
+// DateTimeKind distingue as variantes de data/hora do TOML.
+type DateTimeKind int
+
+const (
+	OffsetDateTime DateTimeKind = iota
+	LocalDateTime
+	LocalDate
+	LocalTime
+)
+
+// DateTime é uma data/hora nativa do formato de origem. Text guarda a forma
+// RFC 3339 (ou apenas a parte de data/hora, nas variantes locais).
+type DateTime struct {
+	Kind DateTimeKind
+	Text string
+}
+
+func (d DateTime) String() string {
+	return d.Text
+}
+
+// MarshalJSON escreve a data/hora como string JSON.
+func (d DateTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Text)
+}
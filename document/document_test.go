@@ -0,0 +1,67 @@
+package document
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestUnmarshalPreservesKeyOrder(t *testing.T) {
+	input := `{"zeta": 1, "alpha": {"y": true, "b": null}, "mid": [{"k2": "x", "k1": "y"}], "zeta": 2}`
+
+	value, err := Unmarshal([]byte(input))
+	if err != nil {
+		t.Fatalf("Error decoding JSON: %v", err)
+	}
+
+	output, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %v", err)
+	}
+
+	expected := `{"zeta":2,"alpha":{"y":true,"b":null},"mid":[{"k2":"x","k1":"y"}]}`
+	if string(output) != expected {
+		t.Errorf("Unexpected JSON output:\nExpected:\n%s\nGot:\n%s", expected, output)
+	}
+}
+
+func TestUnmarshalKeepsNumberText(t *testing.T) {
+	input := `[9007199254740993, 0.10, 1e3, -0]`
+
+	value, err := Unmarshal([]byte(input))
+	if err != nil {
+		t.Fatalf("Error decoding JSON: %v", err)
+	}
+	output, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("Error encoding JSON: %v", err)
+	}
+	if string(output) != `[9007199254740993,0.10,1e3,-0]` {
+		t.Errorf("Unexpected JSON output: %s", output)
+	}
+
+	for text, integer := range map[string]bool{"10": true, "1.0": true, "1e3": true, "1.5": false, "1e-3": false} {
+		if IsInteger(json.Number(text)) != integer {
+			t.Errorf("IsInteger(%s): expected %v", text, integer)
+		}
+	}
+}
+
+func TestUnmarshalRejectsTrailingData(t *testing.T) {
+	if _, err := Unmarshal([]byte(`{"a": 1} {"b": 2}`)); err == nil {
+		t.Error("Expected error for trailing data")
+	}
+}
+
+func TestObjectDelete(t *testing.T) {
+	obj := NewObject()
+	obj.Set("a", 1)
+	obj.Set("b", 2)
+	obj.Set("c", 3)
+	obj.Delete("b")
+	obj.Set("b", 4)
+
+	keys := obj.Keys()
+	if len(keys) != 3 || keys[0] != "a" || keys[1] != "c" || keys[2] != "b" {
+		t.Errorf("Unexpected key order: %v", keys)
+	}
+}
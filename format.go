@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Reader lê a entrada inteira para a árvore genérica (document.Object,
+// []interface{} e escalares).
+type Reader interface {
+	Read(input io.Reader, opts convertOptions) (interface{}, error)
+}
+
+// Writer escreve a árvore genérica no formato de destino.
+type Writer interface {
+	Write(data interface{}, output io.Writer, opts convertOptions) error
+}
+
+// recordStream entrega um registro por chamada; o segundo valor é false
+// quando não há mais registros.
+type recordStream func() (interface{}, bool, error)
+
+// StreamReader é implementado por leitores capazes de entregar uma sequência
+// de registros sem carregar a entrada inteira. Quando a entrada não é uma
+// sequência, ReadStream devolve stream nil e a árvore completa em data.
+type StreamReader interface {
+	Reader
+	ReadStream(input io.Reader, opts convertOptions) (stream recordStream, data interface{}, err error)
+}
+
+// StreamWriter escreve registros à medida que chegam.
+type StreamWriter interface {
+	Writer
+	WriteStream(next recordStream, output io.Writer, opts convertOptions) error
+}
+
+// RecordReader é implementado por leitores cuja árvore não coincide com a
+// lista de registros esperada por formatos tabulares (ex.: no XML os
+// registros são os filhos do elemento raiz).
+type RecordReader interface {
+	ReadRecords(input io.Reader, opts convertOptions) (interface{}, error)
+}
+
+// Format descreve um formato registrado. Reader ou Writer podem ser nil
+// quando o formato só é suportado em uma direção.
+type Format struct {
+	Name        string
+	Aliases     []string
+	Description string
+	Extensions  []string
+	// Tabular indica que o formato guarda uma lista de registros planos.
+	Tabular bool
+	Reader  Reader
+	Writer  Writer
+}
+
+// formats é o registro de formatos, na ordem exibida na ajuda.
+var formats = []*Format{
+	{Name: "json", Description: "JSON", Extensions: []string{".json"}, Reader: jsonFormat{}, Writer: jsonFormat{}},
+	{Name: "ndjson", Aliases: []string{"jsonl"}, Description: "JSON Lines, um registro por linha", Extensions: []string{".ndjson", ".jsonl"}, Reader: ndjsonFormat{}, Writer: ndjsonFormat{}},
+	{Name: "csv", Description: "CSV com delimitador configurável", Extensions: []string{".csv"}, Tabular: true, Reader: csvFormat{}, Writer: csvFormat{}},
+	{Name: "tsv", Aliases: []string{"tab"}, Description: "valores separados por tab", Extensions: []string{".tsv", ".tab"}, Tabular: true, Reader: tsvFormat{}, Writer: tsvFormat{}},
+	{Name: "fixed", Aliases: []string{"fwf"}, Description: "texto de largura fixa, com as colunas de --fixed-spec", Extensions: []string{".fwf"}, Tabular: true, Reader: fixedFormat{}, Writer: fixedFormat{}},
+	{Name: "xml", Description: "XML", Extensions: []string{".xml"}, Reader: xmlFormat{}, Writer: xmlFormat{}},
+	{Name: "yaml", Description: "YAML 1.2", Extensions: []string{".yaml", ".yml"}, Reader: yamlFormat{}, Writer: yamlFormat{}},
+	{Name: "toml", Description: "TOML 1.0", Extensions: []string{".toml"}, Reader: tomlFormat{}, Writer: tomlFormat{}},
+	{Name: "xlsx", Description: "planilha Excel (primeira linha como cabeçalho)", Extensions: []string{".xlsx"}, Tabular: true, Reader: xlsxFormat{}, Writer: xlsxFormat{}},
+}
+
+// lookupFormat procura um formato pelo nome ou por um de seus apelidos.
+func lookupFormat(name string) (*Format, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, format := range formats {
+		if format.Name == name {
+			return format, true
+		}
+		for _, alias := range format.Aliases {
+			if alias == name {
+				return format, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// formatForPath procura um formato pela extensão do arquivo.
+func formatForPath(path string) (*Format, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return nil, false
+	}
+	for _, format := range formats {
+		for _, candidate := range format.Extensions {
+			if candidate == ext {
+				return format, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// readableFormatNames lista os formatos aceitos em --from.
+func readableFormatNames() []string {
+	var names []string
+	for _, format := range formats {
+		if format.Reader != nil {
+			names = append(names, format.Name)
+		}
+	}
+	return names
+}
+
+// writableFormatNames lista os formatos aceitos em --to.
+func writableFormatNames() []string {
+	var names []string
+	for _, format := range formats {
+		if format.Writer != nil {
+			names = append(names, format.Name)
+		}
+	}
+	return names
+}
+
+func sourceFormat(name string) (*Format, error) {
+	format, ok := lookupFormat(name)
+	if !ok || format.Reader == nil {
+		return nil, fmt.Errorf("unsupported input format: %s (supported: %s)", name, strings.Join(readableFormatNames(), ", "))
+	}
+	return format, nil
+}
+
+func targetFormat(name string) (*Format, error) {
+	format, ok := lookupFormat(name)
+	if !ok || format.Writer == nil {
+		return nil, fmt.Errorf("unsupported output format: %s (supported: %s)", name, strings.Join(writableFormatNames(), ", "))
+	}
+	return format, nil
+}
+
+// readFileTree lê um arquivo inteiro para a árvore genérica. Sem
+// formatName, o formato vem da extensão ou, na falta dela, do conteúdo. A
+// codificação é deduzida do início do arquivo.
+func readFileTree(path string, formatName string, opts convertOptions) (interface{}, *Format, error) {
+	var format *Format
+	if formatName != "" {
+		source, err := sourceFormat(formatName)
+		if err != nil {
+			return nil, nil, err
+		}
+		format = source
+	} else if byExtension, ok := formatForPath(path); ok && byExtension.Reader != nil {
+		format = byExtension
+	} else {
+		detected, err := detectFormat(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		format, _ = lookupFormat(detected)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, format, fmt.Errorf("failed to open input file: %v", err)
+	}
+	defer file.Close()
+
+	input, _, err := decodeInput(bufio.NewReaderSize(file, detectPeekSize), encodingAuto)
+	if err != nil {
+		return nil, format, err
+	}
+	data, err := format.Reader.Read(input, opts)
+	return data, format, err
+}
+
+// convertBetween lê a entrada com o leitor de source e escreve com o
+// escritor de target. Quando os dois lados suportam streaming, os registros
+// passam um a um sem montar a árvore inteira. --flatten e --unflatten são
+// aplicados a cada registro entre a leitura e a escrita.
+func convertBetween(source, target *Format, input io.Reader, output io.Writer, opts convertOptions) error {
+	if streamReader, ok := source.Reader.(StreamReader); ok {
+		if streamWriter, ok := target.Writer.(StreamWriter); ok {
+			stream, data, err := streamReader.ReadStream(input, opts)
+			if err != nil {
+				return err
+			}
+			if stream != nil {
+				return streamWriter.WriteStream(reshapeStream(stream, opts), output, opts)
+			}
+			if data, err = reshapeData(data, opts); err != nil {
+				return err
+			}
+			return target.Writer.Write(data, output, opts)
+		}
+	}
+
+	var data interface{}
+	var err error
+	if recordReader, ok := source.Reader.(RecordReader); ok && target.Tabular {
+		data, err = recordReader.ReadRecords(input, opts)
+	} else {
+		data, err = source.Reader.Read(input, opts)
+	}
+	if err != nil {
+		return err
+	}
+	if data, err = reshapeData(data, opts); err != nil {
+		return err
+	}
+	return target.Writer.Write(data, output, opts)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// formatSamples tem uma entrada mínima para cada formato com leitor.
+var formatSamples = map[string]string{
+	"json":   `[{"id": 1, "name": "Alice"}, {"id": 2, "name": "Bob"}]`,
+	"ndjson": "{\"id\": 1, \"name\": \"Alice\"}\n{\"id\": 2, \"name\": \"Bob\"}\n",
+	"csv":    "id,name\n1,Alice\n2,Bob\n",
+	"tsv":    "id\tname\n1\tAlice\n2\tBob\n",
+	"fixed":  "    1Alice\n    2Bob\n",
+	"xml":    `<users><user><id>1</id><name>Alice</name></user><user><id>2</id><name>Bob</name></user></users>`,
+	"yaml":   "- id: 1\n  name: Alice\n- id: 2\n  name: Bob\n",
+	"toml":   "[[users]]\nid = 1\nname = \"Alice\"\n\n[[users]]\nid = 2\nname = \"Bob\"\n",
+}
+
+// sampleFixedSpec descreve as colunas de formatSamples["fixed"].
+var sampleFixedSpec = &fixedSpec{Columns: []fixedColumn{
+	{Name: "id", Start: 0, Width: 5, Type: columnInteger, Align: fixedAlignRight},
+	{Name: "name", Start: 5, Width: 10, Type: columnString, Align: fixedAlignLeft},
+}}
+
+// tableOnlyFormats só aceitam um objeto no nível superior; converter um
+// array para eles deve falhar com um erro claro.
+var tableOnlyFormats = map[string]bool{"toml": true}
+
+func TestRegistryConvertsEveryPair(t *testing.T) {
+	opts := convertOptions{Delimiter: ',', RootName: "root", AttrPrefix: "@", Fixed: sampleFixedSpec}
+
+	for _, source := range formats {
+		if source.Reader == nil {
+			continue
+		}
+		sample, ok := formatSamples[source.Name]
+		if !ok {
+			t.Errorf("No sample input for format %s", source.Name)
+			continue
+		}
+		for _, target := range formats {
+			if target.Writer == nil || target == source {
+				continue
+			}
+			writer := new(bytes.Buffer)
+			err := dispatchConversion(source.Name, target.Name, strings.NewReader(sample), writer, opts)
+			if err != nil && tableOnlyFormats[target.Name] && strings.Contains(err.Error(), "top-level value must be a table") {
+				continue
+			}
+			if err != nil {
+				t.Errorf("%s → %s: %v", source.Name, target.Name, err)
+				continue
+			}
+			if writer.Len() == 0 {
+				t.Errorf("%s → %s: empty output", source.Name, target.Name)
+			}
+		}
+	}
+}
+
+func TestConvertXmlToCsv_RecordsFromRootChildren(t *testing.T) {
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("xml", "csv", strings.NewReader(formatSamples["xml"]), writer, convertOptions{Delimiter: ',', AttrPrefix: "@"}); err != nil {
+		t.Fatalf("Error converting XML to CSV: %v", err)
+	}
+
+	expectedCsvOutput := "id,name\n1,Alice\n2,Bob\n"
+	if writer.String() != expectedCsvOutput {
+		t.Errorf("Unexpected CSV output:\nExpected:\n%s\nGot:\n%s", expectedCsvOutput, writer.String())
+	}
+}
+
+func TestFormatLookup(t *testing.T) {
+	if format, ok := formatForPath("config/app.YML"); !ok || format.Name != "yaml" {
+		t.Errorf("Expected .YML to resolve to yaml, got %v", format)
+	}
+	if _, ok := formatForPath("notes.txt"); ok {
+		t.Error("Expected .txt to be unknown")
+	}
+	if err := dispatchConversion("json", "parquet", strings.NewReader("{}"), new(bytes.Buffer), convertOptions{}); err == nil {
+		t.Error("Expected error for unregistered output format")
+	}
+}
+
+func TestDetectStreamFormat(t *testing.T) {
+	largeArray := "[" + strings.Repeat(`{"id": 1, "name": "Alice"},`, 4000) + `{"id": 2}]`
+	largeNdjson := strings.Repeat("{\"id\": 1, \"name\": \"Alice\"}\n", 4000)
+	largeCsv := "id,name\n" + strings.Repeat("1,Alice\n", 10000)
+
+	tests := []struct {
+		name     string
+		input    string
+		path     string
+		expected string
+	}{
+		{"small json", formatSamples["json"], "-", "json"},
+		{"json larger than the peek", largeArray, "-", "json"},
+		{"ndjson larger than the peek", largeNdjson, "-", "ndjson"},
+		{"csv larger than the peek", largeCsv, "-", "csv"},
+		{"semicolon csv", "nome;preço\nAna;1,50\nBia;2,00\n", "-", "csv"},
+		{"tab separated", "id\tname\n1\tAlice\n", "-", "tsv"},
+		{"pipe separated", "id|name\n1|Alice\n", "-", "csv"},
+		{"xlsx", formatSamples["xlsx"], "-", "xlsx"},
+		{"extension fallback", "plain text", "notes.toml", "toml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReaderSize(strings.NewReader(tt.input), detectPeekSize)
+			got, err := detectStreamFormat(reader, tt.path)
+			if err != nil {
+				t.Fatalf("Error detecting format: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+
+			// A detecção não pode consumir a entrada.
+			rest, _ := io.ReadAll(reader)
+			if string(rest) != tt.input {
+				t.Error("Detection consumed part of the input")
+			}
+		})
+	}
+
+	if _, err := detectStreamFormat(bufio.NewReaderSize(strings.NewReader("plain text"), detectPeekSize), "-"); err == nil {
+		t.Error("Expected error for undetectable stdin without extension fallback")
+	}
+}
+
+func TestSniffStreamCSV(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		skipRows  int
+		delimiter rune
+		header    bool
+	}{
+		{"comma", "id,name\n1,Alice\n2,Bob\n", 0, ',', true},
+		{"semicolon with decimal commas", "nome;preço\nAna;1,50\nBia;2,00\n", 0, ';', true},
+		{"quoted delimiters", "id;note\n1;\"a,b,c\"\n2;\"d,e\"\n", 0, ';', true},
+		{"tab", "id\tname\n1\tAlice\n", 0, '\t', true},
+		{"pipe without header", "1|Alice|10\n2|Bob|20\n", 0, '|', false},
+		{"numbers in the first row", "10,20\n30,40\n", 0, ',', false},
+		{"bom", "\ufeffa;b\n1;2\n", 0, ';', true},
+		{"preamble skipped", "Relatório, gerado em 2024\nid;valor\n1;2\n", 1, ';', true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := bufio.NewReaderSize(strings.NewReader(tt.input), detectPeekSize)
+			dialect, ok := sniffStreamCSV(reader, tt.skipRows)
+			if !ok {
+				t.Fatal("Expected a dialect")
+			}
+			if dialect.Delimiter != tt.delimiter {
+				t.Errorf("Expected delimiter %q, got %q", tt.delimiter, dialect.Delimiter)
+			}
+			if dialect.HasHeader != tt.header {
+				t.Errorf("Expected header %v, got %v", tt.header, dialect.HasHeader)
+			}
+		})
+	}
+
+	if _, ok := sniffStreamCSV(bufio.NewReaderSize(strings.NewReader("just one column\nof text\n"), detectPeekSize), 0); ok {
+		t.Error("Expected no dialect for single-column text")
+	}
+}
+
+func TestEnsureOutputExtension(t *testing.T) {
+	tests := []struct {
+		filename string
+		format   string
+		expected string
+	}{
+		{"data.jsonl", "ndjson", "data.jsonl"},
+		{"data.json", "ndjson", "data.ndjson"},
+		{"x.tab", "tsv", "x.tab"},
+		{"config.YML", "yaml", "config.YML"},
+		{"out.txt", "csv", "out.csv"},
+		{"out", "toml", "out.toml"},
+	}
+
+	for _, tt := range tests {
+		format, _ := lookupFormat(tt.format)
+		if got := ensureOutputExtension(tt.filename, format.Extensions); got != tt.expected {
+			t.Errorf("ensureOutputExtension(%q, %s) = %q; expected %q", tt.filename, tt.format, got, tt.expected)
+		}
+	}
+}
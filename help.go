@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"strings"
 )
 
 // Códigos ANSI para cores e estilos
@@ -16,20 +17,24 @@ const (
 )
 
 func setGlobalUsage() {
-	fmt.Printf("%scli-convert%s — Conversor universal de arquivos JSON, CSV, XML e YAML.\n", ColorBold, ColorReset)
+	fmt.Printf("%scli-convert%s — Conversor universal de arquivos (%s).\n", ColorBold, ColorReset, strings.Join(readableFormatNames(), ", "))
 	fmt.Println()
 
 	fmt.Printf("%sCOMANDOS DISPONÍVEIS%s\n", ColorCyan, ColorReset)
 	fmt.Printf("  %sconvert%s    Converte um arquivo entre formatos suportados\n", ColorYellow, ColorReset)
 	fmt.Printf("  %sdetect%s     Auto-detecta o formato de um arquivo\n", ColorYellow, ColorReset)
+	fmt.Printf("  %svalidate%s   Valida a sintaxe (e opcionalmente um JSON Schema) de um arquivo\n", ColorYellow, ColorReset)
 	fmt.Printf("  %sschema%s     Gera um JSON Schema a partir de um arquivo\n", ColorYellow, ColorReset)
+	fmt.Printf("  %scodegen%s    Gera structs Go ou tipos TypeScript a partir de um arquivo\n", ColorYellow, ColorReset)
 	fmt.Printf("  %sask%s        Pergunta sobre os dados em linguagem natural (IA)\n", ColorYellow, ColorReset)
 	fmt.Println()
 
 	fmt.Printf("%sEXEMPLOS%s\n", ColorCyan, ColorReset)
 	fmt.Printf("  %scli-convert convert --from json --to csv --input data.json --output data.csv%s\n", ColorGray, ColorReset)
 	fmt.Printf("  %scli-convert detect --input arquivo.json%s\n", ColorGray, ColorReset)
-	fmt.Printf("  %scli-convert schema --input dados.csv%s\n", ColorGray, ColorReset)
+	fmt.Printf("  %scli-convert validate --input dados.csv%s\n", ColorGray, ColorReset)
+	fmt.Printf("  %scli-convert schema --input dados.csv --output dados.schema.json%s\n", ColorGray, ColorReset)
+	fmt.Printf("  %scli-convert codegen --lang go --input payload.json%s\n", ColorGray, ColorReset)
 	fmt.Printf("  %scli-convert ask --input vendas.csv --question \"Qual o total de vendas?\"%s\n", ColorGray, ColorReset)
 	fmt.Println()
 
@@ -48,34 +53,107 @@ func setConvertUsage(flagSet *flag.FlagSet) {
 		fmt.Println()
 
 		fmt.Printf("%sDESCRIÇÃO%s\n", ColorCyan, ColorReset)
-		fmt.Println("  Converte arquivos entre JSON, CSV, XML e YAML, com opções de personalização")
+		fmt.Println("  Converte arquivos entre quaisquer formatos suportados, com opções de personalização")
 		fmt.Println("  para delimitadores CSV e elementos raiz XML.")
 		fmt.Println("  Se --from não for especificado, o formato é detectado automaticamente.")
 		fmt.Println()
 
 		fmt.Printf("%sFORMATOS SUPORTADOS%s\n", ColorCyan, ColorReset)
-		fmt.Printf("  %s• json%s\n", ColorBlue, ColorReset)
-		fmt.Printf("  %s• csv%s\n", ColorBlue, ColorReset)
-		fmt.Printf("  %s• xml%s\n", ColorBlue, ColorReset)
-		fmt.Printf("  %s• yaml%s\n", ColorBlue, ColorReset)
+		for _, format := range formats {
+			fmt.Printf("  %s• %-8s%s %s (%s)%s%s\n", ColorBlue, format.Name, ColorReset, format.Description, strings.Join(format.Extensions, ", "), formatAliases(format), formatDirection(format))
+		}
 		fmt.Println()
 
 		fmt.Printf("%sFLAGS OBRIGATÓRIOS%s\n", ColorCyan, ColorReset)
-		fmt.Printf("  %s--from%s <string>       Formato de origem (json, csv, xml, yaml)\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--from%s <string>       Formato de origem (%s)\n", ColorYellow, ColorReset, strings.Join(readableFormatNames(), ", "))
 		fmt.Println("                       Opcional: detectado automaticamente se omitido")
 		fmt.Println()
-		fmt.Printf("  %s--to%s <string>         Formato de destino (json, csv, xml, yaml)\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--to%s <string>         Formato de destino (%s)\n", ColorYellow, ColorReset, strings.Join(writableFormatNames(), ", "))
 		fmt.Printf("  %s--input%s <string>      Caminho do arquivo de entrada\n", ColorYellow, ColorReset)
+		fmt.Println("                       Opcional: '-' ou omitido lê do stdin")
 		fmt.Printf("  %s--output%s <string>     Caminho do arquivo de saída\n", ColorYellow, ColorReset)
+		fmt.Println("                       Opcional: '-' ou omitido escreve no stdout")
+		fmt.Println("                       Mensagens de status vão sempre para o stderr")
 		fmt.Println()
 
 		fmt.Printf("%sFLAGS OPCIONAIS%s\n", ColorCyan, ColorReset)
 		fmt.Printf("  %s--delimiter%s <char>    Delimitador CSV\n", ColorYellow, ColorReset)
-		fmt.Println("                       Padrão: ','")
+		fmt.Println("                       Padrão: deduzido da entrada CSV (',', ';', tab ou '|'); ',' na saída")
+		fmt.Println("                       Sem --delimiter, também se deduz se a primeira linha é cabeçalho")
+		fmt.Println()
+		fmt.Printf("  %s--no-header%s           CSV sem linha de cabeçalho: colunas col1, col2, ...\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--header%s <lista>      Nomes das colunas de um CSV sem cabeçalho, ex.: 'id,nome,email'\n", ColorYellow, ColorReset)
+		fmt.Println("                       Para trocar um cabeçalho existente, use com --skip-rows 1")
+		fmt.Printf("  %s--skip-rows%s <n>       Descarta as n primeiras linhas do CSV (preâmbulo de relatórios)\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--comment%s <char>      Ignora linhas do CSV que começam com o caractere (ex.: '#')\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--ragged-rows%s <modo>  Linhas CSV com mais ou menos campos que o cabeçalho\n", ColorYellow, ColorReset)
+		fmt.Println("                       error: interrompe a conversão (padrão)")
+		fmt.Println("                       pad: completa com null e descarta os campos excedentes")
+		fmt.Println("                       extra: completa com null e guarda os excedentes em \"_extra\"")
+		fmt.Println("                       Nomes de coluna repetidos viram nome_2, nome_3, ...")
+		fmt.Println()
+		fmt.Printf("  %s--quote%s <estilo>      Aspas na saída CSV\n", ColorYellow, ColorReset)
+		fmt.Println("                       minimal: só quando necessário (padrão)")
+		fmt.Println("                       all: em todos os campos")
+		fmt.Println("                       nonnumeric: em tudo que não é número ou booleano")
+		fmt.Println("                       none: nunca; delimitadores e quebras de linha escapados com \\")
+		fmt.Printf("  %s--escape%s <estilo>     Aspas dentro de campos: double (\"\", padrão) ou backslash (\\\")\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--line-ending%s <fim>   Fim de linha da saída CSV e fixed: lf (padrão) ou crlf\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--bom%s                 Grava o BOM UTF-8 no início da saída CSV (acentos no Excel)\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--null-string%s <texto> Texto das células null na saída CSV (ex.: '\\N')\n", ColorYellow, ColorReset)
 		fmt.Println()
 		fmt.Printf("  %s--root%s <string>       Nome do elemento raiz para XML\n", ColorYellow, ColorReset)
 		fmt.Println("                       Padrão: 'root'")
 		fmt.Println()
+		fmt.Printf("  %s--attr-prefix%s <string> Prefixo das chaves que representam atributos XML\n", ColorYellow, ColorReset)
+		fmt.Println("                       Padrão: '@' (ex.: <item id=\"42\"> vira \"@id\": 42)")
+		fmt.Println()
+		fmt.Printf("  %s--merge-attrs%s         Mescla atributos XML como campos comuns, sem prefixo\n", ColorYellow, ColorReset)
+		fmt.Println()
+		fmt.Printf("  %s--documents%s <modo>    Entrada YAML com vários documentos (---)\n", ColorYellow, ColorReset)
+		fmt.Println("                       array: um array com os documentos (padrão)")
+		fmt.Println("                       ndjson: um documento JSON por linha (requer --to json)")
+		fmt.Println("                       split: um arquivo por documento (saida-1.json, saida-2.json, ...)")
+		fmt.Println()
+		fmt.Printf("  %s--yaml-stream%s         Escreve um array de nível superior como documentos YAML separados por ---\n", ColorYellow, ColorReset)
+		fmt.Println()
+		fmt.Printf("  %s--on-bad-line%s <modo>  Linhas inválidas na entrada NDJSON\n", ColorYellow, ColorReset)
+		fmt.Println("                       abort: interrompe a conversão (padrão)")
+		fmt.Println("                       skip: ignora a linha")
+		fmt.Println("                       collect: ignora a linha e lista as rejeitadas no final")
+		fmt.Println()
+		fmt.Printf("  %s--bad-lines%s <string>  Arquivo que recebe as linhas rejeitadas no modo collect\n", ColorYellow, ColorReset)
+		fmt.Println()
+		fmt.Printf("  %s--sheet%s <string>      Planilha xlsx por nome ou posição (1, 2, ...)\n", ColorYellow, ColorReset)
+		fmt.Println("                       Padrão: a primeira; '*' lê todas como {planilha: registros}")
+		fmt.Println("                       Na escrita, nome da planilha (padrão: 'Sheet1')")
+		fmt.Println()
+		fmt.Printf("  %s--flatten%s             Achata objetos e arrays em colunas pontilhadas (address.city, tags[0])\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--unflatten%s           Reconstrói objetos e arrays a partir de colunas pontilhadas\n", ColorYellow, ColorReset)
+		fmt.Println()
+		fmt.Printf("  %s--strict%s              Falha (código 1) se a conversão perder dados, com o relatório em stderr\n", ColorYellow, ColorReset)
+		fmt.Println("                       Sem --strict, as perdas são apenas avisadas em stderr")
+		fmt.Println()
+		fmt.Printf("  %s--infer%s <modo>        Inferência de tipos ao ler CSV, XML e YAML\n", ColorYellow, ColorReset)
+		fmt.Println("                       none: tudo vira texto")
+		fmt.Println("                       safe (padrão): só o que volta idêntico (\"007\" e \"1e3\" ficam texto)")
+		fmt.Println("                       aggressive: tudo o que parece número ou booleano")
+		fmt.Println()
+		fmt.Printf("  %s--column-types%s <lista> Tipos por coluna, ex.: 'zip=string,age=integer'\n", ColorYellow, ColorReset)
+		fmt.Println("                       Tipos: string, integer, number, boolean, auto")
+		fmt.Println()
+		fmt.Printf("  %s--types-from%s <arquivo> JSON Schema com os tipos das colunas (ex.: gerado por schema)\n", ColorYellow, ColorReset)
+		fmt.Println()
+		fmt.Printf("  %s--fixed-spec%s <arquivo> Colunas do formato fixed: name, start, width, type, align\n", ColorYellow, ColorReset)
+		fmt.Println("                       Em CSV, JSON, YAML ou TOML; start conta a partir de 1")
+		fmt.Println()
+		fmt.Printf("  %s--input-encoding%s <c>  Codificação da entrada: auto (padrão), utf-8, utf-16le, utf-16be,\n", ColorYellow, ColorReset)
+		fmt.Println("                       latin1 ou windows-1252. auto usa o BOM ou deduz pelos bytes")
+		fmt.Println()
+		fmt.Printf("  %s--output-encoding%s <c> Codificação da saída: utf-8 (padrão), utf-16le, utf-16be, latin1\n", ColorYellow, ColorReset)
+		fmt.Println("                       ou windows-1252. UTF-16 sai com BOM; caracteres sem representação")
+		fmt.Println("                       viram '?' e contam como perda")
+		fmt.Println()
 		fmt.Printf("  %s-h%s, %s--help%s            Mostra esta mensagem de ajuda\n", ColorYellow, ColorReset, ColorYellow, ColorReset)
 		fmt.Println()
 
@@ -89,5 +167,72 @@ func setConvertUsage(flagSet *flag.FlagSet) {
 		fmt.Printf("  %s# Auto-detectar formato e converter para JSON%s\n", ColorGray, ColorReset)
 		fmt.Println("  cli-convert convert --to json --input dados.csv --output dados.json")
 		fmt.Println()
+		fmt.Printf("  %s# JSON aninhado para CSV e de volta, sem perder a estrutura%s\n", ColorGray, ColorReset)
+		fmt.Println("  cli-convert convert --to csv --flatten --input pedidos.json --output pedidos.csv")
+		fmt.Println("  cli-convert convert --to json --unflatten --input pedidos.csv --output pedidos.json")
+		fmt.Println()
+		fmt.Printf("  %s# Logs NDJSON para CSV, guardando as linhas inválidas%s\n", ColorGray, ColorReset)
+		fmt.Println("  cli-convert convert --from ndjson --to csv --input app.log.jsonl --output app.csv --on-bad-line collect --bad-lines rejeitadas.jsonl")
+		fmt.Println()
+		fmt.Printf("  %s# Um arquivo JSON por documento de um manifesto Kubernetes%s\n", ColorGray, ColorReset)
+		fmt.Println("  cli-convert convert --from yaml --to json --documents split --input k8s.yaml --output k8s.json")
+		fmt.Println()
+		fmt.Printf("  %s# Em um pipeline, lendo do stdin e escrevendo no stdout%s\n", ColorGray, ColorReset)
+		fmt.Println("  curl -s https://exemplo.com/deploy.json | cli-convert convert --to yaml | kubectl apply -f -")
+		fmt.Println()
+	}
+}
+
+func setValidateUsage(flagSet *flag.FlagSet) {
+	flagSet.Usage = func() {
+		fmt.Printf("%scli-convert validate%s — Valida um arquivo e lista todos os problemas encontrados.\n", ColorBold, ColorReset)
+		fmt.Println()
+
+		fmt.Printf("%sUSO%s\n", ColorCyan, ColorReset)
+		fmt.Println("  cli-convert validate --input <arquivo> [flags]")
+		fmt.Println()
+
+		fmt.Printf("%sFLAGS%s\n", ColorCyan, ColorReset)
+		fmt.Printf("  %s--input%s <string>      Arquivo a validar (obrigatório)\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--format%s <string>     Formato do arquivo (%s)\n", ColorYellow, ColorReset, strings.Join(readableFormatNames(), ", "))
+		fmt.Println("                       Padrão: pela extensão ou detectado pelo conteúdo")
+		fmt.Printf("  %s--delimiter%s <char>    Delimitador CSV\n", ColorYellow, ColorReset)
+		fmt.Println("                       Padrão: deduzido do conteúdo (',', ';', tab ou '|')")
+		fmt.Printf("  %s--report%s <modo>       text (padrão) ou json, para uso em scripts\n", ColorYellow, ColorReset)
+		fmt.Printf("  %s--schema%s <string>     JSON Schema (draft 2020-12) que os dados devem seguir\n", ColorYellow, ColorReset)
+		fmt.Println("                       Aceita JSON, YAML ou TOML; erros indicam o JSON Pointer do valor")
+		fmt.Printf("  %s-h%s, %s--help%s            Mostra esta mensagem de ajuda\n", ColorYellow, ColorReset, ColorYellow, ColorReset)
+		fmt.Println()
+
+		fmt.Printf("%sCÓDIGOS DE SAÍDA%s\n", ColorCyan, ColorReset)
+		fmt.Println("  0  arquivo válido")
+		fmt.Println("  1  arquivo inválido (problemas listados)")
+		fmt.Println("  2  arquivo não pôde ser lido ou formato desconhecido")
+		fmt.Println()
+
+		fmt.Printf("%sEXEMPLOS%s\n", ColorCyan, ColorReset)
+		fmt.Println("  cli-convert validate --input dados.csv --delimiter ';'")
+		fmt.Println("  cli-convert validate --input config.yaml --report json")
+		fmt.Println("  cli-convert validate --input usuarios.csv --schema usuarios.schema.json")
+		fmt.Println()
+	}
+}
+
+// formatAliases lista os nomes alternativos aceitos em --from e --to.
+func formatAliases(format *Format) string {
+	if len(format.Aliases) == 0 {
+		return ""
+	}
+	return " — também: " + strings.Join(format.Aliases, ", ")
+}
+
+// formatDirection indica quando um formato só pode ser lido ou só escrito.
+func formatDirection(format *Format) string {
+	switch {
+	case format.Reader == nil:
+		return " — somente saída"
+	case format.Writer == nil:
+		return " — somente entrada"
 	}
+	return ""
 }
@@ -0,0 +1,560 @@
+// Package jsonschema valida a árvore genérica do pacote document contra um
+// subconjunto do JSON Schema draft 2020-12: type, properties, required,
+// items, prefixItems, enum, const, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum, additionalProperties e $ref para o
+// próprio documento (por exemplo "#/$defs/endereco").
+//
+// Palavras-chave desconhecidas são ignoradas, como manda a especificação.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cli-convert/document"
+)
+
+// maxRefDepth limita $ref aninhados, evitando laço infinito em schemas como
+// {"$ref": "#"}.
+const maxRefDepth = 512
+
+var validTypes = map[string]bool{
+	"null": true, "boolean": true, "object": true, "array": true,
+	"number": true, "integer": true, "string": true,
+}
+
+// Schema é um schema já verificado, pronto para validar instâncias.
+type Schema struct {
+	root     interface{}
+	patterns map[string]*regexp.Regexp
+	checked  map[string]bool // referências já verificadas
+}
+
+// Error é uma violação do schema. Path é o JSON Pointer (RFC 6901) do valor
+// na instância; "" indica a raiz.
+type Error struct {
+	Path    string
+	Keyword string
+	Message string
+}
+
+func (e Error) Error() string {
+	return displayPath(e.Path) + ": " + e.Message
+}
+
+// Compile verifica o schema (um *document.Object ou booleano) e resolve de
+// antemão os padrões e referências, para que erros no schema apareçam antes
+// da validação.
+func Compile(schema interface{}) (*Schema, error) {
+	s := &Schema{root: schema, patterns: make(map[string]*regexp.Regexp), checked: make(map[string]bool)}
+	if err := s.check(schema, ""); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// check percorre os subschemas conhecidos validando suas palavras-chave.
+func (s *Schema) check(schema interface{}, path string) error {
+	if _, ok := schema.(bool); ok {
+		return nil
+	}
+	obj, ok := schema.(*document.Object)
+	if !ok {
+		return fmt.Errorf("schema %s: must be an object or a boolean", displayPath(path))
+	}
+
+	for _, key := range obj.Keys() {
+		value, _ := obj.Get(key)
+		keyPath := path + "/" + escapePointer(key)
+
+		switch key {
+		case "type":
+			if err := checkType(value, keyPath); err != nil {
+				return err
+			}
+		case "required":
+			list, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("schema %s: must be an array of strings", keyPath)
+			}
+			for _, item := range list {
+				if _, ok := item.(string); !ok {
+					return fmt.Errorf("schema %s: must be an array of strings", keyPath)
+				}
+			}
+		case "enum":
+			if _, ok := value.([]interface{}); !ok {
+				return fmt.Errorf("schema %s: must be an array", keyPath)
+			}
+		case "pattern":
+			text, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("schema %s: must be a string", keyPath)
+			}
+			re, err := regexp.Compile(text)
+			if err != nil {
+				return fmt.Errorf("schema %s: invalid pattern: %v", keyPath, err)
+			}
+			s.patterns[text] = re
+		case "minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum":
+			if _, ok := toFloat(value); !ok {
+				return fmt.Errorf("schema %s: must be a number", keyPath)
+			}
+		case "$ref":
+			ref, ok := value.(string)
+			if !ok {
+				return fmt.Errorf("schema %s: must be a string", keyPath)
+			}
+			target, err := s.resolve(ref)
+			if err != nil {
+				return fmt.Errorf("schema %s: %v", keyPath, err)
+			}
+			// O alvo pode estar fora das palavras-chave percorridas (por
+			// exemplo em "definitions"), então também é verificado.
+			if !s.checked[ref] {
+				s.checked[ref] = true
+				if err := s.check(target, strings.TrimPrefix(ref, "#")); err != nil {
+					return err
+				}
+			}
+		case "items", "additionalProperties":
+			if err := s.check(value, keyPath); err != nil {
+				return err
+			}
+		case "prefixItems":
+			list, ok := value.([]interface{})
+			if !ok {
+				return fmt.Errorf("schema %s: must be an array of schemas", keyPath)
+			}
+			for i, item := range list {
+				if err := s.check(item, keyPath+"/"+strconv.Itoa(i)); err != nil {
+					return err
+				}
+			}
+		case "properties", "$defs":
+			children, ok := value.(*document.Object)
+			if !ok {
+				return fmt.Errorf("schema %s: must be an object", keyPath)
+			}
+			for _, name := range children.Keys() {
+				child, _ := children.Get(name)
+				if err := s.check(child, keyPath+"/"+escapePointer(name)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func checkType(value interface{}, path string) error {
+	switch v := value.(type) {
+	case string:
+		if !validTypes[v] {
+			return fmt.Errorf("schema %s: unknown type %q", path, v)
+		}
+		return nil
+	case []interface{}:
+		for _, item := range v {
+			name, ok := item.(string)
+			if !ok || !validTypes[name] {
+				return fmt.Errorf("schema %s: unknown type %v", path, item)
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("schema %s: must be a string or an array of strings", path)
+}
+
+// resolve segue uma referência para dentro do próprio schema. Só fragmentos
+// JSON Pointer ("#", "#/$defs/x") são aceitos.
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported $ref %q (only references within the document are supported)", ref)
+	}
+	fragment, err := url.PathUnescape(ref[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid $ref %q: %v", ref, err)
+	}
+	if fragment == "" {
+		return s.root, nil
+	}
+	if !strings.HasPrefix(fragment, "/") {
+		return nil, fmt.Errorf("unsupported $ref %q (anchors are not supported)", ref)
+	}
+
+	current := s.root
+	for _, token := range strings.Split(fragment[1:], "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := current.(type) {
+		case *document.Object:
+			next, ok := node.Get(token)
+			if !ok {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(token)
+			if err != nil || index < 0 || index >= len(node) {
+				return nil, fmt.Errorf("$ref %q not found", ref)
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("$ref %q not found", ref)
+		}
+	}
+	return current, nil
+}
+
+// Validate devolve todas as violações encontradas na instância, na ordem em
+// que aparecem.
+func (s *Schema) Validate(instance interface{}) []Error {
+	var errs []Error
+	s.validate(s.root, instance, "", 0, &errs)
+	return errs
+}
+
+func (s *Schema) validate(schema interface{}, instance interface{}, path string, depth int, errs *[]Error) {
+	if allowed, ok := schema.(bool); ok {
+		if !allowed {
+			*errs = append(*errs, Error{Path: path, Keyword: "false", Message: "no value is allowed here"})
+		}
+		return
+	}
+	obj, ok := schema.(*document.Object)
+	if !ok {
+		return
+	}
+	report := func(keyword, format string, args ...interface{}) {
+		*errs = append(*errs, Error{Path: path, Keyword: keyword, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if value, ok := obj.Get("$ref"); ok {
+		if depth >= maxRefDepth {
+			report("$ref", "$ref recursion too deep")
+		} else if target, err := s.resolve(value.(string)); err == nil {
+			s.validate(target, instance, path, depth+1, errs)
+		}
+	}
+
+	if value, ok := obj.Get("type"); ok {
+		var names []string
+		switch v := value.(type) {
+		case string:
+			names = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				names = append(names, item.(string))
+			}
+		}
+		matched := false
+		for _, name := range names {
+			if matchesType(instance, name) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report("type", "expected %s, got %s", strings.Join(names, " or "), typeOf(instance))
+			// Os demais testes não fazem sentido com o tipo errado.
+			return
+		}
+	}
+
+	if value, ok := obj.Get("enum"); ok {
+		found := false
+		for _, option := range value.([]interface{}) {
+			if equal(instance, option) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			report("enum", "value %s is not one of %s", formatValue(instance), formatValue(value))
+		}
+	}
+
+	if value, ok := obj.Get("const"); ok && !equal(instance, value) {
+		report("const", "value %s must be %s", formatValue(instance), formatValue(value))
+	}
+
+	if text, ok := stringValue(instance); ok {
+		if value, ok := obj.Get("pattern"); ok {
+			pattern := value.(string)
+			if !s.patterns[pattern].MatchString(text) {
+				report("pattern", "%q does not match pattern %q", text, pattern)
+			}
+		}
+	}
+
+	if _, ok := toFloat(instance); ok {
+		s.validateBounds(obj, instance, report)
+	}
+
+	switch v := instance.(type) {
+	case *document.Object:
+		s.validateObject(obj, v, path, depth, errs, report)
+	case []interface{}:
+		s.validateArray(obj, v, path, depth, errs)
+	}
+}
+
+func (s *Schema) validateBounds(schema *document.Object, number interface{}, report func(string, string, ...interface{})) {
+	if value, ok := schema.Get("minimum"); ok && compareNumbers(number, value) < 0 {
+		report("minimum", "%s is less than minimum %s", numberText(number), numberText(value))
+	}
+	if value, ok := schema.Get("maximum"); ok && compareNumbers(number, value) > 0 {
+		report("maximum", "%s is greater than maximum %s", numberText(number), numberText(value))
+	}
+	if value, ok := schema.Get("exclusiveMinimum"); ok && compareNumbers(number, value) <= 0 {
+		report("exclusiveMinimum", "%s must be greater than %s", numberText(number), numberText(value))
+	}
+	if value, ok := schema.Get("exclusiveMaximum"); ok && compareNumbers(number, value) >= 0 {
+		report("exclusiveMaximum", "%s must be less than %s", numberText(number), numberText(value))
+	}
+}
+
+func (s *Schema) validateObject(schema *document.Object, instance *document.Object, path string, depth int, errs *[]Error, report func(string, string, ...interface{})) {
+	if value, ok := schema.Get("required"); ok {
+		for _, item := range value.([]interface{}) {
+			name := item.(string)
+			if _, present := instance.Get(name); !present {
+				report("required", "missing required property %q", name)
+			}
+		}
+	}
+
+	var properties *document.Object
+	if value, ok := schema.Get("properties"); ok {
+		properties = value.(*document.Object)
+	}
+	additional, hasAdditional := schema.Get("additionalProperties")
+
+	for _, key := range instance.Keys() {
+		value, _ := instance.Get(key)
+		childPath := path + "/" + escapePointer(key)
+
+		if properties != nil {
+			if propertySchema, ok := properties.Get(key); ok {
+				s.validate(propertySchema, value, childPath, depth, errs)
+				continue
+			}
+		}
+		if !hasAdditional {
+			continue
+		}
+		if allowed, ok := additional.(bool); ok && !allowed {
+			*errs = append(*errs, Error{Path: childPath, Keyword: "additionalProperties", Message: fmt.Sprintf("additional property %q is not allowed", key)})
+			continue
+		}
+		s.validate(additional, value, childPath, depth, errs)
+	}
+}
+
+func (s *Schema) validateArray(schema *document.Object, instance []interface{}, path string, depth int, errs *[]Error) {
+	start := 0
+	if value, ok := schema.Get("prefixItems"); ok {
+		prefix := value.([]interface{})
+		for i := 0; i < len(prefix) && i < len(instance); i++ {
+			s.validate(prefix[i], instance[i], path+"/"+strconv.Itoa(i), depth, errs)
+		}
+		start = len(prefix)
+	}
+
+	if items, ok := schema.Get("items"); ok {
+		for i := start; i < len(instance); i++ {
+			s.validate(items, instance[i], path+"/"+strconv.Itoa(i), depth, errs)
+		}
+	}
+}
+
+// typeOf devolve o tipo JSON Schema do valor. Números sem parte fracionária
+// contam como "integer".
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string, document.DateTime:
+		return "string"
+	case int, int64:
+		return "integer"
+	case float64:
+		if v == math.Trunc(v) && !math.IsInf(v, 0) {
+			return "integer"
+		}
+		return "number"
+	case json.Number:
+		if document.IsInteger(v) {
+			return "integer"
+		}
+		return "number"
+	case *document.Object:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func matchesType(value interface{}, name string) bool {
+	actual := typeOf(value)
+	if name == "number" {
+		return actual == "number" || actual == "integer"
+	}
+	return actual == name
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// toRat devolve o valor exato de um número, para comparações que não podem
+// passar por float64 (inteiros acima de 2^53, decimais).
+func toRat(value interface{}) (*big.Rat, bool) {
+	switch v := value.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(v)), true
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case float64:
+		if math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, false
+		}
+		return new(big.Rat).SetFloat64(v), true
+	case json.Number:
+		return new(big.Rat).SetString(string(v))
+	}
+	return nil, false
+}
+
+func stringValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case document.DateTime:
+		return v.Text, true
+	}
+	return "", false
+}
+
+// equal compara valores da árvore como o JSON Schema: números pelo valor e
+// objetos sem considerar a ordem das chaves.
+func equal(a, b interface{}) bool {
+	if x, ok := toRat(a); ok {
+		y, ok := toRat(b)
+		return ok && x.Cmp(y) == 0
+	}
+	if x, ok := toFloat(a); ok {
+		y, ok := toFloat(b)
+		return ok && x == y
+	}
+	if x, ok := stringValue(a); ok {
+		y, ok := stringValue(b)
+		return ok && x == y
+	}
+
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case *document.Object:
+		y, ok := b.(*document.Object)
+		if !ok || x.Len() != y.Len() {
+			return false
+		}
+		for _, key := range x.Keys() {
+			xv, _ := x.Get(key)
+			yv, present := y.Get(key)
+			if !present || !equal(xv, yv) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func formatValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// compareNumbers compara dois números pelo valor exato, como enum e const;
+// infinitos (só em float64) são comparados como float.
+func compareNumbers(a, b interface{}) int {
+	if x, ok := toRat(a); ok {
+		if y, ok := toRat(b); ok {
+			return x.Cmp(y)
+		}
+	}
+	x, _ := toFloat(a)
+	y, _ := toFloat(b)
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// numberText escreve um número como no documento; json.Number mantém o
+// texto original, sem arredondar.
+func numberText(value interface{}) string {
+	if number, ok := value.(json.Number); ok {
+		return string(number)
+	}
+	f, _ := toFloat(value)
+	return formatNumber(f)
+}
+
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// escapePointer escapa um token de JSON Pointer (RFC 6901).
+func escapePointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func displayPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
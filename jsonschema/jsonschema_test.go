@@ -0,0 +1,170 @@
+package jsonschema
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cli-convert/document"
+)
+
+func mustParse(t *testing.T, text string) interface{} {
+	t.Helper()
+	value, err := document.Unmarshal([]byte(text))
+	if err != nil {
+		t.Fatalf("invalid test JSON %s: %v", text, err)
+	}
+	return value
+}
+
+const userSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "array",
+	"items": {"$ref": "#/$defs/user"},
+	"$defs": {
+		"user": {
+			"type": "object",
+			"required": ["id", "email"],
+			"additionalProperties": false,
+			"properties": {
+				"id": {"type": "integer", "minimum": 1},
+				"email": {"type": "string", "pattern": "^[^@]+@[^@]+$"},
+				"role": {"enum": ["admin", "user"]},
+				"score": {"type": ["number", "null"], "maximum": 10, "exclusiveMinimum": 0},
+				"a/b": {"const": true},
+				"tags": {"type": "array", "prefixItems": [{"type": "string"}], "items": {"type": "integer"}}
+			}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance string
+		expected []string
+	}{
+		{"valid", `[{"id": 1, "email": "a@b.c", "role": "admin", "score": 9.5, "tags": ["x", 1, 2]}]`, nil},
+		{"integral float is an integer", `[{"id": 2.0, "email": "a@b.c", "score": null}]`, nil},
+		{"wrong top-level type", `{"id": 1}`, []string{"(root): expected array, got object"}},
+		{
+			"every violation with its pointer",
+			`[{"id": 0, "email": "nope", "role": "root", "score": 0, "extra": 1}, {"email": 3, "a/b": false, "tags": [1, "x"]}]`,
+			[]string{
+				`/0/id: 0 is less than minimum 1`,
+				`/0/email: "nope" does not match pattern "^[^@]+@[^@]+$"`,
+				`/0/role: value "root" is not one of ["admin","user"]`,
+				`/0/score: 0 must be greater than 0`,
+				`/0/extra: additional property "extra" is not allowed`,
+				`/1: missing required property "id"`,
+				`/1/email: expected string, got integer`,
+				`/1/a~1b: value false must be true`,
+				`/1/tags/0: expected string, got integer`,
+				`/1/tags/1: expected integer, got string`,
+			},
+		},
+		{"number above maximum", `[{"id": 1, "email": "a@b", "score": 10.5}]`, []string{"/0/score: 10.5 is greater than maximum 10"}},
+	}
+
+	schema, err := Compile(mustParse(t, userSchema))
+	if err != nil {
+		t.Fatalf("Error compiling schema: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range schema.Validate(mustParse(t, tt.instance)) {
+				got = append(got, e.Error())
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Unexpected errors:\nExpected: %q\nGot:      %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidate_RecursiveRef(t *testing.T) {
+	schema, err := Compile(mustParse(t, `{"type": "object", "properties": {"name": {"type": "string"}, "children": {"type": "array", "items": {"$ref": "#"}}}}`))
+	if err != nil {
+		t.Fatalf("Error compiling schema: %v", err)
+	}
+	errs := schema.Validate(mustParse(t, `{"name": "a", "children": [{"name": "b", "children": [{"name": 1}]}]}`))
+	if len(errs) != 1 || errs[0].Path != "/children/0/children/0/name" {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	loop, err := Compile(mustParse(t, `{"$ref": "#"}`))
+	if err != nil {
+		t.Fatalf("Error compiling schema: %v", err)
+	}
+	if errs := loop.Validate(mustParse(t, `1`)); len(errs) != 1 || !strings.Contains(errs[0].Message, "too deep") {
+		t.Errorf("Expected recursion error, got %v", errs)
+	}
+}
+
+func TestValidate_BooleanSchemas(t *testing.T) {
+	schema, err := Compile(mustParse(t, `{"properties": {"any": true, "none": false}}`))
+	if err != nil {
+		t.Fatalf("Error compiling schema: %v", err)
+	}
+	errs := schema.Validate(mustParse(t, `{"any": [1, {}], "none": null}`))
+	if len(errs) != 1 || errs[0].Error() != "/none: no value is allowed here" {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+}
+
+func TestValidate_ExactNumbers(t *testing.T) {
+	schema, err := Compile(mustParse(t, `{"items": {"enum": [9007199254740993, 0.1]}}`))
+	if err != nil {
+		t.Fatalf("Error compiling schema: %v", err)
+	}
+	errs := schema.Validate(mustParse(t, `[9007199254740993, 0.10, 9007199254740992]`))
+	if len(errs) != 1 || errs[0].Path != "/2" {
+		t.Errorf("Expected only /2 to fail the enum, got %v", errs)
+	}
+
+	bounds, err := Compile(mustParse(t, `{"items": {"exclusiveMaximum": 9007199254740993, "minimum": 0.1}}`))
+	if err != nil {
+		t.Fatalf("Error compiling schema: %v", err)
+	}
+	var got []string
+	for _, e := range bounds.Validate(mustParse(t, `[9007199254740992, 9007199254740993, 0.1, 0.09999999999999999]`)) {
+		got = append(got, e.Error())
+	}
+	expected := []string{
+		"/1: 9007199254740993 must be less than 9007199254740993",
+		"/3: 0.09999999999999999 is less than minimum 0.1",
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Unexpected bound errors:\nExpected: %q\nGot:      %q", expected, got)
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		schema string
+		want   string
+	}{
+		{`{"type": "text"}`, `schema /type: unknown type "text"`},
+		{`{"properties": {"a": {"pattern": "("}}}`, "schema /properties/a/pattern: invalid pattern"},
+		{`{"items": {"$ref": "#/$defs/missing"}}`, `schema /items/$ref: $ref "#/$defs/missing" not found`},
+		{`{"$ref": "other.json#/a"}`, "only references within the document are supported"},
+		{`{"required": "id"}`, "schema /required: must be an array of strings"},
+		{`[]`, "schema (root): must be an object or a boolean"},
+	}
+
+	for _, tt := range tests {
+		_, err := Compile(mustParse(t, tt.schema))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%s): expected error containing %q, got %v", tt.schema, tt.want, err)
+		}
+	}
+}
+
+func TestCompile_ChecksRefTargets(t *testing.T) {
+	_, err := Compile(mustParse(t, `{"items": {"$ref": "#/definitions/code"}, "definitions": {"code": {"pattern": "["}}}`))
+	if err == nil || !strings.Contains(err.Error(), "schema /definitions/code/pattern: invalid pattern") {
+		t.Errorf("Expected error in referenced schema, got %v", err)
+	}
+}
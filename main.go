@@ -1,12 +1,21 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"cli-convert/ai"
+	"cli-convert/charset"
+	"cli-convert/codegen"
+	"cli-convert/document"
 	"github.com/joho/godotenv"
 )
 
@@ -30,9 +39,15 @@ func main() {
 	case "detect":
 		runDetect()
 
+	case "validate":
+		runValidate()
+
 	case "schema":
 		runSchema()
 
+	case "codegen":
+		runCodegen()
+
 	case "ask":
 		runAsk()
 
@@ -50,12 +65,38 @@ func main() {
 func runConvert() {
 	convertCmd := flag.NewFlagSet("convert", flag.ExitOnError)
 
-	input := convertCmd.String("input", "", "arquivo de entrada")
-	output := convertCmd.String("output", "", "arquivo de saída")
-	from := convertCmd.String("from", "", "formato de origem (json, csv, xml, yaml)")
-	to := convertCmd.String("to", "", "formato de destino (json, csv, xml, yaml)")
+	input := convertCmd.String("input", stdioPath, "arquivo de entrada (\"-\" ou omitido: stdin)")
+	output := convertCmd.String("output", stdioPath, "arquivo de saída (\"-\" ou omitido: stdout)")
+	from := convertCmd.String("from", "", "formato de origem ("+strings.Join(readableFormatNames(), ", ")+")")
+	to := convertCmd.String("to", "", "formato de destino ("+strings.Join(writableFormatNames(), ", ")+")")
 	delimiterFlag := convertCmd.String("delimiter", ",", "delimitador CSV")
+	noHeader := convertCmd.Bool("no-header", false, "CSV sem linha de cabeçalho (colunas col1, col2, ...)")
+	headerFlag := convertCmd.String("header", "", "nomes das colunas de um CSV sem cabeçalho, ex.: \"id,nome,email\"")
+	skipRows := convertCmd.Int("skip-rows", 0, "linhas do início do CSV a descartar antes do cabeçalho")
+	comment := convertCmd.String("comment", "", "caractere que marca linhas de comentário no CSV")
+	raggedRows := convertCmd.String("ragged-rows", raggedError, "linhas CSV de tamanho diferente do cabeçalho: error, pad ou extra")
+	quote := convertCmd.String("quote", csvQuoteMinimal, "aspas na saída CSV: minimal, all, nonnumeric ou none")
+	escape := convertCmd.String("escape", csvEscapeDouble, "escape de aspas na saída CSV: double ou backslash")
+	lineEnding := convertCmd.String("line-ending", "lf", "fim de linha da saída CSV e fixed: lf ou crlf")
+	bom := convertCmd.Bool("bom", false, "grava o BOM UTF-8 no início da saída CSV (Excel)")
+	nullString := convertCmd.String("null-string", "", "texto das células null na saída CSV, ex.: \\N")
 	root := convertCmd.String("root", "root", "nome do elemento raiz para XML")
+	attrPrefix := convertCmd.String("attr-prefix", "@", "prefixo das chaves que representam atributos XML")
+	mergeAttrs := convertCmd.Bool("merge-attrs", false, "mescla atributos XML como campos comuns")
+	documents := convertCmd.String("documents", "array", "como entregar YAML com vários documentos (array, ndjson, split)")
+	yamlStream := convertCmd.Bool("yaml-stream", false, "escreve um array de nível superior como documentos YAML separados por ---")
+	onBadLine := convertCmd.String("on-bad-line", badLineAbort, "linhas NDJSON inválidas: abort, skip ou collect")
+	badLinesPath := convertCmd.String("bad-lines", "", "arquivo que recebe as linhas NDJSON rejeitadas (modo collect)")
+	sheet := convertCmd.String("sheet", "", "planilha xlsx por nome ou posição (\"*\" para todas)")
+	flatten := convertCmd.Bool("flatten", false, "achata objetos e arrays em colunas pontilhadas (address.city, tags[0])")
+	unflatten := convertCmd.Bool("unflatten", false, "reconstrói objetos e arrays a partir de colunas pontilhadas")
+	strict := convertCmd.Bool("strict", false, "falha (código 1) se a conversão perder dados, em vez de só avisar")
+	infer := convertCmd.String("infer", inferSafe, "inferência de tipos em CSV, XML e YAML: none, safe ou aggressive")
+	columnTypes := convertCmd.String("column-types", "", "tipos por coluna, ex.: \"zip=string,age=integer\"")
+	typesFrom := convertCmd.String("types-from", "", "JSON Schema com os tipos das colunas")
+	fixedSpecPath := convertCmd.String("fixed-spec", "", "colunas do formato fixed (name, start, width, type, align)")
+	inputEncoding := convertCmd.String("input-encoding", encodingAuto, "codificação da entrada: auto, utf-8, utf-16le, utf-16be, latin1 ou windows-1252")
+	outputEncoding := convertCmd.String("output-encoding", charset.UTF8, "codificação da saída: utf-8, utf-16le, utf-16be, latin1 ou windows-1252")
 	convertCmd.Bool("help", false, "Mostra ajuda")
 
 	setConvertUsage(convertCmd)
@@ -70,71 +111,312 @@ func runConvert() {
 
 	convertCmd.Parse(os.Args[2:])
 
-	// Validações
-	if *input == "" {
-		fmt.Println("Missing required --input file")
+	inEncoding, err := parseInputEncoding(*inputEncoding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	if *output == "" {
-		fmt.Println("Missing required --output file")
+	outEncoding, err := charset.Normalize(*outputEncoding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Abre a entrada; "-" lê do stdin
+	var source io.Reader = os.Stdin
+	if !isStdio(*input) {
+		fileIn, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening input file: %v\n", err)
+			os.Exit(1)
+		}
+		defer fileIn.Close()
+		source = fileIn
+	}
+	reader := bufio.NewReaderSize(source, detectPeekSize)
+
+	// Converte a entrada para UTF-8 antes de detectar o formato
+	reader, encoding, err := decodeInput(reader, inEncoding)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if inEncoding == encodingAuto && encoding != "" && encoding != charset.UTF8 {
+		fmt.Fprintf(os.Stderr, "Auto-detected encoding: %s\n", encoding)
+	}
+
 	// Auto-detecta formato se --from não foi especificado
 	if *from == "" {
-		detected, err := ai.DetectFormat(*input)
+		detected, err := detectStreamFormat(reader, *input)
 		if err != nil {
-			fmt.Printf("Could not auto-detect format. Please specify --from.\nError: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Could not auto-detect format. Please specify --from.\nError: %v\n", err)
 			os.Exit(1)
 		}
 		*from = detected
-		fmt.Printf("Auto-detected format: %s\n", *from)
+		fmt.Fprintf(os.Stderr, "Auto-detected format: %s\n", *from)
 	}
 
 	if *to == "" {
-		fmt.Println("Missing required --to format")
+		fmt.Fprintln(os.Stderr, "Missing required --to format")
 		os.Exit(1)
 	}
 
-	// Valida formato de destino
-	switch *to {
-	case "json", "csv", "xml", "yaml":
-		*output = ensureOutputExtension(*output, *to)
-	default:
-		fmt.Printf("Unsupported format: %s\n", *to)
+	// Valida formatos no registro
+	target, err := targetFormat(*to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if !isStdio(*output) {
+		*output = ensureOutputExtension(*output, target.Extensions)
+	}
+	if target.Name == "xlsx" && outEncoding != charset.UTF8 {
+		fmt.Fprintln(os.Stderr, "--output-encoding does not apply to xlsx output")
 		os.Exit(1)
 	}
 
-	// Abre arquivos
-	fileIn, err := os.Open(*input)
-	if err != nil {
-		fmt.Printf("Error opening input file: %v\n", err)
+	switch *documents {
+	case "array", "ndjson", "split":
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported --documents mode: %s (use array, ndjson or split)\n", *documents)
 		os.Exit(1)
 	}
-	defer fileIn.Close()
 
-	fileOut, err := os.Create(*output)
-	if err != nil {
-		fmt.Printf("Error creating output file: %v\n", err)
+	switch *onBadLine {
+	case badLineAbort, badLineSkip, badLineCollect:
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported --on-bad-line mode: %s (use abort, skip or collect)\n", *onBadLine)
 		os.Exit(1)
 	}
-	defer fileOut.Close()
 
 	// Valida delimitador
 	runeArray := []rune(*delimiterFlag)
 	if len(runeArray) != 1 {
-		fmt.Println("Delimiter must be a single character")
+		fmt.Fprintln(os.Stderr, "Delimiter must be a single character")
+		os.Exit(1)
+	}
+
+	switch *raggedRows {
+	case raggedError, raggedPad, raggedExtra:
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported --ragged-rows mode: %s (use error, pad or extra)\n", *raggedRows)
+		os.Exit(1)
+	}
+
+	if *skipRows < 0 {
+		fmt.Fprintln(os.Stderr, "--skip-rows must not be negative")
+		os.Exit(1)
+	}
+
+	var commentRune rune
+	if *comment != "" {
+		commentRunes := []rune(*comment)
+		if len(commentRunes) != 1 {
+			fmt.Fprintln(os.Stderr, "Comment must be a single character")
+			os.Exit(1)
+		}
+		commentRune = commentRunes[0]
+	}
+
+	dialect, err := parseCSVDialect(*quote, *escape, *lineEnding, *bom, *nullString)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var header []string
+	if *headerFlag != "" {
+		for _, name := range strings.Split(*headerFlag, ",") {
+			header = append(header, strings.TrimSpace(name))
+		}
+	}
+
+	if *mergeAttrs {
+		*attrPrefix = ""
+	}
+
+	if *flatten && *unflatten {
+		fmt.Fprintln(os.Stderr, "--flatten and --unflatten cannot be used together")
+		os.Exit(1)
+	}
+
+	types, err := loadTypeInference(*infer, *columnTypes, *typesFrom)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+
+	var fixed *fixedSpec
+	if *fixedSpecPath != "" {
+		fixed, err = loadFixedSpec(*fixedSpecPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else if source, ok := lookupFormat(*from); target.Name == "fixed" || (ok && source.Name == "fixed") {
+		// Falha antes de criar o arquivo de saída
+		fmt.Fprintf(os.Stderr, "Error: %v\n", errMissingFixedSpec)
+		os.Exit(1)
+	}
+
+	// Sem --delimiter, o dialeto de um CSV de entrada é deduzido da amostra
 	delimiter := runeArray[0]
+	if source, ok := lookupFormat(*from); ok && source.Name == "csv" && !flagWasSet(convertCmd, "delimiter") {
+		if sniffed, ok := sniffStreamCSV(reader, *skipRows); ok {
+			delimiter = sniffed.Delimiter
+			if !sniffed.HasHeader && header == nil && !flagWasSet(convertCmd, "no-header") {
+				*noHeader = true
+			}
+			fmt.Fprintf(os.Stderr, "Auto-detected CSV delimiter: %s (header: %s)\n", sniffed.DelimiterName(), yesNo(!*noHeader))
+		}
+	}
+
+	opts := convertOptions{
+		Delimiter:  delimiter,
+		NoHeader:   *noHeader,
+		Header:     header,
+		SkipRows:   *skipRows,
+		Comment:    commentRune,
+		RaggedRows: *raggedRows,
+		Dialect:    dialect,
+		RootName:   *root,
+		AttrPrefix: *attrPrefix,
+		Documents:  *documents,
+		YamlStream: *yamlStream,
+		OnBadLine:  *onBadLine,
+		BadLines:   &badLineLog{},
+		Sheet:      *sheet,
+		Flatten:    *flatten,
+		Unflatten:  *unflatten,
+		Lossy:      &lossyLog{},
+		Strict:     *strict,
+		Types:      types,
+		Fixed:      fixed,
+		Encoding:   outEncoding,
+	}
+
+	if *documents == "split" {
+		if source, ok := lookupFormat(*from); !ok || source.Name != "yaml" {
+			fmt.Fprintln(os.Stderr, "--documents split requires YAML input")
+			os.Exit(1)
+		}
+		if isStdio(*output) {
+			fmt.Fprintln(os.Stderr, "--documents split requires an --output file")
+			os.Exit(1)
+		}
+		count, err := splitYamlConversion(reader, *output, target, outEncoding, opts)
+		if err == nil {
+			err = checkLossy(opts)
+		}
+		reportLossyEvents(opts.Lossy, *strict)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintf(os.Stderr, "Conversion from %s to %s completed successfully (%d files).\n", strings.ToUpper(*from), strings.ToUpper(*to), count)
+		return
+	}
+
+	// Abre a saída; "-" escreve no stdout
+	var destination io.Writer = os.Stdout
+	if !isStdio(*output) {
+		fileOut, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer fileOut.Close()
+		destination = fileOut
+	}
+	encoder := newOutputEncoder(destination, outEncoding, opts.Lossy)
+	writer := bufio.NewWriter(encoder)
 
 	// Dispatch de conversão
-	if err := dispatchConversion(*from, *to, fileIn, fileOut, delimiter, *root); err != nil {
-		fmt.Printf("Error: %v\n", err)
+	err = dispatchConversion(*from, *to, reader, writer, opts)
+	if err == nil {
+		// Os caracteres sem representação na codificação de saída só
+		// aparecem ao esvaziar o buffer.
+		if flushErr := writer.Flush(); flushErr != nil {
+			err = fmt.Errorf("failed to write output: %v", flushErr)
+		} else if closeErr := encoder.Close(); closeErr != nil {
+			err = fmt.Errorf("failed to write output: %v", closeErr)
+		} else {
+			err = checkLossy(opts)
+		}
+	}
+	reportLossyEvents(opts.Lossy, *strict)
+	if errors.Is(err, ErrLossyConversion) && !isStdio(*output) {
+		// No modo strict não fica para trás um arquivo com dados perdidos.
+		os.Remove(*output)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if err := reportBadLines(opts.BadLines, *badLinesPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Printf("Conversion from %s to %s completed successfully.\n", strings.ToUpper(*from), strings.ToUpper(*to))
+	fmt.Fprintf(os.Stderr, "Conversion from %s to %s completed successfully.\n", strings.ToUpper(*from), strings.ToUpper(*to))
+}
+
+// splitYamlConversion grava cada documento do stream YAML em um arquivo
+// numerado derivado de output, na codificação encoding, e retorna quantos
+// arquivos foram criados.
+func splitYamlConversion(input io.Reader, output string, target *Format, encoding string, opts convertOptions) (int, error) {
+	docs, err := parseYamlDocuments(input, opts.Types)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, doc := range docs {
+		path := numberedOutputPath(output, i+1)
+		file, err := os.Create(path)
+		if err != nil {
+			return i, fmt.Errorf("failed to create output file %s: %v", path, err)
+		}
+		encoder := newOutputEncoder(file, encoding, opts.Lossy)
+		err = target.Writer.Write(doc, encoder, opts)
+		if err == nil {
+			err = encoder.Close()
+		}
+		file.Close()
+		if err != nil {
+			return i, fmt.Errorf("document %d: %v", i+1, err)
+		}
+	}
+	return len(docs), nil
+}
+
+// reportBadLines lista em stderr as linhas NDJSON rejeitadas no modo collect
+// e, se path não estiver vazio, grava o texto original delas nesse arquivo.
+func reportBadLines(log *badLineLog, path string) error {
+	if log == nil || len(log.Lines) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: %d invalid line(s) skipped:\n", len(log.Lines))
+	for _, line := range log.Lines {
+		fmt.Fprintf(os.Stderr, "  %v\n", line.Err)
+	}
+
+	if path == "" {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create bad lines file %s: %v", path, err)
+	}
+	defer file.Close()
+
+	for _, line := range log.Lines {
+		if _, err := fmt.Fprintln(file, line.Text); err != nil {
+			return fmt.Errorf("failed to write bad lines file %s: %v", path, err)
+		}
+	}
+	return nil
 }
 
 // ──────────────────────────────────────────────
@@ -143,7 +425,7 @@ func runConvert() {
 
 func runDetect() {
 	detectCmd := flag.NewFlagSet("detect", flag.ExitOnError)
-	input := detectCmd.String("input", "", "arquivo para detectar formato")
+	input := detectCmd.String("input", stdioPath, "arquivo para detectar formato (\"-\" ou omitido: stdin)")
 	detectCmd.Bool("help", false, "Mostra ajuda")
 
 	detectCmd.Usage = func() {
@@ -153,7 +435,7 @@ func runDetect() {
 		fmt.Println("  cli-convert detect --input <file>")
 		fmt.Println()
 		fmt.Println("FLAGS:")
-		fmt.Printf("  --input <string>   Arquivo para analisar\n")
+		fmt.Printf("  --input <string>   Arquivo para analisar (\"-\" ou omitido: stdin)\n")
 		fmt.Printf("  -h, --help         Mostra esta ajuda\n")
 	}
 
@@ -166,18 +448,261 @@ func runDetect() {
 
 	detectCmd.Parse(os.Args[2:])
 
-	if *input == "" {
-		fmt.Println("Missing required --input file")
-		os.Exit(1)
+	var format, encoding string
+	var bom bool
+	var err error
+	var stdin *bufio.Reader
+	if isStdio(*input) {
+		raw := bufio.NewReaderSize(os.Stdin, detectPeekSize)
+		peek, _ := raw.Peek(detectPeekSize)
+		_, bomSize := charset.DetectBOM(peek)
+		bom = bomSize > 0
+		stdin, encoding, err = decodeInput(raw, encodingAuto)
+		if err == nil {
+			format, err = detectStreamFormat(stdin, *input)
+		}
+	} else {
+		format, err = detectFormat(*input)
+		if err == nil {
+			encoding, bom, err = detectFileEncoding(*input)
+		}
 	}
-
-	format, err := ai.DetectFormat(*input)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Printf("Detected format: %s\n", format)
+	if encoding != "" {
+		if bom {
+			fmt.Printf("Encoding: %s (BOM)\n", encoding)
+		} else {
+			fmt.Printf("Encoding: %s\n", encoding)
+		}
+	}
+
+	if format == "csv" || format == "tsv" {
+		var dialect ai.CSVDialect
+		var ok bool
+		if stdin != nil {
+			dialect, ok = sniffStreamCSV(stdin, 0)
+		} else {
+			dialect, ok = sniffFileCSV(*input)
+		}
+		if ok {
+			fmt.Printf("Delimiter: %s\n", dialect.DelimiterName())
+			fmt.Printf("Header: %s\n", yesNo(dialect.HasHeader))
+		}
+	}
+}
+
+// ──────────────────────────────────────────────
+//  Comando: validate
+// ──────────────────────────────────────────────
+
+func runValidate() {
+	validateCmd := flag.NewFlagSet("validate", flag.ExitOnError)
+	input := validateCmd.String("input", "", "arquivo para validar")
+	format := validateCmd.String("format", "", "formato do arquivo ("+strings.Join(readableFormatNames(), ", ")+")")
+	delimiterFlag := validateCmd.String("delimiter", ",", "delimitador CSV")
+	report := validateCmd.String("report", "text", "formato do relatório (text, json)")
+	schemaPath := validateCmd.String("schema", "", "JSON Schema (draft 2020-12) que os dados devem seguir")
+	validateCmd.Bool("help", false, "Mostra ajuda")
+
+	setValidateUsage(validateCmd)
+
+	for _, arg := range os.Args[2:] {
+		if arg == "--help" || arg == "-h" {
+			validateCmd.Usage()
+			os.Exit(0)
+		}
+	}
+
+	validateCmd.Parse(os.Args[2:])
+
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "Missing required --input file")
+		os.Exit(validateExitUnreadable)
+	}
+	if *report != "text" && *report != "json" {
+		fmt.Fprintf(os.Stderr, "Unsupported --report mode: %s (use text or json)\n", *report)
+		os.Exit(validateExitUnreadable)
+	}
+	runeArray := []rune(*delimiterFlag)
+	if len(runeArray) != 1 {
+		fmt.Fprintln(os.Stderr, "Delimiter must be a single character")
+		os.Exit(validateExitUnreadable)
+	}
+
+	result := validationReport{File: *input, Format: *format}
+	var issues []validationIssue
+	err := resolveValidationFormat(&result)
+	delimiter := runeArray[0]
+	if err == nil && result.Format == "csv" && !flagWasSet(validateCmd, "delimiter") {
+		if sniffed, ok := sniffFileCSV(*input); ok {
+			delimiter = sniffed.Delimiter
+		}
+	}
+	if err == nil {
+		issues, err = validateWithSchema(*input, result.Format, delimiter, *schemaPath)
+	}
+
+	exitCode := validateExitValid
+	switch {
+	case err != nil:
+		result.Error = err.Error()
+		exitCode = validateExitUnreadable
+	case len(issues) > 0:
+		exitCode = validateExitInvalid
+	}
+	result.Valid = exitCode == validateExitValid
+	result.Issues = issues
+	if result.Issues == nil {
+		result.Issues = []validationIssue{}
+	}
+
+	if *report == "json" {
+		jsonBytes, _ := json.MarshalIndent(result, "", "  ")
+		fmt.Println(string(jsonBytes))
+		os.Exit(exitCode)
+	}
+
+	switch exitCode {
+	case validateExitUnreadable:
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", result.File, err)
+	case validateExitInvalid:
+		for _, issue := range issues {
+			fmt.Printf("%s: %s\n", result.File, issue)
+		}
+		fmt.Printf("%s: invalid %s (%d problem(s) found)\n", result.File, strings.ToUpper(result.Format), len(issues))
+	default:
+		fmt.Printf("%s: valid %s\n", result.File, strings.ToUpper(result.Format))
+	}
+	os.Exit(exitCode)
+}
+
+// resolveValidationFormat preenche o formato do relatório quando --format
+// não foi informado: a extensão tem prioridade, já que um arquivo inválido
+// pode confundir a detecção por conteúdo.
+func resolveValidationFormat(result *validationReport) error {
+	if result.Format != "" {
+		format, ok := lookupFormat(result.Format)
+		if !ok || format.Reader == nil {
+			return fmt.Errorf("unsupported format: %s", result.Format)
+		}
+		result.Format = format.Name
+		return nil
+	}
+
+	if format, ok := formatForPath(result.File); ok && format.Reader != nil {
+		result.Format = format.Name
+		return nil
+	}
+	detected, err := detectFormat(result.File)
+	if err != nil {
+		return fmt.Errorf("could not detect format (use --format): %v", err)
+	}
+	result.Format = detected
+	return nil
+}
+
+// stdioPath em --input/--output indica stdin/stdout.
+const stdioPath = "-"
+
+// detectPeekSize é quanto do início da entrada é examinado na detecção.
+const detectPeekSize = 64 * 1024
+
+func isStdio(path string) bool {
+	return path == "" || path == stdioPath
+}
+
+// detectFormat analisa o conteúdo do arquivo e, se o resultado não for um
+// formato registrado, recorre à extensão.
+func detectFormat(path string) (string, error) {
+	detected, err := ai.DetectFormat(path)
+	return resolveDetectedFormat(detected, err, path)
+}
+
+// detectStreamFormat detecta o formato espiando o início da entrada, sem
+// consumi-la; funciona também com o stdin. input deve ter buffer de pelo
+// menos detectPeekSize bytes.
+func detectStreamFormat(input *bufio.Reader, path string) (string, error) {
+	peek, err := input.Peek(detectPeekSize)
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("failed to read input: %v", err)
+	}
+	detected, err := ai.DetectFormatContent(peek, err == nil)
+	return resolveDetectedFormat(detected, err, path)
+}
+
+// sniffStreamCSV deduz o dialeto do CSV espiando o início da entrada, sem
+// consumi-la. As skipRows primeiras linhas (o preâmbulo) ficam de fora da
+// amostra.
+func sniffStreamCSV(input *bufio.Reader, skipRows int) (ai.CSVDialect, bool) {
+	peek, err := input.Peek(detectPeekSize)
+	if err != nil && err != io.EOF {
+		return ai.CSVDialect{}, false
+	}
+	sample := bytes.TrimPrefix(peek, []byte("\ufeff"))
+	for i := 0; i < skipRows; i++ {
+		end := bytes.IndexByte(sample, '\n')
+		if end < 0 {
+			return ai.CSVDialect{}, false
+		}
+		sample = sample[end+1:]
+	}
+	return ai.SniffCSV(sample, err == nil)
+}
+
+// sniffFileCSV deduz o dialeto a partir do início do arquivo.
+func sniffFileCSV(path string) (ai.CSVDialect, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return ai.CSVDialect{}, false
+	}
+	defer file.Close()
+	input, _, err := decodeInput(bufio.NewReaderSize(file, detectPeekSize), encodingAuto)
+	if err != nil {
+		return ai.CSVDialect{}, false
+	}
+	return sniffStreamCSV(input, 0)
+}
+
+// flagWasSet indica se a flag foi passada na linha de comando (e não só
+// ficou com o valor padrão).
+func flagWasSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
+
+// resolveDetectedFormat confirma o formato detectado no registro e, se não
+// for possível, recorre à extensão do arquivo.
+func resolveDetectedFormat(detected string, err error, path string) (string, error) {
+	if err == nil {
+		if _, ok := lookupFormat(detected); ok {
+			return detected, nil
+		}
+	}
+
+	if format, ok := formatForPath(path); ok && format.Reader != nil && !isStdio(path) {
+		return format.Name, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("detected format %s is not supported", detected)
 }
 
 // ──────────────────────────────────────────────
@@ -187,20 +712,36 @@ func runDetect() {
 func runSchema() {
 	schemaCmd := flag.NewFlagSet("schema", flag.ExitOnError)
 	input := schemaCmd.String("input", "", "arquivo para inferir schema")
+	output := schemaCmd.String("output", stdioPath, "arquivo do schema (\"-\" ou omitido: stdout)")
+	from := schemaCmd.String("from", "", "formato do arquivo ("+strings.Join(readableFormatNames(), ", ")+")")
+	format := schemaCmd.String("format", "json", "formato do schema gerado (json, yaml)")
+	title := schemaCmd.String("title", "", "título do schema (padrão: nome do arquivo)")
+	delimiterFlag := schemaCmd.String("delimiter", ",", "delimitador CSV")
+	enumMax := schemaCmd.Int("enum-max", 0, "gera enum para campos string com até N valores distintos (0 desativa)")
+	aiDescribe := schemaCmd.Bool("ai-describe", false, "pede à IA uma descrição dos campos")
 	schemaCmd.Bool("help", false, "Mostra ajuda")
 
 	schemaCmd.Usage = func() {
 		fmt.Println("cli-convert schema — Gera um JSON Schema a partir de um arquivo de dados.")
 		fmt.Println()
 		fmt.Println("USAGE:")
-		fmt.Println("  cli-convert schema --input <file>")
+		fmt.Println("  cli-convert schema --input <file> [--output <file>] [--format json|yaml]")
 		fmt.Println()
 		fmt.Println("FLAGS:")
 		fmt.Printf("  --input <string>   Arquivo para analisar\n")
+		fmt.Printf("  --output <string>  Arquivo do schema (padrão: stdout)\n")
+		fmt.Printf("  --from <string>    Formato do arquivo de entrada (padrão: pela extensão ou conteúdo)\n")
+		fmt.Printf("  --format <string>  Formato do schema gerado: json ou yaml (padrão: json)\n")
+		fmt.Printf("  --title <string>   Título do schema (padrão: nome do arquivo sem extensão)\n")
+		fmt.Printf("  --delimiter <char> Delimitador CSV (padrão: ',')\n")
+		fmt.Printf("  --enum-max <n>     Gera enum para campos string com até n valores distintos (padrão: 0, desativado)\n")
+		fmt.Printf("  --ai-describe      Acrescenta em \"description\" uma descrição dos campos gerada por IA\n")
 		fmt.Printf("  -h, --help         Mostra esta ajuda\n")
 		fmt.Println()
-		fmt.Println("Para arquivos JSON, o schema é gerado localmente.")
-		fmt.Println("Para outros formatos, usa IA (configure OPENROUTER_API_KEY no .env).")
+		fmt.Println("O schema segue o JSON Schema draft 2020-12 e é inferido localmente, com os")
+		fmt.Println("mesmos leitores do comando convert, unindo todos os registros: tipos")
+		fmt.Println("diferentes viram uniões e \"required\" lista os campos presentes em todos eles.")
+		fmt.Println("Só --ai-describe usa IA (configure OPENROUTER_API_KEY no .env).")
 	}
 
 	for _, arg := range os.Args[2:] {
@@ -213,32 +754,183 @@ func runSchema() {
 	schemaCmd.Parse(os.Args[2:])
 
 	if *input == "" {
-		fmt.Println("Missing required --input file")
+		fmt.Fprintln(os.Stderr, "Missing required --input file")
+		os.Exit(1)
+	}
+	runeArray := []rune(*delimiterFlag)
+	if len(runeArray) != 1 {
+		fmt.Fprintln(os.Stderr, "Delimiter must be a single character")
+		os.Exit(1)
+	}
+	if *enumMax < 0 {
+		fmt.Fprintln(os.Stderr, "--enum-max must not be negative")
+		os.Exit(1)
+	}
+	target, err := schemaOutputFormat(*format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	schema, err := ai.InferSchema(*input)
+	opts := convertOptions{Delimiter: runeArray[0], RootName: "root", AttrPrefix: "@"}
+	data, source, err := readFileTree(*input, *from, opts)
 	if err != nil {
-		fmt.Printf("Error: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Imprime o schema formatado
-	fmt.Printf("Schema for: %s\n", schema["source"])
-	fmt.Printf("Format: %s\n\n", schema["format"])
+	if *title == "" {
+		*title = strings.TrimSuffix(filepath.Base(*input), filepath.Ext(*input))
+	}
+	schema := ai.InferSchema(data, *title, ai.InferOptions{EnumMax: *enumMax})
+	if *aiDescribe {
+		analysis, err := ai.DescribeSchema(schema, *input, source.Name)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		schema = withSchemaDescription(schema, analysis)
+	}
 
-	if props, ok := schema["properties"].(map[string]interface{}); ok && len(props) > 0 {
-		fmt.Println("Properties:")
-		for key, prop := range props {
-			if p, ok := prop.(map[string]interface{}); ok {
-				fmt.Printf("  %s: %v\n", key, p["type"])
-			}
+	// Escreve o schema; "-" escreve no stdout
+	var destination io.Writer = os.Stdout
+	if !isStdio(*output) {
+		fileOut, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+			os.Exit(1)
+		}
+		defer fileOut.Close()
+		destination = fileOut
+	}
+	if err := target.Writer.Write(schema, destination, convertOptions{}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !isStdio(*output) {
+		fmt.Fprintf(os.Stderr, "Schema for %s (%s) written to %s\n", *input, source.Name, *output)
+	}
+}
+
+// schemaOutputFormat restringe o schema gerado a JSON e YAML, os formatos
+// que os validadores de JSON Schema costumam aceitar.
+func schemaOutputFormat(name string) (*Format, error) {
+	format, ok := lookupFormat(name)
+	if !ok || (format.Name != "json" && format.Name != "yaml") {
+		return nil, fmt.Errorf("unsupported schema format: %s (supported: json, yaml)", name)
+	}
+	return format, nil
+}
+
+// withSchemaDescription devolve uma cópia do schema com "description" logo
+// após "title", onde os leitores humanos esperam encontrá-la.
+func withSchemaDescription(schema *document.Object, description string) *document.Object {
+	result := document.NewObject()
+	for _, key := range schema.Keys() {
+		value, _ := schema.Get(key)
+		result.Set(key, value)
+		if key == "title" {
+			result.Set("description", description)
 		}
 	}
+	if _, ok := result.Get("description"); !ok {
+		result.Set("description", description)
+	}
+	return result
+}
+
+// ──────────────────────────────────────────────
+//  Comando: codegen
+// ──────────────────────────────────────────────
 
-	if analysis, ok := schema["ai_analysis"].(string); ok {
-		fmt.Printf("\nAI Analysis:\n%s\n", analysis)
+func runCodegen() {
+	codegenCmd := flag.NewFlagSet("codegen", flag.ExitOnError)
+	input := codegenCmd.String("input", "", "arquivo de dados de exemplo")
+	output := codegenCmd.String("output", stdioPath, "arquivo gerado (\"-\" ou omitido: stdout)")
+	from := codegenCmd.String("from", "", "formato do arquivo ("+strings.Join(readableFormatNames(), ", ")+")")
+	lang := codegenCmd.String("lang", "", "linguagem gerada ("+strings.Join(codegen.Languages(), ", ")+")")
+	name := codegenCmd.String("name", "", "nome do tipo raiz (padrão: nome do arquivo)")
+	pkg := codegenCmd.String("package", "main", "pacote do código Go gerado")
+	delimiterFlag := codegenCmd.String("delimiter", ",", "delimitador CSV")
+	codegenCmd.Bool("help", false, "Mostra ajuda")
+
+	codegenCmd.Usage = func() {
+		fmt.Println("cli-convert codegen — Gera structs Go ou tipos TypeScript a partir de um arquivo de dados.")
+		fmt.Println()
+		fmt.Println("USAGE:")
+		fmt.Println("  cli-convert codegen --lang go|typescript --input <file> [--output <file>]")
+		fmt.Println()
+		fmt.Println("FLAGS:")
+		fmt.Printf("  --input <string>   Arquivo de dados de exemplo (qualquer formato do convert)\n")
+		fmt.Printf("  --lang <string>    Linguagem gerada: go ou typescript\n")
+		fmt.Printf("  --output <string>  Arquivo gerado (padrão: stdout)\n")
+		fmt.Printf("  --from <string>    Formato do arquivo (padrão: pela extensão ou conteúdo)\n")
+		fmt.Printf("  --name <string>    Nome do tipo raiz (padrão: nome do arquivo)\n")
+		fmt.Printf("  --package <string> Pacote do código Go (padrão: main)\n")
+		fmt.Printf("  --delimiter <char> Delimitador CSV (padrão: ',')\n")
+		fmt.Printf("  -h, --help         Mostra esta ajuda\n")
+		fmt.Println()
+		fmt.Println("Os tipos vêm do mesmo schema inferido pelo comando schema: objetos aninhados")
+		fmt.Println("viram tipos nomeados, arrays viram slices e campos ausentes em algum registro")
+		fmt.Println("são opcionais (omitempty no Go, \"?\" no TypeScript).")
+	}
+
+	for _, arg := range os.Args[2:] {
+		if arg == "--help" || arg == "-h" {
+			codegenCmd.Usage()
+			os.Exit(0)
+		}
+	}
+
+	codegenCmd.Parse(os.Args[2:])
+
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "Missing required --input file")
+		os.Exit(1)
+	}
+	if *lang == "" {
+		fmt.Fprintln(os.Stderr, "Missing required --lang (go or typescript)")
+		os.Exit(1)
+	}
+	runeArray := []rune(*delimiterFlag)
+	if len(runeArray) != 1 {
+		fmt.Fprintln(os.Stderr, "Delimiter must be a single character")
+		os.Exit(1)
+	}
+
+	opts := convertOptions{Delimiter: runeArray[0], RootName: "root", AttrPrefix: "@"}
+	data, _, err := readFileTree(*input, *from, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *name == "" {
+		*name = strings.TrimSuffix(filepath.Base(*input), filepath.Ext(*input))
+	}
+	schema := ai.InferSchema(data, "", ai.InferOptions{})
+	code, err := codegen.Generate(*lang, schema, codegen.Options{
+		Name:       *name,
+		Package:    *pkg,
+		Source:     filepath.Base(*input),
+		AttrPrefix: opts.AttrPrefix,
+		TextKey:    xmlTextKey,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if isStdio(*output) {
+		os.Stdout.Write(code)
+		return
+	}
+	if err := os.WriteFile(*output, code, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating output file: %v\n", err)
+		os.Exit(1)
 	}
+	fmt.Fprintf(os.Stderr, "Types for %s written to %s\n", *input, *output)
 }
 
 // ──────────────────────────────────────────────
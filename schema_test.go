@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"cli-convert/ai"
+	"cli-convert/document"
+)
+
+func TestInferSchema_LocalForEveryFormat(t *testing.T) {
+	tests := []struct {
+		file     string
+		content  string
+		expected string
+	}{
+		{"config.json", `{"name": "app", "port": 8080}`,
+			`{"name":{"type":"string"},"port":{"type":"integer"}}`},
+		{"config.yaml", "name: app\ndebug: true\n",
+			`{"name":{"type":"string"},"debug":{"type":"boolean"}}`},
+		{"config.xml", "<config><name>app</name></config>",
+			`{"config":{"type":"object","properties":{"name":{"type":"string"}},"required":["name"]}}`},
+		{"config.toml", "name = \"app\"\n[db]\nport = 5432\n",
+			`{"name":{"type":"string"},"db":{"type":"object","properties":{"port":{"type":"integer"}},"required":["port"]}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			path := writeValidationFile(t, tt.file, tt.content)
+			data, _, err := readFileTree(path, "", convertOptions{Delimiter: ',', AttrPrefix: "@"})
+			if err != nil {
+				t.Fatalf("Error reading %s: %v", tt.file, err)
+			}
+			schema := ai.InferSchema(data, "", ai.InferOptions{})
+			props, _ := schema.Get("properties")
+			got, err := json.Marshal(props)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Unexpected properties:\nExpected: %s\nGot:      %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInferSchema_MergesAllRecords(t *testing.T) {
+	content := `[
+  {"id": 1, "name": "Alice", "status": "active", "score": 9, "tags": [{"k": "a"}]},
+  {"id": 2, "name": null, "status": "inactive", "score": 7.5, "tags": [{"k": "b", "v": 1}]},
+  {"id": 3, "status": "active", "tags": []}
+]`
+	path := writeValidationFile(t, "users.json", content)
+	data, _, err := readFileTree(path, "", convertOptions{})
+	if err != nil {
+		t.Fatalf("Error reading: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		opts     ai.InferOptions
+		expected string
+	}{
+		{"without enum", ai.InferOptions{}, `{"type":"array","items":{"type":"object","properties":{` +
+			`"id":{"type":"integer"},` +
+			`"name":{"type":["string","null"]},` +
+			`"status":{"type":"string"},` +
+			`"score":{"type":"number"},` +
+			`"tags":{"type":"array","items":{"type":"object","properties":{"k":{"type":"string"},"v":{"type":"integer"}},"required":["k"]}}` +
+			`},"required":["id","status","tags"]}}`},
+		{"with enum", ai.InferOptions{EnumMax: 2}, `{"type":"array","items":{"type":"object","properties":{` +
+			`"id":{"type":"integer"},` +
+			`"name":{"type":["string","null"]},` +
+			`"status":{"type":"string","enum":["active","inactive"]},` +
+			`"score":{"type":"number"},` +
+			`"tags":{"type":"array","items":{"type":"object","properties":{"k":{"type":"string"},"v":{"type":"integer"}},"required":["k"]}}` +
+			`},"required":["id","status","tags"]}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema := ai.InferSchema(data, "", tt.opts)
+			schema.Delete("$schema")
+			got, err := json.Marshal(schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.expected {
+				t.Errorf("Unexpected schema:\nExpected: %s\nGot:      %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestInferSchema_UnknownTypeLeavesTypeOut(t *testing.T) {
+	first := document.NewObject()
+	first.Set("v", "a")
+	second := document.NewObject()
+	second.Set("v", uint8(1))
+
+	schema := ai.InferSchema([]interface{}{first, second, first}, "", ai.InferOptions{EnumMax: 2})
+	items, _ := schema.Get("items")
+	got, _ := json.Marshal(items)
+	if expected := `{"type":"object","properties":{"v":{}},"required":["v"]}`; string(got) != expected {
+		t.Errorf("Unexpected items schema:\nExpected: %s\nGot:      %s", expected, got)
+	}
+}
+
+func TestInferSchema_OutputFeedsValidator(t *testing.T) {
+	content := "id,name,role\n1,Alice,admin\n2,,user\n3,Carol,admin\n"
+	path := writeValidationFile(t, "users.csv", content)
+	data, _, err := readFileTree(path, "", convertOptions{Delimiter: ','})
+	if err != nil {
+		t.Fatalf("Error reading: %v", err)
+	}
+	schema := ai.InferSchema(data, "users", ai.InferOptions{EnumMax: 2})
+
+	for _, name := range []string{"json", "yaml"} {
+		t.Run(name, func(t *testing.T) {
+			target, err := schemaOutputFormat(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := target.Writer.Write(schema, &buf, convertOptions{}); err != nil {
+				t.Fatalf("Error writing schema: %v", err)
+			}
+			if !strings.Contains(buf.String(), ai.SchemaDialect) {
+				t.Errorf("Expected $schema %s in output:\n%s", ai.SchemaDialect, buf.String())
+			}
+
+			schemaPath := writeValidationFile(t, "users.schema."+name, buf.String())
+			if _, err := loadSchema(schemaPath); err != nil {
+				t.Fatalf("Generated schema does not compile: %v", err)
+			}
+			// O nome vazio é só um aviso estrutural; o schema aceita o arquivo.
+			issues, err := validateWithSchema(path, "csv", ',', schemaPath)
+			if err != nil || len(issues) != 1 || issues[0].Path != "" {
+				t.Errorf("Expected source file to match the schema, got %v (err %v)", issues, err)
+			}
+
+			bad := writeValidationFile(t, "bad.csv", "id,name,role\nx,Dan,guest\n")
+			issues, _ = validateWithSchema(bad, "csv", ',', schemaPath)
+			if len(issues) != 2 {
+				t.Errorf("Expected 2 issues for bad file, got %v", issues)
+			}
+		})
+	}
+
+	if _, err := schemaOutputFormat("csv"); err == nil {
+		t.Error("Expected error for csv schema output")
+	}
+}
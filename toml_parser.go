@@ -0,0 +1,873 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cli-convert/document"
+)
+
+// tomlError aponta a posição (linha e coluna, a partir de 1) de um erro de
+// sintaxe ou de semântica em um documento TOML.
+type tomlError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *tomlError) Error() string {
+	return fmt.Sprintf("toml: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+// tomlTableKind registra como uma tabela foi criada, para aplicar as regras
+// de redefinição do TOML 1.0.
+type tomlTableKind int
+
+const (
+	tomlImplicit tomlTableKind = iota // criada como caminho de um cabeçalho
+	tomlExplicit                      // definida por [cabeçalho]
+	tomlDotted                        // criada por chaves pontuadas (a.b = 1)
+	tomlInline                        // tabela inline, fechada para extensão
+)
+
+var (
+	tomlBareKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	tomlIntPattern     = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)$`)
+	tomlFloatPattern   = regexp.MustCompile(`^[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?$`)
+	tomlHexPattern     = regexp.MustCompile(`^0x[0-9A-Fa-f](_?[0-9A-Fa-f])*$`)
+	tomlOctPattern     = regexp.MustCompile(`^0o[0-7](_?[0-7])*$`)
+	tomlBinPattern     = regexp.MustCompile(`^0b[01](_?[01])*$`)
+	tomlDatePattern    = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	tomlTimePattern    = regexp.MustCompile(`^(\d{2}):(\d{2}):(\d{2})(\.\d+)?$`)
+	tomlOffsetPattern  = regexp.MustCompile(`^(Z|z|[+-]\d{2}:\d{2})$`)
+)
+
+type tomlParser struct {
+	src         string
+	pos         int
+	root        *document.Object
+	current     *document.Object
+	kinds       map[*document.Object]tomlTableKind
+	arrayTables map[*document.Object]map[string]bool
+}
+
+// parseToml lê um documento TOML 1.0 para a árvore genérica.
+func parseToml(input io.Reader) (*document.Object, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %v", err)
+	}
+
+	root := document.NewObject()
+	p := &tomlParser{
+		src:         strings.TrimPrefix(string(data), "\ufeff"),
+		root:        root,
+		current:     root,
+		kinds:       map[*document.Object]tomlTableKind{root: tomlExplicit},
+		arrayTables: make(map[*document.Object]map[string]bool),
+	}
+	if !utf8.ValidString(p.src) {
+		return nil, &tomlError{Line: 1, Column: 1, Message: "document is not valid UTF-8"}
+	}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func (p *tomlParser) errorAt(pos int, format string, args ...interface{}) error {
+	line, column := 1, 1
+	for _, r := range p.src[:pos] {
+		if r == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return &tomlError{Line: line, Column: column, Message: fmt.Sprintf(format, args...)}
+}
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.pos, format, args...)
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *tomlParser) rest() string {
+	return p.src[p.pos:]
+}
+
+func (p *tomlParser) skipWhitespace() {
+	for !p.eof() && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+// skipComment consome um comentário até o fim da linha.
+func (p *tomlParser) skipComment() error {
+	if p.peek() != '#' {
+		return nil
+	}
+	for !p.eof() && p.src[p.pos] != '\n' {
+		c := p.src[p.pos]
+		if c == '\r' && strings.HasPrefix(p.rest(), "\r\n") {
+			break
+		}
+		if (c < 0x20 && c != '\t') || c == 0x7f {
+			return p.errorf("control character %q is not allowed in comments", c)
+		}
+		p.pos++
+	}
+	return nil
+}
+
+// skipNewline consome uma quebra de linha, se houver.
+func (p *tomlParser) skipNewline() (bool, error) {
+	switch {
+	case strings.HasPrefix(p.rest(), "\n"):
+		p.pos++
+		return true, nil
+	case strings.HasPrefix(p.rest(), "\r\n"):
+		p.pos += 2
+		return true, nil
+	case p.peek() == '\r':
+		return false, p.errorf("carriage return must be followed by a newline")
+	}
+	return false, nil
+}
+
+// skipBlank consome espaços, comentários e quebras de linha (dentro de
+// arrays).
+func (p *tomlParser) skipBlank() error {
+	for {
+		p.skipWhitespace()
+		if err := p.skipComment(); err != nil {
+			return err
+		}
+		newline, err := p.skipNewline()
+		if err != nil {
+			return err
+		}
+		if !newline {
+			return nil
+		}
+	}
+}
+
+func (p *tomlParser) expectLineEnd() error {
+	p.skipWhitespace()
+	if err := p.skipComment(); err != nil {
+		return err
+	}
+	if p.eof() {
+		return nil
+	}
+	newline, err := p.skipNewline()
+	if err != nil {
+		return err
+	}
+	if !newline {
+		return p.errorf("expected end of line, found %q", p.peek())
+	}
+	return nil
+}
+
+func (p *tomlParser) parse() error {
+	for {
+		p.skipWhitespace()
+		if p.eof() {
+			return nil
+		}
+
+		switch p.peek() {
+		case '#', '\n', '\r':
+		case '[':
+			if err := p.parseTableHeader(); err != nil {
+				return err
+			}
+		default:
+			if err := p.parseKeyValue(p.current); err != nil {
+				return err
+			}
+		}
+
+		if err := p.expectLineEnd(); err != nil {
+			return err
+		}
+	}
+}
+
+// parseKey lê uma chave simples ou pontuada (a."b.c".d).
+func (p *tomlParser) parseKey() ([]string, error) {
+	var parts []string
+	for {
+		p.skipWhitespace()
+		var part string
+		switch p.peek() {
+		case '"':
+			if strings.HasPrefix(p.rest(), `"""`) {
+				return nil, p.errorf("multi-line strings cannot be used as keys")
+			}
+			s, err := p.parseBasicString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		case '\'':
+			if strings.HasPrefix(p.rest(), "'''") {
+				return nil, p.errorf("multi-line strings cannot be used as keys")
+			}
+			s, err := p.parseLiteralString()
+			if err != nil {
+				return nil, err
+			}
+			part = s
+		default:
+			start := p.pos
+			for !p.eof() && isTomlBareKeyChar(p.src[p.pos]) {
+				p.pos++
+			}
+			if start == p.pos {
+				if p.eof() {
+					return nil, p.errorf("expected a key")
+				}
+				return nil, p.errorf("invalid character %q in key", p.peek())
+			}
+			part = p.src[start:p.pos]
+		}
+		parts = append(parts, part)
+
+		p.skipWhitespace()
+		if p.peek() != '.' {
+			return parts, nil
+		}
+		p.pos++
+	}
+}
+
+func isTomlBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseTableHeader() error {
+	start := p.pos
+	isArray := strings.HasPrefix(p.rest(), "[[")
+	if isArray {
+		p.pos += 2
+	} else {
+		p.pos++
+	}
+
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+
+	closing := "]"
+	if isArray {
+		closing = "]]"
+	}
+	if !strings.HasPrefix(p.rest(), closing) {
+		return p.errorf("expected %q to close table header", closing)
+	}
+	p.pos += len(closing)
+
+	table := p.root
+	for _, key := range keys[:len(keys)-1] {
+		table, err = p.descend(table, key, true, start)
+		if err != nil {
+			return err
+		}
+	}
+
+	last := keys[len(keys)-1]
+	name := strings.Join(keys, ".")
+	existing, exists := table.Get(last)
+
+	if isArray {
+		newTable := document.NewObject()
+		p.kinds[newTable] = tomlExplicit
+		switch {
+		case !exists:
+			table.Set(last, []interface{}{newTable})
+			if p.arrayTables[table] == nil {
+				p.arrayTables[table] = make(map[string]bool)
+			}
+			p.arrayTables[table][last] = true
+		case p.arrayTables[table][last]:
+			table.Set(last, append(existing.([]interface{}), newTable))
+		default:
+			return p.errorAt(start, "key %q is already defined and is not an array of tables", name)
+		}
+		p.current = newTable
+		return nil
+	}
+
+	if !exists {
+		newTable := document.NewObject()
+		p.kinds[newTable] = tomlExplicit
+		table.Set(last, newTable)
+		p.current = newTable
+		return nil
+	}
+
+	existingTable, isTable := existing.(*document.Object)
+	if !isTable || p.arrayTables[table][last] {
+		return p.errorAt(start, "key %q is already defined and is not a table", name)
+	}
+	switch p.kinds[existingTable] {
+	case tomlExplicit:
+		return p.errorAt(start, "table %q is already defined", name)
+	case tomlDotted:
+		return p.errorAt(start, "table %q is already defined by dotted keys", name)
+	case tomlInline:
+		return p.errorAt(start, "inline table %q cannot be extended", name)
+	}
+	p.kinds[existingTable] = tomlExplicit
+	p.current = existingTable
+	return nil
+}
+
+// descend devolve a subtabela key de table, criando-a se necessário. Em um
+// array de tabelas, o caminho continua pelo último elemento.
+func (p *tomlParser) descend(table *document.Object, key string, fromHeader bool, pos int) (*document.Object, error) {
+	existing, exists := table.Get(key)
+	if !exists {
+		child := document.NewObject()
+		if fromHeader {
+			p.kinds[child] = tomlImplicit
+		} else {
+			p.kinds[child] = tomlDotted
+		}
+		table.Set(key, child)
+		return child, nil
+	}
+
+	if p.arrayTables[table][key] {
+		list := existing.([]interface{})
+		return list[len(list)-1].(*document.Object), nil
+	}
+
+	child, isTable := existing.(*document.Object)
+	if !isTable {
+		return nil, p.errorAt(pos, "key %q is already defined and is not a table", key)
+	}
+	switch kind := p.kinds[child]; {
+	case kind == tomlInline:
+		return nil, p.errorAt(pos, "inline table %q cannot be extended", key)
+	case kind == tomlExplicit && !fromHeader:
+		return nil, p.errorAt(pos, "table %q is defined by a header and cannot be extended with dotted keys", key)
+	}
+	return child, nil
+}
+
+func (p *tomlParser) parseKeyValue(table *document.Object) error {
+	start := p.pos
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if p.peek() != '=' {
+		return p.errorf("expected '=' after key %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipWhitespace()
+
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+
+	target := table
+	for _, key := range keys[:len(keys)-1] {
+		target, err = p.descend(target, key, false, start)
+		if err != nil {
+			return err
+		}
+	}
+
+	last := keys[len(keys)-1]
+	if _, exists := target.Get(last); exists {
+		return p.errorAt(start, "duplicate key %q", strings.Join(keys, "."))
+	}
+	target.Set(last, value)
+	return nil
+}
+
+func (p *tomlParser) parseValue() (interface{}, error) {
+	if p.eof() {
+		return nil, p.errorf("expected a value")
+	}
+
+	switch c := p.peek(); c {
+	case '"':
+		if strings.HasPrefix(p.rest(), `"""`) {
+			return p.parseMultilineBasicString()
+		}
+		return p.parseBasicString()
+	case '\'':
+		if strings.HasPrefix(p.rest(), "'''") {
+			return p.parseMultilineLiteralString()
+		}
+		return p.parseLiteralString()
+	case '[':
+		return p.parseArray()
+	case '{':
+		return p.parseInlineTable()
+	case '\n', '\r', '#':
+		return nil, p.errorf("expected a value")
+	}
+
+	start := p.pos
+	token := p.scanToken()
+	if token == "true" {
+		return true, nil
+	}
+	if token == "false" {
+		return false, nil
+	}
+	if value, ok := parseTomlDateTime(token); ok {
+		return value, nil
+	}
+	if value, ok, err := parseTomlNumber(token); ok {
+		return value, nil
+	} else if err != nil {
+		return nil, p.errorAt(start, "%v", err)
+	}
+	if token == "" {
+		return nil, p.errorf("unexpected character %q", p.peek())
+	}
+	return nil, p.errorAt(start, "invalid value %q", token)
+}
+
+// scanToken lê um valor sem aspas (número, booleano ou data/hora). Uma data
+// seguida de espaço e hora é lida como um único token.
+func (p *tomlParser) scanToken() string {
+	start := p.pos
+	for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	token := p.src[start:p.pos]
+
+	if tomlDatePattern.MatchString(token) && len(p.rest()) >= 3 && p.src[p.pos] == ' ' &&
+		isDigit(p.src[p.pos+1]) && isDigit(p.src[p.pos+2]) {
+		p.pos++
+		for !p.eof() && !strings.ContainsRune(" \t\r\n,]}#", rune(p.src[p.pos])) {
+			p.pos++
+		}
+		token = p.src[start:p.pos]
+	}
+	return token
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func parseTomlNumber(token string) (interface{}, bool, error) {
+	switch token {
+	case "inf", "+inf":
+		return math.Inf(1), true, nil
+	case "-inf":
+		return math.Inf(-1), true, nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), true, nil
+	}
+
+	base := 0
+	digits := token
+	switch {
+	case tomlHexPattern.MatchString(token):
+		base, digits = 16, token[2:]
+	case tomlOctPattern.MatchString(token):
+		base, digits = 8, token[2:]
+	case tomlBinPattern.MatchString(token):
+		base, digits = 2, token[2:]
+	case tomlIntPattern.MatchString(token):
+		base = 10
+	}
+	if base != 0 {
+		i, err := strconv.ParseInt(strings.ReplaceAll(digits, "_", ""), base, 64)
+		if err != nil {
+			return nil, false, fmt.Errorf("integer %s is out of range", token)
+		}
+		return int(i), true, nil
+	}
+
+	if tomlFloatPattern.MatchString(token) {
+		// O texto original vira json.Number, como na inferência dos
+		// formatos de texto: "1.50" não perde o zero nem a precisão. Sem
+		// "_" e sem "+" inicial, todo float TOML é um número JSON válido.
+		text := strings.TrimPrefix(strings.ReplaceAll(token, "_", ""), "+")
+		if _, err := strconv.ParseFloat(text, 64); err != nil {
+			return nil, false, fmt.Errorf("float %s is out of range", token)
+		}
+		return json.Number(text), true, nil
+	}
+	return nil, false, nil
+}
+
+// parseTomlDateTime reconhece as quatro variantes de data/hora do TOML e
+// normaliza o separador para "T" e o fuso "z" para "Z".
+func parseTomlDateTime(token string) (document.DateTime, bool) {
+	if validTomlDate(token) {
+		return document.DateTime{Kind: document.LocalDate, Text: token}, true
+	}
+	if validTomlTime(token) {
+		return document.DateTime{Kind: document.LocalTime, Text: token}, true
+	}
+
+	if len(token) < 19 || !strings.ContainsRune("Tt ", rune(token[10])) || !validTomlDate(token[:10]) {
+		return document.DateTime{}, false
+	}
+	rest := token[11:]
+	timeEnd := 8
+	for timeEnd < len(rest) && (rest[timeEnd] == '.' || isDigit(rest[timeEnd])) {
+		timeEnd++
+	}
+	if !validTomlTime(rest[:timeEnd]) {
+		return document.DateTime{}, false
+	}
+
+	text := token[:10] + "T" + rest[:timeEnd]
+	offset := rest[timeEnd:]
+	if offset == "" {
+		return document.DateTime{Kind: document.LocalDateTime, Text: text}, true
+	}
+	if !tomlOffsetPattern.MatchString(offset) {
+		return document.DateTime{}, false
+	}
+	if offset != "Z" && offset != "z" {
+		hour, _ := strconv.Atoi(offset[1:3])
+		minute, _ := strconv.Atoi(offset[4:6])
+		if hour > 23 || minute > 59 {
+			return document.DateTime{}, false
+		}
+	}
+	return document.DateTime{Kind: document.OffsetDateTime, Text: text + strings.ToUpper(offset)}, true
+}
+
+func validTomlDate(s string) bool {
+	m := tomlDatePattern.FindStringSubmatch(s)
+	if m == nil {
+		return false
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	if month < 1 || month > 12 || day < 1 {
+		return false
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Day() == day
+}
+
+func validTomlTime(s string) bool {
+	m := tomlTimePattern.FindStringSubmatch(s)
+	if m == nil {
+		return false
+	}
+	hour, _ := strconv.Atoi(m[1])
+	minute, _ := strconv.Atoi(m[2])
+	second, _ := strconv.Atoi(m[3])
+	return hour < 24 && minute < 60 && second <= 60
+}
+
+func (p *tomlParser) parseArray() (interface{}, error) {
+	p.pos++
+	list := []interface{}{}
+
+	for {
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return list, nil
+		}
+
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+
+		if err := p.skipBlank(); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+			p.pos++
+			return list, nil
+		default:
+			if p.eof() {
+				return nil, p.errorf("unterminated array")
+			}
+			return nil, p.errorf("expected ',' or ']' in array, found %q", p.peek())
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (interface{}, error) {
+	p.pos++
+	obj := document.NewObject()
+	p.kinds[obj] = tomlDotted
+
+	p.skipWhitespace()
+	if p.peek() == '}' {
+		p.pos++
+		p.freeze(obj)
+		return obj, nil
+	}
+
+	for {
+		p.skipWhitespace()
+		if p.peek() == '}' {
+			return nil, p.errorf("trailing comma is not allowed in inline tables")
+		}
+		if err := p.parseKeyValue(obj); err != nil {
+			return nil, err
+		}
+
+		p.skipWhitespace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			p.freeze(obj)
+			return obj, nil
+		default:
+			if p.eof() {
+				return nil, p.errorf("unterminated inline table")
+			}
+			return nil, p.errorf("expected ',' or '}' in inline table, found %q", p.peek())
+		}
+	}
+}
+
+// freeze marca a tabela inline e suas subtabelas como fechadas.
+func (p *tomlParser) freeze(obj *document.Object) {
+	p.kinds[obj] = tomlInline
+	for _, key := range obj.Keys() {
+		value, _ := obj.Get(key)
+		if child, ok := value.(*document.Object); ok {
+			p.freeze(child)
+		}
+	}
+}
+
+func (p *tomlParser) parseBasicString() (string, error) {
+	start := p.pos
+	p.pos++
+	var sb strings.Builder
+
+	for {
+		if p.eof() {
+			return "", p.errorAt(start, "unterminated string")
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return sb.String(), nil
+		case c == '\\':
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case c == '\n' || c == '\r':
+			return "", p.errorAt(start, "unterminated string")
+		case (c < 0x20 && c != '\t') || c == 0x7f:
+			return "", p.errorf("control character %q must be escaped", c)
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) parseMultilineBasicString() (string, error) {
+	start := p.pos
+	p.pos += 3
+	p.skipNewline()
+	var sb strings.Builder
+
+	for {
+		if p.eof() {
+			return "", p.errorAt(start, "unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.rest(), `"""`) {
+			quotes := 3
+			for quotes < 5 && p.pos+quotes < len(p.src) && p.src[p.pos+quotes] == '"' {
+				quotes++
+			}
+			sb.WriteString(strings.Repeat(`"`, quotes-3))
+			p.pos += quotes
+			return sb.String(), nil
+		}
+
+		c := p.src[p.pos]
+		switch {
+		case c == '\\':
+			if p.lineEndingBackslash() {
+				continue
+			}
+			if err := p.parseEscape(&sb); err != nil {
+				return "", err
+			}
+		case c == '\n':
+			sb.WriteByte('\n')
+			p.pos++
+		case c == '\r':
+			if _, err := p.skipNewline(); err != nil {
+				return "", err
+			}
+			sb.WriteByte('\n')
+		case (c < 0x20 && c != '\t') || c == 0x7f:
+			return "", p.errorf("control character %q must be escaped", c)
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// lineEndingBackslash consome uma barra no fim da linha junto com todo o
+// espaço em branco seguinte, como define o TOML para strings multilinha.
+func (p *tomlParser) lineEndingBackslash() bool {
+	i := p.pos + 1
+	for i < len(p.src) && (p.src[i] == ' ' || p.src[i] == '\t') {
+		i++
+	}
+	if i < len(p.src) && p.src[i] == '\r' {
+		i++
+	}
+	if i >= len(p.src) || p.src[i] != '\n' {
+		return false
+	}
+	for i < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[i])) {
+		i++
+	}
+	p.pos = i
+	return true
+}
+
+func (p *tomlParser) parseEscape(sb *strings.Builder) error {
+	start := p.pos
+	p.pos++
+	if p.eof() {
+		return p.errorAt(start, "unterminated escape sequence")
+	}
+
+	c := p.src[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		sb.WriteByte('\b')
+	case 't':
+		sb.WriteByte('\t')
+	case 'n':
+		sb.WriteByte('\n')
+	case 'f':
+		sb.WriteByte('\f')
+	case 'r':
+		sb.WriteByte('\r')
+	case '"':
+		sb.WriteByte('"')
+	case '\\':
+		sb.WriteByte('\\')
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.src) {
+			return p.errorAt(start, "incomplete unicode escape")
+		}
+		code, err := strconv.ParseUint(p.src[p.pos:p.pos+size], 16, 32)
+		if err != nil || code > utf8.MaxRune || (code >= 0xD800 && code <= 0xDFFF) {
+			return p.errorAt(start, "invalid unicode escape \\%c%s", c, p.src[p.pos:p.pos+size])
+		}
+		p.pos += size
+		sb.WriteRune(rune(code))
+	default:
+		return p.errorAt(start, "invalid escape sequence \\%c", c)
+	}
+	return nil
+}
+
+func (p *tomlParser) parseLiteralString() (string, error) {
+	start := p.pos
+	p.pos++
+	for {
+		if p.eof() {
+			return "", p.errorAt(start, "unterminated string")
+		}
+		c := p.src[p.pos]
+		switch {
+		case c == '\'':
+			value := p.src[start+1 : p.pos]
+			p.pos++
+			return value, nil
+		case c == '\n' || c == '\r':
+			return "", p.errorAt(start, "unterminated string")
+		case (c < 0x20 && c != '\t') || c == 0x7f:
+			return "", p.errorf("control character %q is not allowed in literal strings", c)
+		}
+		p.pos++
+	}
+}
+
+func (p *tomlParser) parseMultilineLiteralString() (string, error) {
+	start := p.pos
+	p.pos += 3
+	p.skipNewline()
+	var sb strings.Builder
+
+	for {
+		if p.eof() {
+			return "", p.errorAt(start, "unterminated multi-line string")
+		}
+		if strings.HasPrefix(p.rest(), "'''") {
+			quotes := 3
+			for quotes < 5 && p.pos+quotes < len(p.src) && p.src[p.pos+quotes] == '\'' {
+				quotes++
+			}
+			sb.WriteString(strings.Repeat("'", quotes-3))
+			p.pos += quotes
+			return sb.String(), nil
+		}
+
+		c := p.src[p.pos]
+		switch {
+		case c == '\r':
+			if _, err := p.skipNewline(); err != nil {
+				return "", err
+			}
+			sb.WriteByte('\n')
+		case (c < 0x20 && c != '\t' && c != '\n') || c == 0x7f:
+			return "", p.errorf("control character %q is not allowed in literal strings", c)
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+}
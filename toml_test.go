@@ -0,0 +1,304 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cli-convert/document"
+)
+
+func TestParseToml(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name: "tables and arrays of tables",
+			input: `
+title = "TOML Example" # comentário
+
+[owner]
+name = "Tom"
+dob = 1979-05-27T07:32:00-08:00
+
+[database]
+ports = [ 8000, 8001, 8002 ]
+enabled = true
+
+[servers.alpha]
+ip = "10.0.0.1"
+
+[[fruits]]
+name = "apple"
+
+[fruits.physical]
+color = "red"
+
+[[fruits]]
+name = "banana"
+`,
+			expected: `{"title":"TOML Example","owner":{"name":"Tom","dob":"1979-05-27T07:32:00-08:00"},"database":{"ports":[8000,8001,8002],"enabled":true},"servers":{"alpha":{"ip":"10.0.0.1"}},"fruits":[{"name":"apple","physical":{"color":"red"}},{"name":"banana"}]}`,
+		},
+		{
+			name: "dotted keys and inline tables",
+			input: `
+name = { first = "Tom", last = "Preston-Werner" }
+site."google.com" = true
+physical.color = "orange"
+physical.shape = "round"
+points = [ { x = 1, y = 2 }, { x = 7, y = 8 } ]
+`,
+			expected: `{"name":{"first":"Tom","last":"Preston-Werner"},"site":{"google.com":true},"physical":{"color":"orange","shape":"round"},"points":[{"x":1,"y":2},{"x":7,"y":8}]}`,
+		},
+		{
+			name: "strings",
+			input: `basic = "tab\there \"quoted\" \u00e7"
+literal = 'C:\Users\nodejs'
+multi = """
+Roses are red
+Violets are blue"""
+folded = """\
+    The quick brown \
+    fox."""
+raw = '''
+I [dw]on't need \d{2} apples'''
+quotes = """Here are two quotation marks: "". Simple."""
+`,
+			expected: `{"basic":"tab\there \"quoted\" ç","literal":"C:\\Users\\nodejs","multi":"Roses are red\nViolets are blue","folded":"The quick brown fox.","raw":"I [dw]on't need \\d{2} apples","quotes":"Here are two quotation marks: \"\". Simple."}`,
+		},
+		{
+			name: "numbers",
+			input: `int = +99
+big = 1_000_000
+hex = 0xDEAD_beef
+oct = 0o755
+bin = 0b1101
+float = -3.1415
+exp = 5e+22
+frac = 6.626e-34
+`,
+			expected: `{"int":99,"big":1000000,"hex":3735928559,"oct":493,"bin":13,"float":-3.1415,"exp":5e+22,"frac":6.626e-34}`,
+		},
+		{
+			name: "floats keep their text",
+			input: `price = 1.50
+big = +1_000.250
+precise = 0.1000000000000000055511151231257827
+`,
+			expected: `{"price":1.50,"big":1000.250,"precise":0.1000000000000000055511151231257827}`,
+		},
+		{
+			name: "datetimes",
+			input: `odt = 1979-05-27 07:32:00.999999z
+ldt = 1979-05-27T07:32:00
+ld = 1979-05-27
+lt = 00:32:00.5
+`,
+			expected: `{"odt":"1979-05-27T07:32:00.999999Z","ldt":"1979-05-27T07:32:00","ld":"1979-05-27","lt":"00:32:00.5"}`,
+		},
+		{
+			name: "implicit table defined later",
+			input: `
+[x.y.z]
+a = 1
+[x]
+b = 2
+`,
+			expected: `{"x":{"y":{"z":{"a":1}},"b":2}}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			doc, err := parseToml(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("Error parsing TOML: %v", err)
+			}
+			output, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("Error encoding JSON: %v", err)
+			}
+			if string(output) != tc.expected {
+				t.Errorf("Unexpected result:\nExpected:\n%s\nGot:\n%s", tc.expected, output)
+			}
+		})
+	}
+}
+
+func TestParseTomlDateTimeKinds(t *testing.T) {
+	doc, err := parseToml(strings.NewReader("a = 1979-05-27T07:32:00Z\nb = 1979-05-27T07:32:00\nc = 1979-05-27\nd = 07:32:00\n"))
+	if err != nil {
+		t.Fatalf("Error parsing TOML: %v", err)
+	}
+
+	expected := map[string]document.DateTimeKind{
+		"a": document.OffsetDateTime,
+		"b": document.LocalDateTime,
+		"c": document.LocalDate,
+		"d": document.LocalTime,
+	}
+	for key, kind := range expected {
+		value, _ := doc.Get(key)
+		dt, ok := value.(document.DateTime)
+		if !ok || dt.Kind != kind {
+			t.Errorf("Key %s: expected datetime kind %d, got %#v", key, kind, value)
+		}
+	}
+}
+
+func TestParseTomlErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		line  int
+	}{
+		{"duplicate key", "a = 1\na = 2\n", 2},
+		{"table redefined", "[a]\nb = 1\n[a]\nc = 2\n", 3},
+		{"inline table extended", "a = { b = 1 }\na.c = 2\n", 2},
+		{"header over dotted keys", "a.b = 1\n[a]\n", 2},
+		{"array of tables over table", "[a]\n[[a]]\n", 2},
+		{"missing newline", "a = 1 b = 2\n", 1},
+		{"invalid escape", "a = \"\\x\"\n", 1},
+		{"unterminated string", "a = 1\nb = \"open\n", 2},
+		{"trailing comma in inline table", "a = { b = 1, }\n", 1},
+		{"leading zero", "a = 012\n", 1},
+		{"invalid date", "a = 2023-02-30\n", 1},
+		{"missing value", "a =\n", 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseToml(strings.NewReader(tc.input))
+			tomlErr, ok := err.(*tomlError)
+			if !ok {
+				t.Fatalf("Expected *tomlError, got %T (%v)", err, err)
+			}
+			if tomlErr.Line != tc.line {
+				t.Errorf("Expected error on line %d, got %v", tc.line, tomlErr)
+			}
+		})
+	}
+}
+
+func TestConvertJsonToToml(t *testing.T) {
+	jsonInput := `{
+		"title": "Exemplo",
+		"owner": {"name": "Tom", "tags": ["a", "b"], "address": {"city": "São Paulo"}},
+		"ports": [8000, 8001.5],
+		"id": 9007199254740993,
+		"price": 0.10,
+		"point": [{"x": 1}, {"x": 2, "meta": {"ok": true}}],
+		"matrix": [[1, 2], [3]],
+		"weird key": "v"
+	}`
+
+	expectedToml := `title = "Exemplo"
+ports = [8000, 8001.5]
+id = 9007199254740993
+price = 0.10
+matrix = [[1, 2], [3]]
+"weird key" = "v"
+
+[owner]
+name = "Tom"
+tags = ["a", "b"]
+
+[owner.address]
+city = "São Paulo"
+
+[[point]]
+x = 1
+
+[[point]]
+x = 2
+
+[point.meta]
+ok = true
+`
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("json", "toml", strings.NewReader(jsonInput), writer, convertOptions{}); err != nil {
+		t.Fatalf("Error converting JSON to TOML: %v", err)
+	}
+	if writer.String() != expectedToml {
+		t.Errorf("Unexpected TOML output:\nExpected:\n%s\nGot:\n%s", expectedToml, writer.String())
+	}
+
+	roundTrip, err := parseToml(strings.NewReader(writer.String()))
+	if err != nil {
+		t.Fatalf("Generated TOML does not parse: %v", err)
+	}
+	if owner, _ := roundTrip.Get("owner"); owner == nil {
+		t.Errorf("Round trip lost the owner table")
+	}
+}
+
+func TestConvertJsonToToml_MixedArray(t *testing.T) {
+	writer := new(bytes.Buffer)
+	if err := dispatchConversion("json", "toml", strings.NewReader(`{"a": [1, "x", {"b": true}, [2.5]]}`), writer, convertOptions{}); err != nil {
+		t.Fatalf("Error converting mixed array to TOML: %v", err)
+	}
+	if expected := "a = [1, \"x\", { b = true }, [2.5]]\n"; writer.String() != expected {
+		t.Errorf("Unexpected TOML output:\nExpected: %q\nGot:      %q", expected, writer.String())
+	}
+	if _, err := parseToml(strings.NewReader(writer.String())); err != nil {
+		t.Errorf("Generated TOML does not parse: %v", err)
+	}
+}
+
+func TestConvertJsonToToml_Unrepresentable(t *testing.T) {
+	cases := []struct {
+		input   string
+		message string
+	}{
+		{`[1, 2]`, "top-level value must be a table, got array"},
+		{`{"a": {"b": null}}`, "a.b: null values cannot be represented"},
+		{`{"a": [1, null]}`, "a.[1]: null values cannot be represented"},
+		{`{"a": {"id": 99999999999999999999}}`, "a.id: integer 99999999999999999999 is out of the 64-bit range"},
+	}
+
+	for _, tc := range cases {
+		err := dispatchConversion("json", "toml", strings.NewReader(tc.input), new(bytes.Buffer), convertOptions{})
+		if err == nil || !strings.Contains(err.Error(), tc.message) {
+			t.Errorf("Input %s: expected error containing %q, got %v", tc.input, tc.message, err)
+		}
+	}
+}
+
+func TestConvertTomlToJson_NonFiniteFloats(t *testing.T) {
+	input := "limit = inf\n[stats]\nvalues = [1.5, nan, -inf]\n"
+
+	for _, target := range []string{"json", "ndjson"} {
+		t.Run(target, func(t *testing.T) {
+			log := &lossyLog{}
+			writer := new(bytes.Buffer)
+			if err := dispatchConversion("toml", target, strings.NewReader(input), writer, convertOptions{Lossy: log}); err != nil {
+				t.Fatalf("Error converting TOML with inf/nan: %v", err)
+			}
+			compact := new(bytes.Buffer)
+			if err := json.Compact(compact, writer.Bytes()); err != nil {
+				t.Fatalf("Invalid JSON output: %v", err)
+			}
+			if expected := `{"limit":null,"stats":{"values":[1.5,null,null]}}`; compact.String() != expected {
+				t.Errorf("Unexpected output:\nExpected: %s\nGot:      %s", expected, compact.String())
+			}
+			expected := []lossyEvent{
+				{Path: "/limit", Reason: "+Inf has no JSON representation and is written as null"},
+				{Path: "/stats/values/1", Reason: "NaN has no JSON representation and is written as null"},
+				{Path: "/stats/values/2", Reason: "-Inf has no JSON representation and is written as null"},
+			}
+			if !reflect.DeepEqual(log.Events, expected) {
+				t.Errorf("Unexpected lossy events: %v", log.Events)
+			}
+		})
+	}
+
+	err := dispatchConversion("toml", "json", strings.NewReader(input), new(bytes.Buffer), convertOptions{Strict: true})
+	if !errors.Is(err, ErrLossyConversion) {
+		t.Errorf("Expected strict mode to reject inf/nan, got %v", err)
+	}
+}
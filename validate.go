@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"cli-convert/jsonschema"
+)
+
+// Códigos de saída do comando validate.
+const (
+	validateExitValid      = 0
+	validateExitInvalid    = 1
+	validateExitUnreadable = 2
+)
+
+// validationIssue é um problema encontrado no arquivo. Line, Column e Row
+// começam em 1; zero indica posição desconhecida. Path, nas violações de
+// schema, é o JSON Pointer do valor em forma de fragmento ("#/0/id"; "#" é
+// a raiz).
+type validationIssue struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Row     int    `json:"row,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+func (i validationIssue) String() string {
+	var position []string
+	if i.Path != "" {
+		position = append(position, i.Path)
+	}
+	if i.Row > 0 {
+		position = append(position, fmt.Sprintf("row %d", i.Row))
+	}
+	if i.Line > 0 {
+		position = append(position, fmt.Sprintf("line %d", i.Line))
+	}
+	if i.Column > 0 {
+		position = append(position, fmt.Sprintf("column %d", i.Column))
+	}
+	if len(position) == 0 {
+		return i.Message
+	}
+	return strings.Join(position, ", ") + ": " + i.Message
+}
+
+// validationReport é o resultado do comando validate no modo --report json.
+type validationReport struct {
+	File   string            `json:"file"`
+	Format string            `json:"format,omitempty"`
+	Valid  bool              `json:"valid"`
+	Issues []validationIssue `json:"issues"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// validateFile escolhe o validador do formato. O erro indica que o arquivo
+// não pôde ser lido; problemas no conteúdo voltam como issues.
+func validateFile(path string, formatName string, delimiter rune) ([]validationIssue, error) {
+	format, ok := lookupFormat(formatName)
+	if !ok || format.Reader == nil {
+		return nil, fmt.Errorf("unsupported format: %s", formatName)
+	}
+
+	switch format.Name {
+	case "json":
+		return validateFileJSON(path)
+	case "csv":
+		return validateFileCSV(path, delimiter)
+	case "tsv":
+		return validateFileTSV(path)
+	case "xml":
+		return validateFileXML(path)
+	case "yaml":
+		return validateFileYaml(path)
+	}
+	return validateWithReader(path, format, delimiter)
+}
+
+// loadSchema lê um JSON Schema em qualquer formato com leitor (JSON, YAML,
+// TOML...), escolhido pela extensão ou pelo conteúdo.
+func loadSchema(path string) (*jsonschema.Schema, error) {
+	tree, _, err := readFileTree(path, "", convertOptions{Delimiter: ','})
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %v", path, err)
+	}
+	schema, err := jsonschema.Compile(tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return schema, nil
+}
+
+// validateWithSchema roda o validador do formato e, com schemaPath,
+// confere também os dados contra o schema. Avisos estruturais (ex.: coluna
+// vazia no CSV) não impedem a checagem: ela só fica de fora quando o leitor
+// do formato não consegue montar a árvore, problema que o validador do
+// formato já aponta.
+func validateWithSchema(path string, formatName string, delimiter rune, schemaPath string) ([]validationIssue, error) {
+	issues, err := validateFile(path, formatName, delimiter)
+	if err != nil || schemaPath == "" {
+		return issues, err
+	}
+	schema, err := loadSchema(schemaPath)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := readValidationTree(path, formatName, delimiter)
+	if err != nil {
+		if len(issues) > 0 {
+			return issues, nil
+		}
+		return []validationIssue{issueFromError(err)}, nil
+	}
+	return append(issues, schemaIssues(schema, tree)...), nil
+}
+
+// readValidationTree lê o arquivo com o leitor do formato, com as mesmas
+// opções das conversões.
+func readValidationTree(path string, formatName string, delimiter rune) (interface{}, error) {
+	format, ok := lookupFormat(formatName)
+	if !ok || format.Reader == nil {
+		return nil, fmt.Errorf("unsupported format: %s", formatName)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+	defer file.Close()
+
+	input, _, err := decodeInput(bufio.NewReaderSize(file, detectPeekSize), encodingAuto)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	opts := convertOptions{Delimiter: delimiter, RootName: "root", AttrPrefix: "@"}
+	return format.Reader.Read(input, opts)
+}
+
+// schemaIssues converte as violações do schema em issues com o JSON Pointer
+// do valor.
+func schemaIssues(schema *jsonschema.Schema, tree interface{}) []validationIssue {
+	var issues []validationIssue
+	for _, e := range schema.Validate(tree) {
+		pointer := (&url.URL{Fragment: e.Path}).EscapedFragment()
+		issues = append(issues, validationIssue{Path: "#" + pointer, Message: e.Message})
+	}
+	return issues
+}
+
+// readValidationInput lê o arquivo inteiro. Arquivo ausente, diretório ou
+// erro de leitura são erros; arquivo vazio é um problema de conteúdo.
+func readValidationInput(path string) ([]byte, []validationIssue, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, errors.New("file not exist")
+		}
+		return nil, nil, fmt.Errorf("access error: %v", err)
+	}
+
+	if info.IsDir() {
+		return nil, nil, errors.New("is a directory")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error reading file: %v", err)
+	}
+
+	if len(data) == 0 {
+		return nil, []validationIssue{{Message: "file is empty"}}, nil
+	}
+	return decodeData(data), nil, nil
+}
+
+// validateWithReader valida formatos sem validador próprio passando o
+// arquivo pelo leitor do registro. Linhas NDJSON inválidas são todas
+// coletadas.
+func validateWithReader(path string, format *Format, delimiter rune) ([]validationIssue, error) {
+	data, issues, err := readValidationInput(path)
+	if err != nil || issues != nil {
+		return issues, err
+	}
+
+	log := &badLineLog{}
+	opts := convertOptions{Delimiter: delimiter, OnBadLine: badLineCollect, BadLines: log}
+	if _, err := format.Reader.Read(bytes.NewReader(data), opts); err != nil {
+		issues = append(issues, issueFromError(err))
+	}
+	for _, line := range log.Lines {
+		issues = append(issues, issueFromError(line.Err))
+	}
+	return issues, nil
+}
+
+// issueFromError aproveita a posição dos erros dos parsers do projeto.
+func issueFromError(err error) validationIssue {
+	var yamlErr *yamlError
+	var tomlErr *tomlError
+	var lineErr *ndjsonLineError
+	switch {
+	case errors.As(err, &yamlErr):
+		return validationIssue{Line: yamlErr.Line, Column: yamlErr.Column, Message: yamlErr.Message}
+	case errors.As(err, &tomlErr):
+		return validationIssue{Line: tomlErr.Line, Column: tomlErr.Column, Message: tomlErr.Message}
+	case errors.As(err, &lineErr):
+		return validationIssue{Line: lineErr.Line, Message: lineErr.Err.Error()}
+	}
+	return validationIssue{Message: err.Error()}
+}
+
+// positionAt converte um deslocamento em bytes em linha e coluna (1-based).
+func positionAt(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// skipSeparators avança offset sobre espaços e separadores, até o início do
+// próximo token.
+func skipSeparators(data []byte, offset int64, separators string) int64 {
+	for offset < int64(len(data)) && strings.IndexByte(separators, data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
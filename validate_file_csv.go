@@ -1,67 +1,83 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/csv"
 	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 )
 
-func validateFileCSV(path string, delimiter rune) error {
-	info, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("file not exist")
-		} else {
-			return fmt.Errorf("access error : %v", err)
-		}
-	}
-
-	if info.IsDir() {
-		return errors.New("is a directory")
-	}
-
-	if info.Size() == 0 {
-		return errors.New("file is empty")
+// validateFileCSV lê o CSV inteiro e lista cada linha com erro de sintaxe,
+// número de colunas diferente do cabeçalho ou célula vazia. Row conta os
+// registros a partir do cabeçalho (row 1).
+func validateFileCSV(path string, delimiter rune) ([]validationIssue, error) {
+	data, issues, err := readValidationInput(path)
+	if err != nil || issues != nil {
+		return issues, err
 	}
 
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	reader := csv.NewReader(file)
+	reader := csv.NewReader(bytes.NewReader(data))
 	reader.Comma = delimiter
 	reader.FieldsPerRecord = -1
+	return validateRecords(reader, delimiter)
+}
 
-	records, err := reader.ReadAll()
-	if err != nil {
-		return fmt.Errorf("failed to parse CSV: %v", err)
-	}
-
-	if len(records) == 0 {
-		return errors.New("CSV file is empty")
+// validateFileTSV faz as mesmas verificações do CSV separando as linhas
+// com o leitor do TSV, que não trata aspas.
+func validateFileTSV(path string) ([]validationIssue, error) {
+	data, issues, err := readValidationInput(path)
+	if err != nil || issues != nil {
+		return issues, err
 	}
+	return validateRecords(newTsvReader(bufio.NewReader(bytes.NewReader(data)), 0), '\t')
+}
 
-	expectedCols := len(records[0])
+func validateRecords(reader recordSource, delimiter rune) ([]validationIssue, error) {
+	var issues []validationIssue
+	var header []string
+	row := 0
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		row++
+		if err != nil {
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, fmt.Errorf("error reading file: %v", err)
+			}
+			issues = append(issues, validationIssue{Row: row, Line: parseErr.Line, Column: parseErr.Column, Message: parseErr.Err.Error()})
+			continue
+		}
 
-	if expectedCols == 1 {
-		return fmt.Errorf("CSV appears to have only 1 column. Wrong delimiter? (current: '%c')", delimiter)
-	}
+		if header == nil {
+			header = record
+			if len(header) == 1 {
+				issues = append(issues, validationIssue{Row: row, Line: 1, Message: fmt.Sprintf("CSV appears to have only 1 column. Wrong delimiter? (current: '%c')", delimiter)})
+			}
+			continue
+		}
 
-	for i, record := range records[1:] {
-		if len(record) != expectedCols {
-			return fmt.Errorf("row %d: expected %d columns, but got %d", i+2, expectedCols, len(record))
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			issues = append(issues, validationIssue{Row: row, Line: line, Message: fmt.Sprintf("expected %d columns, but got %d", len(header), len(record))})
+			continue
 		}
 
 		for j, value := range record {
 			if strings.TrimSpace(value) == "" {
-				return fmt.Errorf("row %d: column '%s' is empty", i+2, records[0][j])
+				fieldLine, column := reader.FieldPos(j)
+				issues = append(issues, validationIssue{Row: row, Line: fieldLine, Column: column, Message: fmt.Sprintf("column '%s' is empty", header[j])})
 			}
 		}
 	}
 
-	return nil
+	if header == nil && len(issues) == 0 {
+		issues = append(issues, validationIssue{Message: "CSV file is empty"})
+	}
+	return issues, nil
 }
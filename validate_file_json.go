@@ -1,44 +1,97 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"os"
 )
 
-func validateFileJSON(path string) error {
-	info, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("file not exist")
-		} else {
-			return fmt.Errorf("access error: %v", err)
-		}
-	}
+// jsonValidationFrame acompanha um objeto ou array aberto durante a
+// validação.
+type jsonValidationFrame struct {
+	object  bool
+	keys    map[string]bool
+	wantKey bool
+}
 
-	if info.IsDir() {
-		return errors.New("is a directory")
+// validateFileJSON percorre o documento token a token. Além do erro de
+// sintaxe (que interrompe a leitura), aponta chaves duplicadas e dados após
+// o valor de nível superior.
+func validateFileJSON(path string) ([]validationIssue, error) {
+	data, issues, err := readValidationInput(path)
+	if err != nil || issues != nil {
+		return issues, err
 	}
 
-	if info.Size() == 0 {
-		return errors.New("file is empty")
-	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var stack []*jsonValidationFrame
+	values := 0
 
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			errOffset := decoder.InputOffset()
+			var syntax *json.SyntaxError
+			if errors.As(err, &syntax) && syntax.Offset > 0 {
+				// Offset conta os bytes lidos, incluindo o caractere inválido.
+				errOffset = syntax.Offset - 1
+			}
+			line, column := positionAt(data, errOffset)
+			issues = append(issues, validationIssue{Line: line, Column: column, Message: err.Error()})
+			return issues, nil
+		}
+
+		start := skipSeparators(data, offset, " \t\r\n,:")
+		if len(stack) == 0 {
+			values++
+			if values > 1 {
+				line, column := positionAt(data, start)
+				issues = append(issues, validationIssue{Line: line, Column: column, Message: "unexpected data after top-level value"})
+				return issues, nil
+			}
+		}
 
-	read, err := io.ReadAll(file)
-	if err != nil {
-		return err
+		var top *jsonValidationFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				stack = append(stack, &jsonValidationFrame{object: t == '{', keys: make(map[string]bool), wantKey: true})
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				if len(stack) > 0 && stack[len(stack)-1].object {
+					stack[len(stack)-1].wantKey = true
+				}
+			}
+		default:
+			if top != nil && top.object && top.wantKey {
+				key := t.(string)
+				if top.keys[key] {
+					line, column := positionAt(data, start)
+					issues = append(issues, validationIssue{Line: line, Column: column, Message: fmt.Sprintf("duplicate key %q", key)})
+				}
+				top.keys[key] = true
+				top.wantKey = false
+				continue
+			}
+			if top != nil && top.object {
+				top.wantKey = true
+			}
+		}
 	}
 
-	if !json.Valid(read) {
-		return errors.New("invalid json")
+	if values == 0 {
+		issues = append(issues, validationIssue{Message: "no JSON value found"})
 	}
-	return nil
+	return issues, nil
 }
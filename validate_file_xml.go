@@ -1,44 +1,65 @@
 package main
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
-	"fmt"
 	"io"
-	"os"
+	"strings"
 )
 
-func validateFileXML(path string) error {
-	info, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return fmt.Errorf("file not exist")
-		} else {
-			return fmt.Errorf("access error : %v", err)
-		}
+// validateFileXML verifica se o documento é XML bem formado com um único
+// elemento raiz. Erros de sintaxe interrompem a leitura; raízes extras e
+// texto fora da raiz são todos listados.
+func validateFileXML(path string) ([]validationIssue, error) {
+	data, issues, err := readValidationInput(path)
+	if err != nil || issues != nil {
+		return issues, err
 	}
 
-	if info.IsDir() {
-		return errors.New("is a directory")
-	}
+	decoder := newXmlDecoder(bytes.NewReader(data))
+	depth := 0
+	roots := 0
 
-	if info.Size() == 0 {
-		return errors.New("file is empty")
-	}
+	for {
+		offset := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var syntax *xml.SyntaxError
+			if errors.As(err, &syntax) {
+				issues = append(issues, validationIssue{Line: syntax.Line, Message: syntax.Msg})
+			} else {
+				line, column := positionAt(data, decoder.InputOffset())
+				issues = append(issues, validationIssue{Line: line, Column: column, Message: err.Error()})
+			}
+			return issues, nil
+		}
 
-	file, err := os.Open(path)
-	if err != nil {
-		return err
+		switch t := token.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+				if roots > 1 {
+					line, column := positionAt(data, skipSeparators(data, offset, " \t\r\n"))
+					issues = append(issues, validationIssue{Line: line, Column: column, Message: "multiple root elements (<" + t.Name.Local + ">)"})
+				}
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && strings.TrimSpace(string(t)) != "" {
+				line, column := positionAt(data, skipSeparators(data, offset, " \t\r\n"))
+				issues = append(issues, validationIssue{Line: line, Column: column, Message: "text outside the root element"})
+			}
+		}
 	}
-	defer file.Close()
 
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return fmt.Errorf("error reading file: %v", err)
-	}
-	var v interface{}
-	if err := xml.Unmarshal(data, &v); err != nil {
-		return fmt.Errorf("invalid XML format: %v", err)
+	if roots == 0 {
+		issues = append(issues, validationIssue{Message: "no root element found"})
 	}
-	return nil
+	return issues, nil
 }
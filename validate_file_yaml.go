@@ -1,34 +1,19 @@
 package main
 
 import (
-	"errors"
-	"fmt"
-	"os"
+	"bytes"
 )
 
-func validateFileYaml(path string) error {
-	info, err := os.Stat(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return errors.New("file not exist")
-		} else {
-			return fmt.Errorf("access error : %v", err)
-		}
+// validateFileYaml analisa o stream YAML inteiro (todos os documentos) com o
+// parser do projeto.
+func validateFileYaml(path string) ([]validationIssue, error) {
+	data, issues, err := readValidationInput(path)
+	if err != nil || issues != nil {
+		return issues, err
 	}
 
-	if info.IsDir() {
-		return errors.New("is a directory")
+	if _, err := parseYamlDocuments(bytes.NewReader(data), nil); err != nil {
+		issues = append(issues, issueFromError(err))
 	}
-
-	if info.Size() == 0 {
-		return errors.New("file is empty")
-	}
-
-	file, err := os.Open(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	return nil
+	return issues, nil
 }
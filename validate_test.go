@@ -0,0 +1,195 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeValidationFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		format   string
+		expected []validationIssue
+	}{
+		{"valid json", "ok.json", formatSamples["json"], "json", nil},
+		{"valid csv", "ok.csv", formatSamples["csv"], "csv", nil},
+		{"valid xml", "ok.xml", formatSamples["xml"], "xml", nil},
+		{"valid yaml", "ok.yaml", formatSamples["yaml"], "yaml", nil},
+		{"tsv has no quoting", "ok.tsv", "id\tnote\n1\tdiz \"oi\n", "tsv", nil},
+		{"empty file", "empty.json", "", "json", []validationIssue{{Message: "file is empty"}}},
+		{
+			"json duplicate key and syntax error", "bad.json", "{\"a\": 1,\n \"a\": 2,\n \"b\": }", "json",
+			[]validationIssue{
+				{Line: 2, Column: 2, Message: `duplicate key "a"`},
+				{Line: 3, Column: 7, Message: "missing value after object key"},
+			},
+		},
+		{
+			"json trailing value", "two.json", "{}\n[]", "json",
+			[]validationIssue{{Line: 2, Column: 1, Message: "unexpected data after top-level value"}},
+		},
+		{
+			"csv reports every row", "bad.csv", "id,name\n1,\n2\n3,Bob\n4,\"x\"y\n", "csv",
+			[]validationIssue{
+				{Row: 2, Line: 2, Column: 3, Message: "column 'name' is empty"},
+				{Row: 3, Line: 3, Message: "expected 2 columns, but got 1"},
+				{Row: 5, Line: 5, Column: 5, Message: `extraneous or missing " in quoted-field`},
+			},
+		},
+		{
+			"xml extra roots and stray text", "bad.xml", "<a/>\ntext\n<b/>\n<c/>", "xml",
+			[]validationIssue{
+				{Line: 2, Column: 1, Message: "text outside the root element"},
+				{Line: 3, Column: 1, Message: "multiple root elements (<b>)"},
+				{Line: 4, Column: 1, Message: "multiple root elements (<c>)"},
+			},
+		},
+		{
+			"xml syntax error", "broken.xml", "<a>\n<b></a>", "xml",
+			[]validationIssue{{Line: 2, Message: "element <b> closed by </a>"}},
+		},
+		{
+			"yaml syntax error", "bad.yaml", "a: 1\na: 2\n", "yaml",
+			[]validationIssue{{Line: 2, Column: 1, Message: `duplicate mapping key "a"`}},
+		},
+		{
+			"ndjson reports every line", "bad.jsonl", "{\"a\": 1}\nnope\n{\"a\": 2}\n[\n", "ndjson",
+			[]validationIssue{
+				{Line: 2, Message: "invalid character 'o' in literal null (expecting 'u')"},
+				{Line: 4, Message: "unexpected end of JSON input"},
+			},
+		},
+		{
+			"toml uses parser position", "bad.toml", "a = 1\na = 2\n", "toml",
+			[]validationIssue{{Line: 2, Column: 1, Message: `duplicate key "a"`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeValidationFile(t, tt.file, tt.content)
+			issues, err := validateFile(path, tt.format, ',')
+			if err != nil {
+				t.Fatalf("Unexpected read error: %v", err)
+			}
+			if !reflect.DeepEqual(issues, tt.expected) {
+				t.Errorf("Unexpected issues:\nExpected: %v\nGot:      %v", tt.expected, issues)
+			}
+		})
+	}
+}
+
+func TestValidateFile_Unreadable(t *testing.T) {
+	if _, err := validateFile(filepath.Join(t.TempDir(), "missing.json"), "json", ','); err == nil {
+		t.Error("Expected error for missing file")
+	}
+	if _, err := validateFile(t.TempDir(), "csv", ','); err == nil {
+		t.Error("Expected error for directory")
+	}
+	if _, err := validateFile(writeValidationFile(t, "a.bin", "x"), "parquet", ','); err == nil {
+		t.Error("Expected error for unsupported format")
+	}
+}
+
+func TestValidateWithSchema_Tree(t *testing.T) {
+	schemaPath := writeValidationFile(t, "users.schema.yaml", `type: array
+items:
+  type: object
+  required: [id, name]
+  additionalProperties: false
+  properties:
+    id: {type: integer, minimum: 1}
+    name: {type: string}
+    "a/b": {type: boolean}
+`)
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		format   string
+		expected []validationIssue
+	}{
+		{"valid csv", "ok.csv", "id,name\n1,Alice\n", "csv", nil},
+		{
+			"csv rows use the converter tree", "bad.csv", "id,name,a/b\n0,Alice,true\n2,Bob,maybe\n", "csv",
+			[]validationIssue{
+				{Path: "#/0/id", Message: "0 is less than minimum 1"},
+				{Path: "#/1/a~1b", Message: "expected boolean, got string"},
+			},
+		},
+		{
+			"root-level violation", "obj.json", `{"id": 1}`, "json",
+			[]validationIssue{{Path: "#", Message: "expected array, got object"}},
+		},
+		{
+			"yaml data", "users.yaml", "- id: 1\n  name: Alice\n  extra: x\n", "yaml",
+			[]validationIssue{{Path: "#/0/extra", Message: `additional property "extra" is not allowed`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeValidationFile(t, tt.file, tt.content)
+			issues, err := validateWithSchema(path, tt.format, ',', schemaPath)
+			if err != nil {
+				t.Fatalf("Unexpected read error: %v", err)
+			}
+			if !reflect.DeepEqual(issues, tt.expected) {
+				t.Errorf("Unexpected issues:\nExpected: %v\nGot:      %v", tt.expected, issues)
+			}
+		})
+	}
+
+	if _, err := loadSchema(writeValidationFile(t, "bad.schema.json", `{"type": "text"}`)); err == nil {
+		t.Error("Expected error for invalid schema")
+	}
+}
+
+func TestValidateWithSchema(t *testing.T) {
+	schemaPath := writeValidationFile(t, "ids.schema.json", `{"type": "array", "items": {"properties": {"id": {"type": "integer"}}}}`)
+
+	tests := []struct {
+		name     string
+		file     string
+		content  string
+		expected []validationIssue
+	}{
+		{
+			"structural warnings keep schema errors", "warn.csv", "id,name\nx,\n",
+			[]validationIssue{
+				{Row: 2, Line: 2, Column: 3, Message: "column 'name' is empty"},
+				{Path: "#/0/id", Message: "expected integer, got string"},
+			},
+		},
+		{
+			"unparseable file reports only the syntax error", "broken.csv", "id,name\n1,\"x\"y\n",
+			[]validationIssue{{Row: 2, Line: 2, Column: 5, Message: `extraneous or missing " in quoted-field`}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeValidationFile(t, tt.file, tt.content)
+			issues, err := validateWithSchema(path, "csv", ',', schemaPath)
+			if err != nil {
+				t.Fatalf("Unexpected read error: %v", err)
+			}
+			if !reflect.DeepEqual(issues, tt.expected) {
+				t.Errorf("Unexpected issues:\nExpected: %v\nGot:      %v", tt.expected, issues)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"cli-convert/document"
+)
+
+// buildXlsx monta um pacote xlsx com pares nome/conteúdo, na ordem dada.
+func buildXlsx(parts ...string) []byte {
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+	for i := 0; i+1 < len(parts); i += 2 {
+		writer, _ := archive.Create(parts[i])
+		writer.Write([]byte(parts[i+1]))
+	}
+	archive.Close()
+	return buf.Bytes()
+}
+
+// sampleXlsxParts descreve uma pasta de trabalho como o Excel a grava: textos
+// em sharedStrings.xml, datas como números com estilo de data e células
+// vazias omitidas.
+var sampleXlsxParts = []string{
+	"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Users" sheetId="1" r:id="rId1"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+	"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="/xl/worksheets/sheet2.xml"/></Relationships>`,
+	"xl/sharedStrings.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>id</t></si><si><t>name</t></si><si><t>joined</t></si><si><t>active</t></si>
+<si><r><t>Ali</t></r><r><t>ce</t></r></si><si><t>Bob</t></si><si><t>text</t></si></sst>`,
+	"xl/styles.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts><numFmt numFmtId="164" formatCode="dd/mm/yyyy\ hh:mm"/></numFmts>
+<cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/></cellXfs></styleSheet>`,
+	"xl/worksheets/sheet1.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>3</v></c></row>
+<row r="2"><c r="A2"><v>1</v></c><c r="B2" t="s"><v>4</v></c><c r="C2" s="1"><v>45000</v></c><c r="D2" t="b"><v>1</v></c></row>
+<row r="4"><c r="A4"><v>2.5</v></c><c r="B4" t="s"><v>5</v></c><c r="C4" s="2"><v>45000.75</v></c></row>
+</sheetData></worksheet>`,
+	"xl/worksheets/sheet2.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>6</v></c></row>
+<row r="2"><c r="A2" t="inlineStr"><is><t>hello</t></is></c></row>
+</sheetData></worksheet>`,
+}
+
+func init() {
+	formatSamples["xlsx"] = string(buildXlsx(sampleXlsxParts...))
+}
+
+func TestReadXlsx(t *testing.T) {
+	workbook := buildXlsx(sampleXlsxParts...)
+
+	tests := []struct {
+		name     string
+		sheet    string
+		expected string
+	}{
+		{"first sheet", "", `[{"id":1,"name":"Alice","joined":"2023-03-15","active":true},{"id":2.5,"name":"Bob","joined":"2023-03-15T18:00:00","active":null}]`},
+		{"by name", "Notes", `[{"text":"hello"}]`},
+		{"by index", "2", `[{"text":"hello"}]`},
+		{"all sheets", "*", `{"Users":[{"id":1,"name":"Alice","joined":"2023-03-15","active":true},{"id":2.5,"name":"Bob","joined":"2023-03-15T18:00:00","active":null}],"Notes":[{"text":"hello"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := xlsxFormat{}.Read(bytes.NewReader(workbook), convertOptions{Sheet: tt.sheet})
+			if err != nil {
+				t.Fatalf("Error reading xlsx: %v", err)
+			}
+			got, _ := json.Marshal(data)
+			if string(got) != tt.expected {
+				t.Errorf("Unexpected records:\nExpected: %s\nGot:      %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadXlsx_DateCellKinds(t *testing.T) {
+	data, err := xlsxFormat{}.Read(bytes.NewReader(buildXlsx(sampleXlsxParts...)), convertOptions{})
+	if err != nil {
+		t.Fatalf("Error reading xlsx: %v", err)
+	}
+	first := data.([]interface{})[0].(*document.Object)
+	joined, _ := first.Get("joined")
+	if value, ok := joined.(document.DateTime); !ok || value.Kind != document.LocalDate {
+		t.Errorf("Expected joined to be a local date, got %#v", joined)
+	}
+}
+
+func TestReadXlsx_Errors(t *testing.T) {
+	workbook := buildXlsx(sampleXlsxParts...)
+
+	tests := []struct {
+		name  string
+		input []byte
+		sheet string
+		want  string
+	}{
+		{"unknown sheet", workbook, "Sales", `sheet "Sales" not found (available: Users, Notes)`},
+		{"index out of range", workbook, "3", "sheet index 3 out of range"},
+		{"not a zip", []byte("id,name\n"), "", "not a valid workbook"},
+		{"missing workbook", buildXlsx("word/document.xml", "<document/>"), "", "missing part xl/workbook.xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := xlsxFormat{}.Read(bytes.NewReader(tt.input), convertOptions{Sheet: tt.sheet})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConvertJsonToXlsx_RoundTrip(t *testing.T) {
+	input := `{"users": [{"id": 1, "name": "Alice & Co", "tags": ["a", "b"]}, {"id": 2, "active": false}], "orders/2024": [{"total": 10.5}]}`
+
+	workbook := new(bytes.Buffer)
+	if err := dispatchConversion("json", "xlsx", strings.NewReader(input), workbook, convertOptions{}); err != nil {
+		t.Fatalf("Error converting JSON to XLSX: %v", err)
+	}
+
+	output := new(bytes.Buffer)
+	if err := dispatchConversion("xlsx", "json", bytes.NewReader(workbook.Bytes()), output, convertOptions{Sheet: "*"}); err != nil {
+		t.Fatalf("Error converting XLSX to JSON: %v", err)
+	}
+
+	var compact bytes.Buffer
+	json.Compact(&compact, output.Bytes())
+	expected := `{"users":[{"id":1,"name":"Alice \u0026 Co","tags":"a | b","active":null},{"id":2,"name":null,"tags":null,"active":false}],"orders_2024":[{"total":10.5}]}`
+	if compact.String() != expected {
+		t.Errorf("Unexpected round trip:\nExpected: %s\nGot:      %s", expected, compact.String())
+	}
+}
+
+func TestConvertJsonToXlsx_ExactNumbers(t *testing.T) {
+	input := `[{"id": 9007199254740993, "small": 42, "price": 0.10, "ratio": 0.30000000000000004}]`
+
+	workbook := new(bytes.Buffer)
+	if err := dispatchConversion("json", "xlsx", strings.NewReader(input), workbook, convertOptions{}); err != nil {
+		t.Fatalf("Error converting JSON to XLSX: %v", err)
+	}
+	output := new(bytes.Buffer)
+	if err := dispatchConversion("xlsx", "ndjson", bytes.NewReader(workbook.Bytes()), output, convertOptions{}); err != nil {
+		t.Fatalf("Error converting XLSX to NDJSON: %v", err)
+	}
+
+	// O id não cabe em um double e vira texto; os decimais voltam com o
+	// texto gravado na planilha.
+	expected := `{"id":"9007199254740993","small":42,"price":0.10,"ratio":0.30000000000000004}` + "\n"
+	if output.String() != expected {
+		t.Errorf("Unexpected round trip:\nExpected: %s\nGot:      %s", expected, output.String())
+	}
+}
+
+func TestWriteXlsx_DateTimeCells(t *testing.T) {
+	row := document.NewObject()
+	row.Set("day", document.DateTime{Kind: document.LocalDate, Text: "2023-03-15"})
+	row.Set("at", document.DateTime{Kind: document.LocalDateTime, Text: "2023-03-15T18:00:00"})
+	row.Set("time", document.DateTime{Kind: document.LocalTime, Text: "06:30:00"})
+
+	workbook := new(bytes.Buffer)
+	if err := writeXlsx([]interface{}{row}, workbook, "", nil); err != nil {
+		t.Fatalf("Error writing xlsx: %v", err)
+	}
+	data, err := xlsxFormat{}.Read(bytes.NewReader(workbook.Bytes()), convertOptions{})
+	if err != nil {
+		t.Fatalf("Error reading xlsx: %v", err)
+	}
+
+	got, _ := json.Marshal(data)
+	expected := `[{"day":"2023-03-15","at":"2023-03-15T18:00:00","time":"06:30:00"}]`
+	if string(got) != expected {
+		t.Errorf("Unexpected dates:\nExpected: %s\nGot:      %s", expected, got)
+	}
+}
+
+func TestWriteXlsx_OffsetDateTimeIsLossy(t *testing.T) {
+	row := document.NewObject()
+	row.Set("at", document.DateTime{Kind: document.OffsetDateTime, Text: "2023-03-15T18:00:00-03:00"})
+
+	lossy := &lossyLog{}
+	if err := writeXlsx([]interface{}{row}, new(bytes.Buffer), "", lossy); err != nil {
+		t.Fatalf("Error writing xlsx: %v", err)
+	}
+	expected := []lossyEvent{{Path: "/0/at", Reason: "time zone offset of 2023-03-15T18:00:00-03:00 dropped (xlsx dates have no time zone)"}}
+	if !reflect.DeepEqual(lossy.Events, expected) {
+		t.Errorf("Unexpected lossy events:\nExpected: %v\nGot:      %v", expected, lossy.Events)
+	}
+}
+
+func TestReadXlsx_DuplicateHeaders(t *testing.T) {
+	parts := append([]string(nil), sampleXlsxParts[:6]...)
+	parts = append(parts, "xl/worksheets/sheet1.xml", `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>id</t></is></c><c r="B1" t="inlineStr"><is><t>id</t></is></c><c r="D1" t="inlineStr"><is><t>col3</t></is></c></row>
+<row r="2"><c r="A2"><v>1</v></c><c r="B2"><v>2</v></c><c r="C2"><v>3</v></c><c r="D2"><v>4</v></c><c r="E2"><v>5</v></c></row>
+</sheetData></worksheet>`)
+
+	data, err := xlsxFormat{}.Read(bytes.NewReader(buildXlsx(parts...)), convertOptions{})
+	if err != nil {
+		t.Fatalf("Error reading xlsx: %v", err)
+	}
+	got, _ := json.Marshal(data)
+	if expected := `[{"id":1,"id_2":2,"col3_2":3,"col3":4,"col5":5}]`; string(got) != expected {
+		t.Errorf("Unexpected records:\nExpected: %s\nGot:      %s", expected, got)
+	}
+}
+
+func TestXlsxDateFormat(t *testing.T) {
+	tests := []struct {
+		id     int
+		code   string
+		kind   document.DateTimeKind
+		isDate bool
+	}{
+		{14, "", document.LocalDate, true},
+		{22, "", document.LocalDateTime, true},
+		{21, "", document.LocalTime, true},
+		{0, "", 0, false},
+		{164, "0.00%", 0, false},
+		{164, "#,##0.00", 0, false},
+		{164, "yyyy-mm-dd", document.LocalDate, true},
+		{164, `[$-416]mmm\-yy`, document.LocalDate, true},
+		{164, "[h]:mm:ss", document.LocalTime, true},
+		{164, `"day" 0`, 0, false},
+		{164, "dd/mm/yyyy hh:mm", document.LocalDateTime, true},
+	}
+
+	for _, tt := range tests {
+		kind, ok := xlsxDateFormat(tt.id, tt.code)
+		if ok != tt.isDate || kind != tt.kind {
+			t.Errorf("xlsxDateFormat(%d, %q) = %v, %v; expected %v, %v", tt.id, tt.code, kind, ok, tt.kind, tt.isDate)
+		}
+	}
+}
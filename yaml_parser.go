@@ -0,0 +1,1426 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"cli-convert/document"
+)
+
+// Parser YAML 1.2 usado por parseYamlToInterface.
+//
+// A entrada é lida em dois passos: o parser monta uma árvore de yamlNode
+// (preservando estilo, tags, âncoras e posição de cada nó) e o resolver
+// converte essa árvore nos tipos genéricos usados pelos conversores.
+
+type yamlNodeKind int
+
+const (
+	yamlScalar yamlNodeKind = iota
+	yamlMapping
+	yamlSequence
+	yamlAlias
+)
+
+// yamlContext indica onde um nó em bloco aparece, o que define se coleções
+// em bloco podem começar na mesma linha.
+type yamlContext int
+
+const (
+	yamlDocumentCtx yamlContext = iota
+	yamlSequenceCtx
+	yamlMappingCtx
+	yamlExplicitCtx
+)
+
+const yamlCoreTagPrefix = "tag:yaml.org,2002:"
+
+// yamlMaxAliasExpansion limita a quantidade de nós gerados por aliases,
+// protegendo contra documentos do tipo "billion laughs".
+const yamlMaxAliasExpansion = 1000000
+
+type yamlNode struct {
+	kind     yamlNodeKind
+	tag      string
+	anchor   string
+	value    string
+	plain    bool
+	children []*yamlNode
+	alias    *yamlNode
+	line     int
+	column   int
+}
+
+// yamlError descreve um erro de sintaxe com a posição (1-based) onde ocorreu.
+type yamlError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *yamlError) Error() string {
+	return fmt.Sprintf("yaml: line %d, column %d: %s", e.Line, e.Column, e.Message)
+}
+
+type yamlMark struct {
+	pos    int
+	line   int
+	column int
+}
+
+type yamlProperties struct {
+	anchor string
+	tag    string
+}
+
+func (p yamlProperties) empty() bool {
+	return p.anchor == "" && p.tag == ""
+}
+
+type yamlParser struct {
+	src     []rune
+	pos     int
+	line    int
+	column  int
+	anchors map[string]*yamlNode
+}
+
+// parseYamlDocuments lê um stream YAML completo e retorna um valor por
+// documento. types decide o tipo dos escalares simples sem tag; o modo
+// aggressive segue o schema core do YAML 1.2.
+func parseYamlDocuments(input io.Reader, types *typeInference) ([]interface{}, error) {
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read input file: %v", err)
+	}
+
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	parser := &yamlParser{src: []rune(text), line: 1}
+	nodes, err := parser.parseStream()
+	if err != nil {
+		return nil, err
+	}
+
+	docs := make([]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		resolver := &yamlResolver{types: types}
+		value, err := resolver.resolve(node)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, value)
+	}
+	return docs, nil
+}
+
+// ──────────────────────────────────────────────
+//  Navegação na entrada
+// ──────────────────────────────────────────────
+
+func (p *yamlParser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *yamlParser) peek() rune {
+	return p.at(0)
+}
+
+func (p *yamlParser) at(offset int) rune {
+	if p.pos+offset >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos+offset]
+}
+
+func (p *yamlParser) advance() {
+	if p.eof() {
+		return
+	}
+	if p.src[p.pos] == '\n' {
+		p.line++
+		p.column = 0
+	} else {
+		p.column++
+	}
+	p.pos++
+}
+
+func (p *yamlParser) mark() yamlMark {
+	return yamlMark{pos: p.pos, line: p.line, column: p.column}
+}
+
+func (p *yamlParser) reset(m yamlMark) {
+	p.pos, p.line, p.column = m.pos, m.line, m.column
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return p.errorAt(p.mark(), format, args...)
+}
+
+func (p *yamlParser) errorAt(m yamlMark, format string, args ...interface{}) error {
+	return &yamlError{Line: m.line, Column: m.column + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+func isYamlBlank(r rune) bool {
+	return r == ' ' || r == '\t'
+}
+
+// isYamlSeparator reporta espaço, quebra de linha ou fim da entrada (at
+// retorna 0 no fim).
+func isYamlSeparator(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == 0
+}
+
+func isYamlFlowIndicator(r rune) bool {
+	return r == ',' || r == '[' || r == ']' || r == '{' || r == '}'
+}
+
+func (p *yamlParser) skipBlanks() {
+	for isYamlBlank(p.peek()) {
+		p.advance()
+	}
+}
+
+// skipInline pula espaços e um eventual comentário até o fim da linha.
+func (p *yamlParser) skipInline() {
+	p.skipBlanks()
+	if p.peek() == '#' && (p.pos == 0 || isYamlSeparator(p.src[p.pos-1])) {
+		for !p.eof() && p.peek() != '\n' {
+			p.advance()
+		}
+	}
+}
+
+// skipToNextContent avança até o próximo caractere significativo, pulando
+// comentários e linhas vazias. Tabs não podem ser usados como indentação.
+func (p *yamlParser) skipToNextContent() error {
+	for {
+		p.skipInline()
+		if p.eof() || p.peek() != '\n' {
+			return nil
+		}
+		p.advance()
+		for p.peek() == ' ' {
+			p.advance()
+		}
+		if p.peek() == '\t' {
+			m := p.mark()
+			p.skipBlanks()
+			if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+				return p.errorAt(m, "found a tab character where an indentation space is expected")
+			}
+		}
+	}
+}
+
+func (p *yamlParser) atLineStart() bool {
+	i := p.pos - 1
+	for i >= 0 && p.src[i] == ' ' {
+		i--
+	}
+	return i < 0 || p.src[i] == '\n'
+}
+
+func (p *yamlParser) isDocumentMarker() bool {
+	if p.column != 0 || p.pos+3 > len(p.src) {
+		return false
+	}
+	marker := string(p.src[p.pos : p.pos+3])
+	return (marker == "---" || marker == "...") && isYamlSeparator(p.at(3))
+}
+
+func (p *yamlParser) atSequenceEntry() bool {
+	return p.peek() == '-' && isYamlSeparator(p.at(1)) && !p.isDocumentMarker()
+}
+
+// endOfEntry garante que nada além de comentários sobrou na linha do nó
+// recém-lido e posiciona o parser no próximo conteúdo.
+func (p *yamlParser) endOfEntry() error {
+	if err := p.skipToNextContent(); err != nil {
+		return err
+	}
+	if !p.eof() && !p.atLineStart() {
+		return p.errorf("unexpected %q after value", p.peek())
+	}
+	return nil
+}
+
+// ──────────────────────────────────────────────
+//  Documentos e nós em bloco
+// ──────────────────────────────────────────────
+
+func (p *yamlParser) parseStream() ([]*yamlNode, error) {
+	var docs []*yamlNode
+
+	for {
+		if err := p.skipToNextContent(); err != nil {
+			return nil, err
+		}
+
+		sawDirective := false
+		for !p.eof() && p.column == 0 && p.peek() == '%' {
+			for !p.eof() && p.peek() != '\n' {
+				p.advance()
+			}
+			sawDirective = true
+			if err := p.skipToNextContent(); err != nil {
+				return nil, err
+			}
+		}
+
+		if p.eof() {
+			if sawDirective {
+				return nil, p.errorf("directives must be followed by a document start marker '---'")
+			}
+			return docs, nil
+		}
+
+		if p.isDocumentMarker() {
+			marker := p.peek()
+			p.advance()
+			p.advance()
+			p.advance()
+			if marker == '.' {
+				continue
+			}
+		} else if sawDirective {
+			return nil, p.errorf("expected document start marker '---' after directives")
+		}
+
+		p.anchors = make(map[string]*yamlNode)
+		doc, err := p.parseBlockNode(-1, yamlDocumentCtx)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+
+		if err := p.endOfEntry(); err != nil {
+			return nil, err
+		}
+		if !p.eof() && !p.isDocumentMarker() {
+			return nil, p.errorf("unexpected content after document")
+		}
+		if p.isDocumentMarker() && p.peek() == '.' {
+			p.advance()
+			p.advance()
+			p.advance()
+		}
+	}
+}
+
+// parseBlockNode lê um nó cujo conteúdo precisa estar mais indentado que
+// parentIndent. O nó pode começar na linha atual ou nas seguintes.
+func (p *yamlParser) parseBlockNode(parentIndent int, ctx yamlContext) (*yamlNode, error) {
+	p.skipBlanks()
+	start := p.mark()
+	lineStart := p.atLineStart()
+
+	props, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	p.skipInline()
+
+	if p.eof() || p.peek() == '\n' {
+		if err := p.skipToNextContent(); err != nil {
+			return nil, err
+		}
+		indented := p.column > parentIndent ||
+			(ctx == yamlMappingCtx && p.column == parentIndent && p.atSequenceEntry())
+		if p.eof() || p.isDocumentMarker() || !indented {
+			return p.applyProperties(p.emptyNode(start), props)
+		}
+		return p.parseBlockContent(parentIndent, p.column, true, props, false)
+	}
+
+	allowCollection := lineStart || ctx == yamlSequenceCtx || ctx == yamlExplicitCtx
+	return p.parseBlockContent(parentIndent, start.column, allowCollection, props, !props.empty())
+}
+
+func (p *yamlParser) parseBlockContent(parentIndent, column int, allowCollection bool, props yamlProperties, propsInline bool) (*yamlNode, error) {
+	var node *yamlNode
+	var err error
+
+	switch {
+	case p.atSequenceEntry():
+		if !allowCollection {
+			return nil, p.errorf("block sequence entries are not allowed in this context")
+		}
+		node, err = p.parseBlockSequence(p.column)
+	case p.peek() == '?' && isYamlSeparator(p.at(1)):
+		if !allowCollection {
+			return nil, p.errorf("explicit mapping keys are not allowed in this context")
+		}
+		node, err = p.parseBlockMapping(p.column, yamlProperties{})
+	case p.peek() == '|' || p.peek() == '>':
+		node, err = p.parseBlockScalar(parentIndent)
+	case allowCollection && p.implicitKeyAhead():
+		keyProps := yamlProperties{}
+		if propsInline {
+			keyProps, props = props, yamlProperties{}
+		}
+		node, err = p.parseBlockMapping(column, keyProps)
+	default:
+		node, err = p.parseInlineNode(parentIndent)
+		if err == nil {
+			m := p.mark()
+			p.skipBlanks()
+			if p.peek() == ':' && isYamlSeparator(p.at(1)) {
+				return nil, p.errorf("mapping values are not allowed in this context")
+			}
+			p.reset(m)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.applyProperties(node, props)
+}
+
+func (p *yamlParser) parseInlineNode(parentIndent int) (*yamlNode, error) {
+	switch r := p.peek(); r {
+	case '*':
+		return p.parseAlias()
+	case '[', '{':
+		return p.parseFlowCollection()
+	case '"':
+		return p.parseDoubleQuoted()
+	case '\'':
+		return p.parseSingleQuoted()
+	case '@', '`', ',', ']', '}', '%':
+		return nil, p.errorf("found character %q that cannot start any token", r)
+	}
+	return p.parsePlain(parentIndent, false)
+}
+
+func (p *yamlParser) parseBlockSequence(column int) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlSequence, line: p.line, column: p.column + 1}
+
+	for {
+		p.advance() // '-'
+		item, err := p.parseBlockNode(column, yamlSequenceCtx)
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, item)
+
+		if err := p.endOfEntry(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.isDocumentMarker() || p.column < column {
+			return node, nil
+		}
+		if p.column > column {
+			return nil, p.errorf("bad indentation of a sequence entry")
+		}
+		if !p.atSequenceEntry() {
+			return node, nil
+		}
+	}
+}
+
+func (p *yamlParser) parseBlockMapping(column int, firstKeyProps yamlProperties) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlMapping, line: p.line, column: column + 1}
+	keyProps := firstKeyProps
+
+	for first := true; ; first = false {
+		var key, value *yamlNode
+		var err error
+
+		if p.peek() == '?' && isYamlSeparator(p.at(1)) {
+			p.advance()
+			if key, err = p.parseBlockNode(column, yamlExplicitCtx); err != nil {
+				return nil, err
+			}
+			if err := p.endOfEntry(); err != nil {
+				return nil, err
+			}
+			if !p.eof() && !p.isDocumentMarker() && p.column == column && p.peek() == ':' && isYamlSeparator(p.at(1)) {
+				p.advance()
+				if value, err = p.parseBlockNode(column, yamlExplicitCtx); err != nil {
+					return nil, err
+				}
+			} else {
+				value = p.emptyNode(p.mark())
+			}
+		} else {
+			if !first {
+				if keyProps, err = p.parseProperties(); err != nil {
+					return nil, err
+				}
+				p.skipBlanks()
+			}
+			if key, err = p.parseImplicitKey(); err != nil {
+				return nil, err
+			}
+			if key, err = p.applyProperties(key, keyProps); err != nil {
+				return nil, err
+			}
+			p.skipBlanks()
+			if p.peek() != ':' || !isYamlSeparator(p.at(1)) {
+				return nil, p.errorf("could not find expected ':'")
+			}
+			p.advance()
+			if value, err = p.parseBlockNode(column, yamlMappingCtx); err != nil {
+				return nil, err
+			}
+		}
+		node.children = append(node.children, key, value)
+
+		if err := p.endOfEntry(); err != nil {
+			return nil, err
+		}
+		if p.eof() || p.isDocumentMarker() || p.column < column {
+			return node, nil
+		}
+		if p.column > column {
+			return nil, p.errorf("bad indentation of a mapping entry")
+		}
+	}
+}
+
+// implicitKeyAhead verifica, sem consumir entrada, se a linha atual começa
+// com uma chave implícita ("chave: valor").
+func (p *yamlParser) implicitKeyAhead() bool {
+	i := p.pos
+	src := p.src
+	atSep := func(j int) bool { return j >= len(src) || isYamlSeparator(src[j]) }
+
+	switch {
+	case i >= len(src):
+		return false
+	case src[i] == '"' || src[i] == '\'':
+		quote := src[i]
+		for i++; i < len(src) && src[i] != '\n'; i++ {
+			if quote == '"' && src[i] == '\\' {
+				i++
+				continue
+			}
+			if src[i] == quote {
+				if quote == '\'' && i+1 < len(src) && src[i+1] == '\'' {
+					i++
+					continue
+				}
+				break
+			}
+		}
+		if i >= len(src) || src[i] != quote {
+			return false
+		}
+		i++
+	case src[i] == '*':
+		for i++; i < len(src) && !isYamlSeparator(src[i]) && !isYamlFlowIndicator(src[i]); i++ {
+		}
+	case src[i] == '[' || src[i] == '{':
+		return false
+	default:
+		for ; i < len(src) && src[i] != '\n'; i++ {
+			if src[i] == ':' && atSep(i+1) {
+				return true
+			}
+			if isYamlBlank(src[i]) && i+1 < len(src) && src[i+1] == '#' {
+				return false
+			}
+		}
+		return false
+	}
+
+	for i < len(src) && isYamlBlank(src[i]) {
+		i++
+	}
+	return i < len(src) && src[i] == ':' && atSep(i+1)
+}
+
+func (p *yamlParser) parseImplicitKey() (*yamlNode, error) {
+	switch p.peek() {
+	case '*':
+		return p.parseAlias()
+	case '"':
+		return p.parseDoubleQuoted()
+	case '\'':
+		return p.parseSingleQuoted()
+	case '[', '{':
+		return nil, p.errorf("complex mapping keys are not supported")
+	}
+
+	start := p.mark()
+	text := p.readPlainLine(false)
+	if text == "" {
+		return nil, p.errorAt(start, "could not find expected mapping key")
+	}
+	return &yamlNode{kind: yamlScalar, value: text, plain: true, line: start.line, column: start.column + 1}, nil
+}
+
+func (p *yamlParser) emptyNode(m yamlMark) *yamlNode {
+	return &yamlNode{kind: yamlScalar, plain: true, line: m.line, column: m.column + 1}
+}
+
+// ──────────────────────────────────────────────
+//  Propriedades e aliases
+// ──────────────────────────────────────────────
+
+func (p *yamlParser) parseProperties() (yamlProperties, error) {
+	var props yamlProperties
+	for {
+		switch p.peek() {
+		case '&':
+			if props.anchor != "" {
+				return props, p.errorf("a node can have only one anchor")
+			}
+			p.advance()
+			props.anchor = p.readAnchorName()
+			if props.anchor == "" {
+				return props, p.errorf("did not find expected anchor name")
+			}
+		case '!':
+			if props.tag != "" {
+				return props, p.errorf("a node can have only one tag")
+			}
+			tag, err := p.readTag()
+			if err != nil {
+				return props, err
+			}
+			props.tag = tag
+		default:
+			return props, nil
+		}
+		p.skipBlanks()
+	}
+}
+
+func (p *yamlParser) readAnchorName() string {
+	start := p.pos
+	for !p.eof() && !isYamlSeparator(p.peek()) && !isYamlFlowIndicator(p.peek()) {
+		p.advance()
+	}
+	return string(p.src[start:p.pos])
+}
+
+func (p *yamlParser) readTag() (string, error) {
+	start := p.mark()
+	p.advance() // '!'
+
+	if p.peek() == '<' {
+		p.advance()
+		begin := p.pos
+		for !p.eof() && p.peek() != '>' && p.peek() != '\n' {
+			p.advance()
+		}
+		if p.peek() != '>' {
+			return "", p.errorAt(start, "did not find the expected '>' in verbatim tag")
+		}
+		tag := string(p.src[begin:p.pos])
+		p.advance()
+		return tag, nil
+	}
+
+	begin := p.pos
+	for !p.eof() && !isYamlSeparator(p.peek()) && !isYamlFlowIndicator(p.peek()) {
+		p.advance()
+	}
+	suffix := string(p.src[begin:p.pos])
+	if strings.HasPrefix(suffix, "!") {
+		return yamlCoreTagPrefix + suffix[1:], nil
+	}
+	return "!" + suffix, nil
+}
+
+func (p *yamlParser) applyProperties(node *yamlNode, props yamlProperties) (*yamlNode, error) {
+	if props.empty() {
+		return node, nil
+	}
+	if node.kind == yamlAlias {
+		return nil, &yamlError{Line: node.line, Column: node.column, Message: "an alias node cannot have properties"}
+	}
+	if props.tag != "" {
+		node.tag = props.tag
+	}
+	if props.anchor != "" {
+		node.anchor = props.anchor
+		p.anchors[props.anchor] = node
+	}
+	return node, nil
+}
+
+func (p *yamlParser) parseAlias() (*yamlNode, error) {
+	start := p.mark()
+	p.advance() // '*'
+	name := p.readAnchorName()
+	if name == "" {
+		return nil, p.errorAt(start, "did not find expected alias name")
+	}
+	target, ok := p.anchors[name]
+	if !ok {
+		return nil, p.errorAt(start, "found undefined alias %q", name)
+	}
+	return &yamlNode{kind: yamlAlias, alias: target, line: start.line, column: start.column + 1}, nil
+}
+
+// ──────────────────────────────────────────────
+//  Escalares
+// ──────────────────────────────────────────────
+
+// readPlainLine lê a parte de um escalar simples que está na linha atual,
+// sem espaços finais.
+func (p *yamlParser) readPlainLine(flow bool) string {
+	begin := p.pos
+	end := p.mark()
+
+	for !p.eof() {
+		r := p.peek()
+		if r == '\n' {
+			break
+		}
+		if r == ':' && (isYamlSeparator(p.at(1)) || (flow && isYamlFlowIndicator(p.at(1)))) {
+			break
+		}
+		if isYamlBlank(r) && p.at(1) == '#' {
+			break
+		}
+		if flow && isYamlFlowIndicator(r) {
+			break
+		}
+		p.advance()
+		if !isYamlBlank(r) {
+			end = p.mark()
+		}
+	}
+
+	text := string(p.src[begin:end.pos])
+	p.reset(end)
+	return text
+}
+
+// parsePlain lê um escalar simples, juntando as linhas de continuação que
+// estejam mais indentadas que parentIndent.
+func (p *yamlParser) parsePlain(parentIndent int, flow bool) (*yamlNode, error) {
+	start := p.mark()
+	first := p.readPlainLine(flow)
+	if first == "" {
+		return nil, p.errorAt(start, "found unexpected character %q", p.peek())
+	}
+
+	var b strings.Builder
+	b.WriteString(first)
+
+	for {
+		m := p.mark()
+		p.skipBlanks()
+		if p.peek() != '\n' {
+			p.reset(m)
+			break
+		}
+
+		breaks := 0
+		marker := false
+		for p.peek() == '\n' {
+			p.advance()
+			breaks++
+			if p.isDocumentMarker() {
+				marker = true
+				break
+			}
+			p.skipBlanks()
+		}
+
+		r := p.peek()
+		if marker || p.eof() || r == '#' ||
+			(!flow && p.column <= parentIndent) ||
+			(flow && (isYamlFlowIndicator(r) || r == ':')) {
+			p.reset(m)
+			break
+		}
+
+		line := p.readPlainLine(flow)
+		if line == "" {
+			p.reset(m)
+			break
+		}
+		if breaks == 1 {
+			b.WriteByte(' ')
+		} else {
+			b.WriteString(strings.Repeat("\n", breaks-1))
+		}
+		b.WriteString(line)
+	}
+
+	return &yamlNode{kind: yamlScalar, value: b.String(), plain: true, line: start.line, column: start.column + 1}, nil
+}
+
+// readQuotedWhitespace aplica as regras de dobra de linha dentro de escalares
+// entre aspas: uma quebra vira espaço, linhas vazias viram quebras.
+func (p *yamlParser) readQuotedWhitespace(b *strings.Builder, start yamlMark) error {
+	begin := p.pos
+	p.skipBlanks()
+	if p.peek() != '\n' {
+		b.WriteString(string(p.src[begin:p.pos]))
+		return nil
+	}
+
+	breaks := 0
+	for p.peek() == '\n' {
+		p.advance()
+		breaks++
+		if p.isDocumentMarker() {
+			return p.errorAt(start, "found unexpected document marker while scanning a quoted scalar")
+		}
+		p.skipBlanks()
+	}
+	if breaks == 1 {
+		b.WriteByte(' ')
+	} else {
+		b.WriteString(strings.Repeat("\n", breaks-1))
+	}
+	return nil
+}
+
+func (p *yamlParser) parseSingleQuoted() (*yamlNode, error) {
+	start := p.mark()
+	p.advance()
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return nil, p.errorAt(start, "found unexpected end of stream while scanning a quoted scalar")
+		}
+		switch r := p.peek(); {
+		case r == '\'' && p.at(1) == '\'':
+			b.WriteRune('\'')
+			p.advance()
+			p.advance()
+		case r == '\'':
+			p.advance()
+			return &yamlNode{kind: yamlScalar, value: b.String(), line: start.line, column: start.column + 1}, nil
+		case isYamlBlank(r) || r == '\n':
+			if err := p.readQuotedWhitespace(&b, start); err != nil {
+				return nil, err
+			}
+		default:
+			b.WriteRune(r)
+			p.advance()
+		}
+	}
+}
+
+func (p *yamlParser) parseDoubleQuoted() (*yamlNode, error) {
+	start := p.mark()
+	p.advance()
+
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return nil, p.errorAt(start, "found unexpected end of stream while scanning a quoted scalar")
+		}
+		switch r := p.peek(); {
+		case r == '"':
+			p.advance()
+			return &yamlNode{kind: yamlScalar, value: b.String(), line: start.line, column: start.column + 1}, nil
+		case r == '\\' && p.at(1) == '\n':
+			p.advance()
+			p.advance()
+			p.skipBlanks()
+		case r == '\\':
+			if err := p.readEscape(&b); err != nil {
+				return nil, err
+			}
+		case isYamlBlank(r) || r == '\n':
+			if err := p.readQuotedWhitespace(&b, start); err != nil {
+				return nil, err
+			}
+		default:
+			b.WriteRune(r)
+			p.advance()
+		}
+	}
+}
+
+var yamlEscapes = map[rune]rune{
+	'0': 0, 'a': '\a', 'b': '\b', 't': '\t', '\t': '\t', 'n': '\n', 'v': '\v',
+	'f': '\f', 'r': '\r', 'e': '\x1b', ' ': ' ', '"': '"', '/': '/', '\\': '\\',
+	'N': '\u0085', '_': '\u00a0', 'L': '\u2028', 'P': '\u2029',
+}
+
+func (p *yamlParser) readEscape(b *strings.Builder) error {
+	start := p.mark()
+	p.advance() // '\'
+	r := p.peek()
+	p.advance()
+
+	if escaped, ok := yamlEscapes[r]; ok {
+		b.WriteRune(escaped)
+		return nil
+	}
+
+	size := map[rune]int{'x': 2, 'u': 4, 'U': 8}[r]
+	if size == 0 || p.pos+size > len(p.src) {
+		return p.errorAt(start, "found unknown escape character %q", r)
+	}
+	code, err := strconv.ParseUint(string(p.src[p.pos:p.pos+size]), 16, 32)
+	if err != nil {
+		return p.errorAt(start, "invalid escape sequence \\%c%s", r, string(p.src[p.pos:p.pos+size]))
+	}
+	for i := 0; i < size; i++ {
+		p.advance()
+	}
+	b.WriteRune(rune(code))
+	return nil
+}
+
+// parseBlockScalar lê escalares literais (|) e dobrados (>), com indicadores
+// de chomping (+/-) e de indentação.
+func (p *yamlParser) parseBlockScalar(parentIndent int) (*yamlNode, error) {
+	start := p.mark()
+	folded := p.peek() == '>'
+	p.advance()
+
+	chomp := rune(0)
+	indicator := 0
+	for i := 0; i < 2; i++ {
+		switch r := p.peek(); {
+		case (r == '-' || r == '+') && chomp == 0:
+			chomp = r
+			p.advance()
+		case r >= '1' && r <= '9' && indicator == 0:
+			indicator = int(r - '0')
+			p.advance()
+		}
+	}
+	p.skipInline()
+	if !p.eof() && p.peek() != '\n' {
+		return nil, p.errorf("invalid block scalar header")
+	}
+	p.advance()
+
+	contentIndent := -1
+	if indicator > 0 {
+		contentIndent = max(parentIndent, 0) + indicator
+	} else {
+		for i := p.pos; i < len(p.src); {
+			spaces := 0
+			for i+spaces < len(p.src) && p.src[i+spaces] == ' ' {
+				spaces++
+			}
+			if i+spaces < len(p.src) && p.src[i+spaces] == '\n' {
+				i += spaces + 1
+				continue
+			}
+			if i+spaces < len(p.src) {
+				contentIndent = spaces
+			}
+			break
+		}
+		if contentIndent <= parentIndent {
+			contentIndent = parentIndent + 1
+		}
+	}
+
+	var lines []string
+	for !p.eof() {
+		m := p.mark()
+		spaces := 0
+		for spaces < contentIndent && p.peek() == ' ' {
+			p.advance()
+			spaces++
+		}
+		if p.eof() {
+			break
+		}
+		if p.peek() == '\n' {
+			lines = append(lines, "")
+			p.advance()
+			continue
+		}
+		if spaces < contentIndent || (contentIndent == 0 && p.isDocumentMarker()) {
+			p.reset(m)
+			break
+		}
+		begin := p.pos
+		for !p.eof() && p.peek() != '\n' {
+			p.advance()
+		}
+		lines = append(lines, string(p.src[begin:p.pos]))
+		p.advance()
+	}
+
+	trailing := 0
+	for trailing < len(lines) && lines[len(lines)-1-trailing] == "" {
+		trailing++
+	}
+	body := lines[:len(lines)-trailing]
+
+	var value string
+	if folded {
+		value = foldYamlLines(body)
+	} else {
+		value = strings.Join(body, "\n")
+	}
+
+	switch {
+	case len(body) > 0 && chomp == 0:
+		value += "\n"
+	case len(body) > 0 && chomp == '+':
+		value += "\n" + strings.Repeat("\n", trailing)
+	case chomp == '+':
+		value = strings.Repeat("\n", trailing)
+	}
+
+	return &yamlNode{kind: yamlScalar, value: value, line: start.line, column: start.column + 1}, nil
+}
+
+// foldYamlLines junta as linhas de um escalar dobrado. Linhas mais
+// indentadas e linhas vazias preservam as quebras.
+func foldYamlLines(lines []string) string {
+	var b strings.Builder
+	started := false
+	prevMore := false
+	empty := 0
+
+	for _, line := range lines {
+		if line == "" {
+			if started {
+				empty++
+			} else {
+				b.WriteByte('\n')
+			}
+			continue
+		}
+
+		more := line[0] == ' ' || line[0] == '\t'
+		if started {
+			switch {
+			case more || prevMore:
+				b.WriteString(strings.Repeat("\n", empty+1))
+			case empty == 0:
+				b.WriteByte(' ')
+			default:
+				b.WriteString(strings.Repeat("\n", empty))
+			}
+		}
+		b.WriteString(line)
+		started = true
+		prevMore = more
+		empty = 0
+	}
+	return b.String()
+}
+
+// ──────────────────────────────────────────────
+//  Coleções em fluxo ([...] e {...})
+// ──────────────────────────────────────────────
+
+func (p *yamlParser) skipFlowSpace(start yamlMark) error {
+	for {
+		switch r := p.peek(); {
+		case p.eof():
+			return p.errorAt(start, "found unexpected end of stream while scanning a flow collection")
+		case isYamlBlank(r):
+			p.advance()
+		case r == '\n':
+			p.advance()
+			if p.isDocumentMarker() {
+				return p.errorAt(start, "found unexpected document marker while scanning a flow collection")
+			}
+		case r == '#' && isYamlSeparator(p.src[p.pos-1]):
+			for !p.eof() && p.peek() != '\n' {
+				p.advance()
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+func (p *yamlParser) parseFlowCollection() (*yamlNode, error) {
+	start := p.mark()
+	closing := ']'
+	node := &yamlNode{kind: yamlSequence, line: start.line, column: start.column + 1}
+	if p.peek() == '{' {
+		closing = '}'
+		node.kind = yamlMapping
+	}
+	p.advance()
+
+	for {
+		if err := p.skipFlowSpace(start); err != nil {
+			return nil, err
+		}
+		if p.peek() == closing {
+			p.advance()
+			return node, nil
+		}
+
+		key, value, pair, err := p.parseFlowEntry(start, closing)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case node.kind == yamlMapping:
+			if value == nil {
+				value = p.emptyNode(p.mark())
+			}
+			node.children = append(node.children, key, value)
+		case pair:
+			if value == nil {
+				value = p.emptyNode(p.mark())
+			}
+			node.children = append(node.children, &yamlNode{
+				kind: yamlMapping, children: []*yamlNode{key, value}, line: key.line, column: key.column,
+			})
+		default:
+			node.children = append(node.children, key)
+		}
+
+		if err := p.skipFlowSpace(start); err != nil {
+			return nil, err
+		}
+		switch p.peek() {
+		case ',':
+			p.advance()
+		case closing:
+			p.advance()
+			return node, nil
+		default:
+			return nil, p.errorf("did not find expected ',' or '%c'", closing)
+		}
+	}
+}
+
+// parseFlowEntry lê um item de coleção em fluxo, que pode ser um nó simples
+// ou um par "chave: valor".
+func (p *yamlParser) parseFlowEntry(start yamlMark, closing rune) (key, value *yamlNode, pair bool, err error) {
+	explicit := false
+	if p.peek() == '?' && isYamlSeparator(p.at(1)) {
+		p.advance()
+		explicit = true
+		if err := p.skipFlowSpace(start); err != nil {
+			return nil, nil, false, err
+		}
+	}
+
+	r := p.peek()
+	if (r == ':' && (isYamlSeparator(p.at(1)) || isYamlFlowIndicator(p.at(1)))) || r == ',' || r == closing {
+		key = p.emptyNode(p.mark())
+	} else if key, err = p.parseFlowNode(start); err != nil {
+		return nil, nil, false, err
+	}
+
+	if err := p.skipFlowSpace(start); err != nil {
+		return nil, nil, false, err
+	}
+
+	jsonLike := key.kind != yamlScalar || !key.plain
+	if p.peek() == ':' && (isYamlSeparator(p.at(1)) || isYamlFlowIndicator(p.at(1)) || jsonLike) {
+		p.advance()
+		if err := p.skipFlowSpace(start); err != nil {
+			return nil, nil, false, err
+		}
+		if p.peek() != ',' && p.peek() != closing {
+			if value, err = p.parseFlowNode(start); err != nil {
+				return nil, nil, false, err
+			}
+		}
+		return key, value, true, nil
+	}
+	return key, nil, explicit, nil
+}
+
+func (p *yamlParser) parseFlowNode(start yamlMark) (*yamlNode, error) {
+	nodeStart := p.mark()
+	props, err := p.parseProperties()
+	if err != nil {
+		return nil, err
+	}
+	if !props.empty() {
+		if err := p.skipFlowSpace(start); err != nil {
+			return nil, err
+		}
+	}
+
+	var node *yamlNode
+	switch r := p.peek(); r {
+	case ',', ']', '}':
+		node = p.emptyNode(nodeStart)
+	case '*':
+		node, err = p.parseAlias()
+	case '[', '{':
+		node, err = p.parseFlowCollection()
+	case '"':
+		node, err = p.parseDoubleQuoted()
+	case '\'':
+		node, err = p.parseSingleQuoted()
+	default:
+		node, err = p.parsePlain(-1, true)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return p.applyProperties(node, props)
+}
+
+// ──────────────────────────────────────────────
+//  Resolução para tipos genéricos (schema core)
+// ──────────────────────────────────────────────
+
+var (
+	yamlIntPattern   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlOctPattern   = regexp.MustCompile(`^0o[0-7]+$`)
+	yamlHexPattern   = regexp.MustCompile(`^0x[0-9a-fA-F]+$`)
+	yamlFloatPattern = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlInfPattern   = regexp.MustCompile(`^[-+]?\.(inf|Inf|INF)$`)
+	yamlNaNPattern   = regexp.MustCompile(`^\.(nan|NaN|NAN)$`)
+)
+
+type yamlResolver struct {
+	aliasDepth int
+	expanded   int
+	types      *typeInference
+	// key é a chave de mapeamento mais próxima do nó atual, usada nas
+	// regras de tipo por coluna.
+	key string
+}
+
+func (r *yamlResolver) resolve(node *yamlNode) (interface{}, error) {
+	if r.aliasDepth > 0 {
+		r.expanded++
+		if r.expanded > yamlMaxAliasExpansion {
+			return nil, &yamlError{Line: node.line, Column: node.column, Message: "document expands too many aliases"}
+		}
+	}
+
+	switch node.kind {
+	case yamlAlias:
+		r.aliasDepth++
+		value, err := r.resolve(node.alias)
+		r.aliasDepth--
+		return value, err
+
+	case yamlSequence:
+		list := make([]interface{}, 0, len(node.children))
+		for _, child := range node.children {
+			value, err := r.resolve(child)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+
+	case yamlMapping:
+		return r.resolveMapping(node)
+	}
+	return r.resolveScalar(node)
+}
+
+// resolveScalar aplica a inferência de tipos aos escalares simples sem tag.
+// Escalares com aspas ou com tag seguem as regras do YAML.
+func (r *yamlResolver) resolveScalar(node *yamlNode) (interface{}, error) {
+	if node.tag != "" || !node.plain {
+		return resolveYamlScalar(node)
+	}
+
+	if kind := r.types.columnType(r.key); kind != columnAuto {
+		value, err := forceColumnType(node.value, kind, r.key)
+		if err != nil {
+			return nil, &yamlError{Line: node.line, Column: node.column, Message: err.Error()}
+		}
+		return value, nil
+	}
+
+	switch r.types.mode() {
+	case inferNone:
+		return node.value, nil
+	case inferSafe:
+		// As mesmas regras do CSV e do XML; só "~" é próprio do YAML.
+		switch node.value {
+		case "", "~", "null":
+			return nil, nil
+		}
+		return inferSafeText(node.value), nil
+	}
+	value := resolveYamlCore(node.value)
+	if _, isInt := value.(int); !isInt && yamlIntPattern.MatchString(node.value) {
+		// Inteiros fora do int64 seguem como json.Number em vez de virar
+		// float.
+		return integerValue(canonicalInteger(node.value)), nil
+	}
+	return value, nil
+}
+
+// resolveMapping monta o objeto na ordem do documento. Chaves trazidas por
+// "<<" entram na posição do merge, a menos que o mapeamento as defina
+// explicitamente.
+func (r *yamlResolver) resolveMapping(node *yamlNode) (interface{}, error) {
+	keys := make([]string, len(node.children)/2)
+	explicit := make(map[string]struct{}, len(keys))
+
+	for i := 0; i+1 < len(node.children); i += 2 {
+		keyNode := node.children[i]
+		if isYamlMergeKey(keyNode) {
+			continue
+		}
+
+		key, err := r.resolveKey(keyNode)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := explicit[key]; exists {
+			return nil, &yamlError{Line: keyNode.line, Column: keyNode.column, Message: fmt.Sprintf("duplicate mapping key %q", key)}
+		}
+		explicit[key] = struct{}{}
+		keys[i/2] = key
+	}
+
+	obj := document.NewObject()
+	for i := 0; i+1 < len(node.children); i += 2 {
+		keyNode, valueNode := node.children[i], node.children[i+1]
+
+		if !isYamlMergeKey(keyNode) {
+			parentKey := r.key
+			r.key = keys[i/2]
+			value, err := r.resolve(valueNode)
+			r.key = parentKey
+			if err != nil {
+				return nil, err
+			}
+			obj.Set(keys[i/2], value)
+			continue
+		}
+
+		value, err := r.resolve(valueNode)
+		if err != nil {
+			return nil, err
+		}
+		sources, ok := value.([]interface{})
+		if !ok {
+			sources = []interface{}{value}
+		}
+		for _, source := range sources {
+			sourceObj, ok := source.(*document.Object)
+			if !ok {
+				return nil, &yamlError{Line: valueNode.line, Column: valueNode.column, Message: "merge key value must be a mapping or a sequence of mappings"}
+			}
+			for _, key := range sourceObj.Keys() {
+				if _, isExplicit := explicit[key]; isExplicit {
+					continue
+				}
+				if _, exists := obj.Get(key); !exists {
+					val, _ := sourceObj.Get(key)
+					obj.Set(key, val)
+				}
+			}
+		}
+	}
+	return obj, nil
+}
+
+func isYamlMergeKey(node *yamlNode) bool {
+	return node.kind == yamlScalar && node.plain && node.value == "<<" && node.tag == ""
+}
+
+// resolveKey converte a chave para string. Chaves escalares mantêm o texto
+// original (ex.: "0x1F" continua "0x1F").
+func (r *yamlResolver) resolveKey(node *yamlNode) (string, error) {
+	target := node
+	for target.kind == yamlAlias {
+		target = target.alias
+	}
+	if target.kind != yamlScalar {
+		return "", &yamlError{Line: node.line, Column: node.column, Message: "complex mapping keys are not supported"}
+	}
+	if target.plain && target.tag == "" {
+		if value, _ := resolveYamlScalar(target); value == nil {
+			return "null", nil
+		}
+	}
+	return target.value, nil
+}
+
+func resolveYamlScalar(node *yamlNode) (interface{}, error) {
+	tag := node.tag
+	if tag == "" && !node.plain || tag == "!" {
+		return node.value, nil
+	}
+
+	switch tag {
+	case "", yamlCoreTagPrefix + "int", yamlCoreTagPrefix + "float", yamlCoreTagPrefix + "bool", yamlCoreTagPrefix + "null":
+	default:
+		if strings.HasPrefix(tag, yamlCoreTagPrefix) || !node.plain {
+			return node.value, nil
+		}
+		tag = ""
+	}
+
+	value := resolveYamlCore(node.value)
+	invalid := false
+	switch tag {
+	case yamlCoreTagPrefix + "null":
+		invalid = value != nil
+	case yamlCoreTagPrefix + "bool":
+		_, ok := value.(bool)
+		invalid = !ok
+	case yamlCoreTagPrefix + "int":
+		_, ok := value.(int)
+		invalid = !ok
+	case yamlCoreTagPrefix + "float":
+		switch v := value.(type) {
+		case int:
+			value = float64(v)
+		case float64:
+		default:
+			invalid = true
+		}
+	}
+	if invalid {
+		return nil, &yamlError{Line: node.line, Column: node.column, Message: fmt.Sprintf("cannot resolve %q as %s", node.value, strings.TrimPrefix(tag, yamlCoreTagPrefix))}
+	}
+	return value, nil
+}
+
+// resolveYamlCore aplica as regras de tipos do schema core do YAML 1.2 a um
+// escalar simples.
+func resolveYamlCore(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	switch {
+	case yamlIntPattern.MatchString(s):
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return int(i)
+		}
+	case yamlOctPattern.MatchString(s):
+		if i, err := strconv.ParseInt(s[2:], 8, 64); err == nil {
+			return int(i)
+		}
+	case yamlHexPattern.MatchString(s):
+		if i, err := strconv.ParseInt(s[2:], 16, 64); err == nil {
+			return int(i)
+		}
+	case yamlInfPattern.MatchString(s):
+		if strings.HasPrefix(s, "-") {
+			return math.Inf(-1)
+		}
+		return math.Inf(1)
+	case yamlNaNPattern.MatchString(s):
+		return math.NaN()
+	}
+
+	if yamlFloatPattern.MatchString(s) {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestYamlSuite(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "yaml-suite", "*", "in.yaml"))
+	if err != nil || len(dirs) == 0 {
+		t.Fatalf("No YAML suite cases found: %v", err)
+	}
+
+	for _, mode := range []string{inferAggressive, inferSafe} {
+		for _, inPath := range dirs {
+			dir := filepath.Dir(inPath)
+			t.Run(mode+"/"+filepath.Base(dir), func(t *testing.T) {
+				runYamlSuiteCase(t, dir, mode)
+			})
+		}
+	}
+}
+
+// runYamlSuiteCase compara o resultado com o in.json do caso. Os casos da
+// suíte seguem o schema core do YAML 1.2; no modo safe, os escalares que o
+// schema core converte mas o modo safe não (ex.: "TRUE", "0x1F") podem
+// continuar strings.
+func runYamlSuiteCase(t *testing.T, dir string, mode string) {
+	input, err := os.ReadFile(filepath.Join(dir, "in.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := parseYamlDocuments(bytes.NewReader(input), &typeInference{Mode: mode})
+
+	if _, statErr := os.Stat(filepath.Join(dir, "error")); statErr == nil {
+		var yamlErr *yamlError
+		if !errors.As(err, &yamlErr) {
+			t.Fatalf("Expected a YAML error with position, got: %v", err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Error parsing YAML: %v", err)
+	}
+
+	expectedData, err := os.ReadFile(filepath.Join(dir, "in.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []interface{}
+	decoder := json.NewDecoder(bytes.NewReader(expectedData))
+	for {
+		var doc interface{}
+		if err := decoder.Decode(&doc); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("Invalid in.json: %v", err)
+		}
+		expected = append(expected, doc)
+	}
+
+	// Normaliza os tipos passando o resultado pelo JSON.
+	gotData, err := json.Marshal(docs)
+	if err != nil {
+		t.Fatalf("Error marshaling parsed documents: %v", err)
+	}
+	var got []interface{}
+	if err := json.Unmarshal(gotData, &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if !yamlSuiteMatch(got, expected, mode == inferSafe) {
+		t.Errorf("Unexpected documents:\nExpected: %#v\nGot:      %#v", expected, got)
+	}
+}
+
+// yamlSuiteMatch compara got com expected; com keepText, uma string no
+// lugar de um escalar não-string também é aceita.
+func yamlSuiteMatch(got, expected interface{}, keepText bool) bool {
+	switch e := expected.(type) {
+	case []interface{}:
+		g, ok := got.([]interface{})
+		if !ok || len(g) != len(e) {
+			return false
+		}
+		for i := range e {
+			if !yamlSuiteMatch(g[i], e[i], keepText) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		g, ok := got.(map[string]interface{})
+		if !ok || len(g) != len(e) {
+			return false
+		}
+		for key, value := range e {
+			item, present := g[key]
+			if !present || !yamlSuiteMatch(item, value, keepText) {
+				return false
+			}
+		}
+		return true
+	case string:
+		return got == e
+	}
+	if _, isText := got.(string); isText && keepText {
+		return true
+	}
+	return reflect.DeepEqual(got, expected)
+}
+
+func TestParseYamlErrorPosition(t *testing.T) {
+	_, err := parseYamlToInterface(strings.NewReader("a: 1\nb:\n  - x\n  y: 2\n"), nil)
+	if err == nil {
+		t.Fatal("Expected an error for invalid YAML")
+	}
+	if !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("Expected error to point at line 4, got: %v", err)
+	}
+}